@@ -0,0 +1,173 @@
+// Command rulebench compares keyword matching in the processing engine: the
+// Aho-Corasick automaton against checking every rule with strings.Contains.
+// It generates a synthetic rule set and transactions, verifies both modes give
+// identical results, and then benchmarks them. The worker pool of
+// ProcessTransactions is benchmarked by BenchmarkProcessTransactions in
+// internal/service.
+//
+//	go run ./cmd/rulebench -rules 2000 -rows 5000
+package main
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/service"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var syllables = []string{
+	"ba", "ka", "ja", "sa", "ta", "pa", "ma", "na", "ra", "la",
+	"be", "ke", "se", "te", "pe", "me", "ne", "re", "le", "ge",
+	"bi", "ki", "si", "ti", "pi", "mi", "ni", "ri", "li", "gi",
+	"bu", "ku", "su", "tu", "pu", "mu", "nu", "ru", "lu", "gu",
+	"ban", "kan", "jan", "san", "tan", "pan", "man", "nan", "ran", "lan",
+}
+
+var whtTypes = []string{"wth_21", "wth_23", "wth_26", "wth_4_2", "wth_15"}
+
+func main() {
+	rules := flag.Int("rules", 1000, "number of rules per rule type")
+	rows := flag.Int("rows", 2000, "number of synthetic transactions")
+	seed := flag.Int64("seed", 1, "random seed")
+	flag.Parse()
+
+	rng := rand.New(rand.NewSource(*seed))
+	vocabulary := makeVocabulary(rng, *rules*4)
+	snapshot := makeRuleSet(rng, vocabulary, *rules)
+	transactions := makeTransactions(rng, vocabulary, snapshot, *rows)
+
+	automaton := service.NewProcessingEngine(nil, nil, nil, nil)
+	automaton.LoadRuleSet(snapshot)
+	linear := service.NewProcessingEngine(nil, nil, nil, nil)
+	linear.LoadRuleSet(snapshot)
+	linear.SetLinearMatching(true)
+
+	mismatches := 0
+	for i := range transactions {
+		a, b := transactions[i], transactions[i]
+		automaton.ProcessTransaction(&a)
+		linear.ProcessTransaction(&b)
+		if !reflect.DeepEqual(a, b) {
+			mismatches++
+			if mismatches <= 5 {
+				log.Printf("row %d differs: %q", i, transactions[i].Keterangan)
+			}
+		}
+	}
+	if mismatches > 0 {
+		log.Fatalf("%d of %d rows differ between automaton and linear matching", mismatches, len(transactions))
+	}
+
+	fmt.Printf("rules per type: %d, transactions: %d, results identical\n\n", *rules, len(transactions))
+	report := func(name string, engine *service.ProcessingEngine) testing.BenchmarkResult {
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tx := transactions[i%len(transactions)]
+				engine.ProcessTransaction(&tx)
+			}
+		})
+		fmt.Printf("%-10s %s %s\n", name, result.String(), result.MemString())
+		return result
+	}
+
+	linearResult := report("linear", linear)
+	automatonResult := report("automaton", automaton)
+	if automatonResult.NsPerOp() > 0 {
+		fmt.Printf("\nspeedup: %.1fx\n", float64(linearResult.NsPerOp())/float64(automatonResult.NsPerOp()))
+	}
+}
+
+func makeVocabulary(rng *rand.Rand, size int) []string {
+	seen := map[string]bool{}
+	words := make([]string, 0, size)
+	for len(words) < size {
+		var sb strings.Builder
+		for n := 2 + rng.Intn(3); n > 0; n-- {
+			sb.WriteString(syllables[rng.Intn(len(syllables))])
+		}
+		if word := sb.String(); !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// keyword picks one or two vocabulary words, mixing case like real rule data
+func keyword(rng *rand.Rand, vocabulary []string) string {
+	kw := vocabulary[rng.Intn(len(vocabulary))]
+	if rng.Intn(3) == 0 {
+		kw += " " + vocabulary[rng.Intn(len(vocabulary))]
+	}
+	if rng.Intn(4) == 0 {
+		kw = strings.ToUpper(kw)
+	}
+	return kw
+}
+
+func makeRuleSet(rng *rand.Rand, vocabulary []string, n int) *models.RuleSetSnapshot {
+	snapshot := &models.RuleSetSnapshot{}
+	for i := 1; i <= n; i++ {
+		var notValue sql.NullString
+		if rng.Intn(5) == 0 {
+			notValue = sql.NullString{String: vocabulary[rng.Intn(len(vocabulary))] + ", " + vocabulary[rng.Intn(len(vocabulary))], Valid: true}
+		}
+		snapshot.KoreksiRules = append(snapshot.KoreksiRules, models.KoreksiRule{
+			ID: i, Keyword: keyword(rng, vocabulary), Value: fmt.Sprintf("K%d", i), NotValue: notValue, Priority: rng.Intn(10), IsActive: true,
+		})
+		snapshot.ObyekRules = append(snapshot.ObyekRules, models.ObyekRule{
+			ID: i, Keyword: keyword(rng, vocabulary), Value: fmt.Sprintf("O%d", i), NotValue: notValue, Priority: rng.Intn(10), IsActive: true,
+		})
+		snapshot.WithholdingTaxRules = append(snapshot.WithholdingTaxRules, models.WithholdingTaxRule{
+			ID: i, Keyword: keyword(rng, vocabulary), TaxType: whtTypes[rng.Intn(len(whtTypes))], TaxRate: 0.02, IsActive: true,
+		})
+		category := "input_tax"
+		if i%2 == 0 {
+			category = "output_tax"
+		}
+		snapshot.TaxKeywords = append(snapshot.TaxKeywords, models.TaxKeyword{
+			ID: i, Keyword: keyword(rng, vocabulary), TaxCategory: category, IsActive: true,
+		})
+		snapshot.UmPajakRules = append(snapshot.UmPajakRules, models.UmPajakRule{
+			ID: i, Keyword: keyword(rng, vocabulary), DebitCondition: models.DebitConditionPositive, Priority: rng.Intn(10), IsActive: true,
+		})
+	}
+	return snapshot
+}
+
+// makeTransactions builds descriptions of random words, most of them containing
+// a few rule keywords
+func makeTransactions(rng *rand.Rand, vocabulary []string, snapshot *models.RuleSetSnapshot, n int) []models.TransactionData {
+	transactions := make([]models.TransactionData, n)
+	for i := range transactions {
+		var words []string
+		for w := 4 + rng.Intn(8); w > 0; w-- {
+			words = append(words, vocabulary[rng.Intn(len(vocabulary))])
+		}
+		for k := rng.Intn(4); k > 0; k-- {
+			rule := snapshot.KoreksiRules[rng.Intn(len(snapshot.KoreksiRules))]
+			words = append(words, rule.Keyword)
+		}
+		rng.Shuffle(len(words), func(a, b int) { words[a], words[b] = words[b], words[a] })
+
+		amount := float64(1000 + rng.Intn(1000000))
+		transactions[i] = models.TransactionData{
+			ID:         int64(i + 1),
+			Account:    fmt.Sprintf("%d", 100000+rng.Intn(900000)),
+			Keterangan: strings.Join(words, " "),
+		}
+		if rng.Intn(2) == 0 {
+			transactions[i].Debet = amount
+		} else {
+			transactions[i].Credit = amount
+		}
+	}
+	return transactions
+}
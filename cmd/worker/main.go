@@ -35,13 +35,19 @@ func main() {
 	}
 	defer redisClient.Close()
 
+	asynqRedis := asynq.RedisClientOpt{
+		Addr:     cfg.AsynqRedisAddr,
+		Password: cfg.AsynqRedisPassword,
+		DB:       cfg.AsynqRedisDB,
+	}
+
+	// Asynq client for the tasks handlers queue themselves
+	asynqClient := asynq.NewClient(asynqRedis)
+	defer asynqClient.Close()
+
 	// Create Asynq server
 	srv := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr:     cfg.AsynqRedisAddr,
-			Password: cfg.AsynqRedisPassword,
-			DB:       cfg.AsynqRedisDB,
-		},
+		asynqRedis,
 		asynq.Config{
 			Concurrency: cfg.WorkerConcurrency,
 			Queues: map[string]int{
@@ -57,7 +63,7 @@ func main() {
 
 	// Register task handlers
 	mux := asynq.NewServeMux()
-	worker.RegisterHandlers(mux, db, redisClient, cfg)
+	worker.RegisterHandlers(mux, db, redisClient, asynqClient, cfg)
 
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -39,7 +39,7 @@ func main() {
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	accountHandler := handler.NewAccountHandler(accountRepo)
-	uploadHandler := handler.NewUploadHandler(uploadRepo, excelService, nil, &config.Config{})
+	uploadHandler := handler.NewUploadHandler(uploadRepo, excelService, nil, nil, &config.Config{})
 	koreksiRuleHandler := handler.NewKoreksiRuleHandler(rulesRepo)
 	obyekRuleHandler := handler.NewObyekRuleHandler(rulesRepo)
 	ruleHandler := handler.NewGenericRuleHandler()
@@ -44,6 +44,8 @@ type Config struct {
 	// Processing
 	BatchSize         int
 	WorkerConcurrency int
+	// ProcessingWorkers bounds the goroutines processing the rows of a batch; 0 uses one per CPU
+	ProcessingWorkers int
 
 	// Asynq
 	AsynqRedisAddr     string
@@ -86,6 +88,7 @@ func Load() (*Config, error) {
 
 		BatchSize:         getEnvAsInt("BATCH_SIZE", 5000),
 		WorkerConcurrency: getEnvAsInt("WORKER_CONCURRENCY", 4),
+		ProcessingWorkers: getEnvAsInt("PROCESSING_WORKERS", 0),
 
 		AsynqRedisAddr:     getEnv("ASYNQ_REDIS_ADDR", "127.0.0.1:6379"),
 		AsynqRedisPassword: getEnv("ASYNQ_REDIS_PASSWORD", ""),
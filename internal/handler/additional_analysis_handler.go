@@ -267,9 +267,9 @@ func (h *AdditionalAnalysisHandler) ExportToExcel(c *fiber.Ctx) error {
 // DownloadTemplate downloads Excel template for import
 func (h *AdditionalAnalysisHandler) DownloadTemplate(c *fiber.Ctx) error {
 	// Create template data with headers
-	templateData := `Account Code,Analysis Type,Analysis Title,Status,Notes
-12060700,revenue_recognition,Sample Analysis Title,active,Sample notes here
-11010001,tax_treatment,Another Analysis,active,Another note`
+	templateData := `Account Code,Analysis Type,Analysis Title,Keyword Condition,Status,Notes
+12060700,revenue_recognition,Sample Analysis Title,,active,Sample notes here
+11010001,tax_treatment,Another Analysis,"sewa, !deposit",active,Another note`
 
 	// Set headers for Excel file download
 	c.Set("Content-Type", "text/csv")
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/utils"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
+)
+
+type ClassifierHandler struct {
+	classifierRepo *repository.ClassifierRepository
+	asynqClient    *asynq.Client
+}
+
+func NewClassifierHandler(classifierRepo *repository.ClassifierRepository, asynqClient *asynq.Client) *ClassifierHandler {
+	return &ClassifierHandler{
+		classifierRepo: classifierRepo,
+		asynqClient:    asynqClient,
+	}
+}
+
+// GetModels lists the classifier versions, newest first
+func (h *ClassifierHandler) GetModels(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	list, total, err := h.classifierRepo.GetModels(params.Limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve classifier models", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	return utils.PaginatedResponseBuilder(c, "Classifier models retrieved successfully", fiber.Map{
+		"models":     list,
+		"pagination": pagination,
+	}, pagination)
+}
+
+// GetModel returns one classifier version with its training statistics
+func (h *ClassifierHandler) GetModel(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid model ID", err)
+	}
+
+	model, err := h.classifierRepo.GetModelByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Classifier model not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Classifier model retrieved successfully", model)
+}
+
+// TrainModel queues the training of a new classifier version on the processed rows.
+// The version becomes active once trained; later processing runs use it.
+func (h *ClassifierHandler) TrainModel(c *fiber.Ctx) error {
+	userID, _, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	if h.asynqClient == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Background job processing is not available (Redis not connected)", nil)
+	}
+
+	model := &models.ClassifierModel{CreatedBy: &userID}
+	if err := h.classifierRepo.CreateModel(model); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create classifier model", err)
+	}
+
+	payload, _ := json.Marshal(fiber.Map{"model_id": model.ID})
+	task := asynq.NewTask("classifier:train", payload)
+	info, err := h.asynqClient.Enqueue(task)
+	if err != nil {
+		h.failModel(model, err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to queue classifier training", err)
+	}
+
+	return utils.SuccessResponse(c, "Classifier training queued", fiber.Map{
+		"job_id": info.ID,
+		"model":  model,
+	})
+}
+
+// ActivateModel switches suggestions to another trained version, e.g. to roll back
+func (h *ClassifierHandler) ActivateModel(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid model ID", err)
+	}
+
+	model, err := h.classifierRepo.GetModelByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Classifier model not found", err)
+	}
+	if model.Status != models.ClassifierStatusReady {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Classifier version %d is %s, only trained versions can be activated", model.Version, model.Status), nil)
+	}
+
+	if err := h.classifierRepo.ActivateModel(model.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to activate classifier model", err)
+	}
+	model.IsActive = true
+
+	return utils.SuccessResponse(c, "Classifier model activated successfully", model)
+}
+
+// failModel marks a version that never reached the worker as failed
+func (h *ClassifierHandler) failModel(model *models.ClassifierModel, modelErr error) {
+	errMsg := modelErr.Error()
+	model.Status = models.ClassifierStatusFailed
+	model.ErrorMessage = &errMsg
+	h.classifierRepo.UpdateModel(model)
+}
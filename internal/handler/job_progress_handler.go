@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobProgressHandler struct {
+	jobProgressService *service.JobProgressService
+}
+
+func NewJobProgressHandler(jobProgressService *service.JobProgressService) *JobProgressHandler {
+	return &JobProgressHandler{
+		jobProgressService: jobProgressService,
+	}
+}
+
+// GetProgress returns the queue state of a job with the rows it processed, its
+// throughput and the estimated time left
+func (h *JobProgressHandler) GetProgress(c *fiber.Ctx) error {
+	if h.jobProgressService == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Background job processing is not available (Redis not connected)", nil)
+	}
+
+	progress, err := h.jobProgressService.GetProgress(c.Context(), c.Params("job_id"))
+	if errors.Is(err, service.ErrJobNotFound) {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Job not found", err)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve job progress", err)
+	}
+
+	return utils.SuccessResponse(c, "Job progress retrieved successfully", progress)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type KeywordSynonymHandler struct {
+	rulesRepo *repository.RulesRepository
+}
+
+func NewKeywordSynonymHandler(rulesRepo *repository.RulesRepository) *KeywordSynonymHandler {
+	return &KeywordSynonymHandler{
+		rulesRepo: rulesRepo,
+	}
+}
+
+func (h *KeywordSynonymHandler) GetKeywordSynonyms(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	synonyms, total, err := h.rulesRepo.GetKeywordSynonyms(params.Limit, offset, params.Search)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve keyword synonyms", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	responseData := fiber.Map{
+		"synonyms":   synonyms,
+		"pagination": pagination,
+	}
+
+	return utils.PaginatedResponseBuilder(c, "Keyword synonyms retrieved successfully", responseData, pagination)
+}
+
+func (h *KeywordSynonymHandler) GetKeywordSynonym(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid synonym ID", err)
+	}
+
+	synonym, err := h.rulesRepo.GetKeywordSynonymByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Keyword synonym not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Keyword synonym retrieved successfully", synonym)
+}
+
+func (h *KeywordSynonymHandler) CreateKeywordSynonym(c *fiber.Ctx) error {
+	var req models.KeywordSynonymRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateKeywordSynonymRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	synonym := &models.KeywordSynonym{
+		Term:      req.Term,
+		Canonical: req.Canonical,
+		IsActive:  true, // Default to active
+	}
+
+	if err := h.rulesRepo.CreateKeywordSynonym(synonym); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create keyword synonym", err)
+	}
+
+	return utils.SuccessResponse(c, "Keyword synonym created successfully", synonym)
+}
+
+func (h *KeywordSynonymHandler) UpdateKeywordSynonym(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid synonym ID", err)
+	}
+
+	var req models.KeywordSynonymRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateKeywordSynonymRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	synonym, err := h.rulesRepo.GetKeywordSynonymByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Keyword synonym not found", err)
+	}
+
+	synonym.Term = req.Term
+	synonym.Canonical = req.Canonical
+	synonym.IsActive = req.IsActive
+
+	if err := h.rulesRepo.UpdateKeywordSynonym(synonym); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update keyword synonym", err)
+	}
+
+	return utils.SuccessResponse(c, "Keyword synonym updated successfully", synonym)
+}
+
+func (h *KeywordSynonymHandler) DeleteKeywordSynonym(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid synonym ID", err)
+	}
+
+	if err := h.rulesRepo.DeleteKeywordSynonym(id); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete keyword synonym", err)
+	}
+
+	return utils.SuccessResponse(c, "Keyword synonym deleted successfully", nil)
+}
+
+// validateKeywordSynonymRequest trims the request and rejects terms that are empty or
+// the same once normalized, as the engine compares them
+func validateKeywordSynonymRequest(req *models.KeywordSynonymRequest) error {
+	req.Term = strings.TrimSpace(req.Term)
+	req.Canonical = strings.TrimSpace(req.Canonical)
+
+	term, canonical := service.NormalizeKeyterm(req.Term), service.NormalizeKeyterm(req.Canonical)
+	if term == "" {
+		return fmt.Errorf("Term must contain letters or digits")
+	}
+	if canonical == "" {
+		return fmt.Errorf("Canonical must contain letters or digits")
+	}
+	if term == canonical {
+		return fmt.Errorf("Term and canonical are the same once normalized (%q)", term)
+	}
+	return nil
+}
@@ -19,12 +19,14 @@ import (
 type KoreksiRuleHandler struct {
 	rulesRepo    *repository.RulesRepository
 	excelService *service.ExcelService
+	ruleAnalyzer *service.RuleAnalyzer
 }
 
 func NewKoreksiRuleHandler(rulesRepo *repository.RulesRepository) *KoreksiRuleHandler {
 	return &KoreksiRuleHandler{
 		rulesRepo:    rulesRepo,
 		excelService: service.NewExcelService(),
+		ruleAnalyzer: service.NewRuleAnalyzer(rulesRepo),
 	}
 }
 
@@ -68,15 +70,22 @@ func (h *KoreksiRuleHandler) CreateKoreksiRule(c *fiber.Ctx) error {
 	}
 
 	// Validation
-	if req.Keyword == "" || req.Value == "" {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Keyword and value are required", nil)
+	if req.Value == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Value is required", nil)
+	}
+	if err := service.ValidateRuleMatch(req.Keyword, req.Conditions.Condition); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule: "+err.Error(), err)
 	}
 
 	rule := &models.KoreksiRule{
-		Keyword:  req.Keyword,
-		Value:    req.Value,
-		NotValue: sql.NullString{String: req.NotValue, Valid: req.NotValue != ""},
-		IsActive: true, // Default to active
+		Keyword:    req.Keyword,
+		Value:      req.Value,
+		NotValue:   sql.NullString{String: req.NotValue, Valid: req.NotValue != ""},
+		Conditions: req.Conditions.Condition,
+		IsActive:   true, // Default to active
+	}
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
 	}
 
 	if err := h.rulesRepo.CreateKoreksiRule(rule); err != nil {
@@ -98,8 +107,8 @@ func (h *KoreksiRuleHandler) UpdateKoreksiRule(c *fiber.Ctx) error {
 	}
 
 	// Validation
-	if req.Keyword == "" || req.Value == "" {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Keyword and value are required", nil)
+	if req.Value == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Value is required", nil)
 	}
 
 	rule, err := h.rulesRepo.GetKoreksiRuleByID(id)
@@ -107,9 +116,20 @@ func (h *KoreksiRuleHandler) UpdateKoreksiRule(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Koreksi rule not found", err)
 	}
 
+	// Validate the conditions the rule keeps, which are the stored ones when the
+	// request leaves them out
+	conditions := req.Conditions.Or(rule.Conditions)
+	if err := service.ValidateRuleMatch(req.Keyword, conditions); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule: "+err.Error(), err)
+	}
+
 	rule.Keyword = req.Keyword
 	rule.Value = req.Value
 	rule.NotValue = sql.NullString{String: req.NotValue, Valid: req.NotValue != ""}
+	rule.Conditions = conditions
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
 	rule.IsActive = req.IsActive
 
 	if err := h.rulesRepo.UpdateKoreksiRule(rule); err != nil {
@@ -132,6 +152,40 @@ func (h *KoreksiRuleHandler) DeleteKoreksiRule(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Koreksi rule deleted successfully", nil)
 }
 
+// ReorderKoreksiRules bulk-updates rule priorities so precedence can change without re-creating rules
+func (h *KoreksiRuleHandler) ReorderKoreksiRules(c *fiber.Ctx) error {
+	var req models.RuleReorderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if len(req.Rules) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "At least one rule is required", nil)
+	}
+
+	seen := make(map[int]bool, len(req.Rules))
+	for _, update := range req.Rules {
+		if update.ID <= 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", nil)
+		}
+		if seen[update.ID] {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Rule %d is listed more than once", update.ID), nil)
+		}
+		seen[update.ID] = true
+	}
+
+	if err := h.rulesRepo.ReorderKoreksiRules(req.Rules); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error(), err)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reorder koreksi rules", err)
+	}
+
+	return utils.SuccessResponse(c, "Koreksi rules reordered successfully", fiber.Map{
+		"updated": len(req.Rules),
+	})
+}
+
 func (h *KoreksiRuleHandler) ExportKoreksiRules(c *fiber.Ctx) error {
 	// Get all active rules
 	rules, err := h.rulesRepo.GetAllActiveKoreksiRules()
@@ -163,12 +217,25 @@ func (h *KoreksiRuleHandler) DownloadTemplate(c *fiber.Ctx) error {
 			Keyword:  "PPH 21",
 			Value:    "21",
 			NotValue: sql.NullString{String: "", Valid: false},
+			Priority: 100,
 			IsActive: true,
 		},
 		{
 			Keyword:  "PPH 23",
 			Value:    "23",
 			NotValue: sql.NullString{String: "exclude", Valid: true},
+			Priority: 90,
+			IsActive: true,
+		},
+		{
+			Keyword:  "",
+			Value:    "Entertainment",
+			NotValue: sql.NullString{String: "", Valid: false},
+			Conditions: &models.RuleCondition{All: []models.RuleCondition{
+				{Field: models.ConditionFieldAccount, Op: "prefix", Operand: "6105"},
+				{Field: models.ConditionFieldKeterangan, Op: "regex", Operand: `\b(jamuan|entertain)\b`},
+			}},
+			Priority: 80,
 			IsActive: true,
 		},
 	}
@@ -210,6 +277,11 @@ func (h *KoreksiRuleHandler) ImportKoreksiRules(c *fiber.Ctx) error {
 	// Clean up temp file
 	defer os.Remove(tempPath)
 
+	// Reject rows that can never fire or contradict another rule
+	if err := h.analyzeImport(result); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to analyze imported koreksi rules", err)
+	}
+
 	// If there are no valid rules
 	if result.ValidCount == 0 {
 		// Generate error report
@@ -247,14 +319,15 @@ func (h *KoreksiRuleHandler) ImportKoreksiRules(c *fiber.Ctx) error {
 
 		// Return partial success with error details
 		return c.Status(fiber.StatusPartialContent).JSON(fiber.Map{
-			"success":            true,
-			"message":            fmt.Sprintf("Import completed with %d errors. %d koreksi rules imported successfully.", result.ErrorCount, result.ValidCount),
-			"total_rows":         result.TotalRows,
-			"valid_count":        result.ValidCount,
-			"error_count":        result.ErrorCount,
-			"errors":             getFirstNKoreksiRuleErrors(result.ValidationErrors, 10), // Limit to first 10 errors for readability
-			"error_report_path":  result.ErrorReportPath,
-			"total_imported":     result.ValidCount,
+			"success":           true,
+			"message":           fmt.Sprintf("Import completed with %d errors. %d koreksi rules imported successfully.", result.ErrorCount, result.ValidCount),
+			"total_rows":        result.TotalRows,
+			"valid_count":       result.ValidCount,
+			"error_count":       result.ErrorCount,
+			"errors":            getFirstNKoreksiRuleErrors(result.ValidationErrors, 10), // Limit to first 10 errors for readability
+			"error_report_path": result.ErrorReportPath,
+			"total_imported":    result.ValidCount,
+			"rule_analysis":     result.Analysis,
 		})
 	}
 
@@ -268,9 +341,56 @@ func (h *KoreksiRuleHandler) ImportKoreksiRules(c *fiber.Ctx) error {
 		"valid_count":    result.ValidCount,
 		"error_count":    result.ErrorCount,
 		"total_imported": result.ValidCount,
+		"rule_analysis":  result.Analysis,
 	})
 }
 
+// analyzeImport runs the rule analyzer over the imported rules together with the saved
+// ones. Rows with error findings (never firing, conflicting duplicates) become
+// validation errors; all findings are kept on the result for the response.
+func (h *KoreksiRuleHandler) analyzeImport(result *models.KoreksiRuleImportResult) error {
+	if len(result.ValidRules) == 0 {
+		return nil
+	}
+
+	findings, err := h.ruleAnalyzer.AnalyzeKoreksiImport(result.ValidRules, result.ValidRows)
+	if err != nil {
+		return err
+	}
+	result.Analysis = findings
+
+	rejected := map[int]bool{}
+	for _, f := range findings {
+		if f.Row == 0 || f.Severity != models.RuleFindingError {
+			continue
+		}
+		result.ValidationErrors = append(result.ValidationErrors, models.KoreksiRuleValidationError{
+			Row:     f.Row,
+			Field:   "Keyword",
+			Value:   f.Keyword,
+			Message: f.Message,
+		})
+		rejected[f.Row] = true
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	var rules []models.KoreksiRule
+	var rows []int
+	for i, rule := range result.ValidRules {
+		if !rejected[result.ValidRows[i]] {
+			rules = append(rules, rule)
+			rows = append(rows, result.ValidRows[i])
+		}
+	}
+	result.ValidRules = rules
+	result.ValidRows = rows
+	result.ValidCount = len(rules)
+	result.ErrorCount += len(rejected)
+	return nil
+}
+
 // DownloadErrorReport downloads an error report file
 func (h *KoreksiRuleHandler) DownloadErrorReport(c *fiber.Ctx) error {
 	filename := c.Params("filename")
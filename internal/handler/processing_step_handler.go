@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProcessingStepHandler struct {
+	stepRepo *repository.ProcessingStepRepository
+}
+
+func NewProcessingStepHandler(stepRepo *repository.ProcessingStepRepository) *ProcessingStepHandler {
+	return &ProcessingStepHandler{
+		stepRepo: stepRepo,
+	}
+}
+
+// GetSteps lists every registered step with its settings, in pipeline order
+func (h *ProcessingStepHandler) GetSteps(c *fiber.Ctx) error {
+	stored, err := h.stepRepo.GetSteps()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve processing steps", err)
+	}
+
+	return utils.SuccessResponse(c, "Processing steps retrieved successfully", service.ProcessingStepSettings(stored))
+}
+
+// UpdateStep enables or disables a step and sets its order and config. The next
+// processing run uses the new settings.
+func (h *ProcessingStepHandler) UpdateStep(c *fiber.Ctx) error {
+	userID, _, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	var req models.ProcessingStepRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	name := c.Params("name")
+	if err := service.ValidateProcessingStep(name, req.Config); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	step := &models.ProcessingStep{
+		Name:      name,
+		Enabled:   req.Enabled,
+		StepOrder: req.StepOrder,
+		Config:    req.Config,
+		UpdatedBy: &userID,
+	}
+	if err := h.stepRepo.SaveStep(step); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update processing step", err)
+	}
+
+	return utils.SuccessResponse(c, "Processing step updated successfully", step)
+}
+
+// GetRunStepStats returns the time each step took and the fields it changed in a run
+func (h *ProcessingStepHandler) GetRunStepStats(c *fiber.Ctx) error {
+	runID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid run ID", err)
+	}
+
+	stats, err := h.stepRepo.GetStepStatsByRun(runID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve step stats", err)
+	}
+
+	return utils.SuccessResponse(c, "Step stats retrieved successfully", stats)
+}
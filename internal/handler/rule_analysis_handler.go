@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RuleAnalysisHandler struct {
+	ruleAnalyzer *service.RuleAnalyzer
+}
+
+func NewRuleAnalysisHandler(ruleAnalyzer *service.RuleAnalyzer) *RuleAnalysisHandler {
+	return &RuleAnalysisHandler{
+		ruleAnalyzer: ruleAnalyzer,
+	}
+}
+
+// AnalyzeRules reports shadowed rules, duplicate keywords, keywords excluded by a
+// not_value list and inactive duplicates. ?type=koreksi or ?type=obyek limits the
+// analysis to one rule type; ?severity=error|warning|info filters the findings.
+func (h *RuleAnalysisHandler) AnalyzeRules(c *fiber.Ctx) error {
+	ruleType := c.Query("type")
+	if ruleType != "" && ruleType != models.RuleTypeKoreksi && ruleType != models.RuleTypeObyek {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Type must be koreksi or obyek", nil)
+	}
+
+	report, err := h.ruleAnalyzer.Analyze(ruleType)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to analyze rules", err)
+	}
+
+	if severity := c.Query("severity"); severity != "" {
+		findings := []models.RuleFinding{}
+		for _, f := range report.Findings {
+			if f.Severity == severity {
+				findings = append(findings, f)
+			}
+		}
+		report.Findings = findings
+	}
+
+	return utils.SuccessResponse(c, "Rules analyzed successfully", report)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type RuleCacheHandler struct {
+	redis *redis.Client
+}
+
+func NewRuleCacheHandler(redis *redis.Client) *RuleCacheHandler {
+	return &RuleCacheHandler{
+		redis: redis,
+	}
+}
+
+// GetStatus returns the current rule-set version and the version every web and
+// worker process holds
+func (h *RuleCacheHandler) GetStatus(c *fiber.Ctx) error {
+	if h.redis == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Rule cache requires Redis", fmt.Errorf("redis is not configured"))
+	}
+
+	status, err := service.GetRuleCacheStatus(c.Context(), h.redis)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve rule cache status", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule cache status retrieved successfully", status)
+}
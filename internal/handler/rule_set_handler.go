@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
+)
+
+type RuleSetHandler struct {
+	uploadRepo     *repository.UploadRepository
+	ruleSetRepo    *repository.RuleSetRepository
+	ruleSetService *service.RuleSetService
+	asynqClient    *asynq.Client
+}
+
+func NewRuleSetHandler(
+	uploadRepo *repository.UploadRepository,
+	ruleSetRepo *repository.RuleSetRepository,
+	ruleSetService *service.RuleSetService,
+	asynqClient *asynq.Client,
+) *RuleSetHandler {
+	return &RuleSetHandler{
+		uploadRepo:     uploadRepo,
+		ruleSetRepo:    ruleSetRepo,
+		ruleSetService: ruleSetService,
+		asynqClient:    asynqClient,
+	}
+}
+
+// GetVersions lists stored rule-set versions, newest first
+func (h *RuleSetHandler) GetVersions(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	versions, total, err := h.ruleSetRepo.GetVersions(params.Limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve rule set versions", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	return utils.PaginatedResponseBuilder(c, "Rule set versions retrieved successfully", fiber.Map{
+		"versions":   versions,
+		"pagination": pagination,
+	}, pagination)
+}
+
+// GetVersion returns a single version including its frozen rules
+func (h *RuleSetHandler) GetVersion(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid version ID", err)
+	}
+
+	version, snapshot, err := h.ruleSetService.LoadVersion(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Rule set version not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule set version retrieved successfully", fiber.Map{
+		"version": version,
+		"rules":   snapshot,
+	})
+}
+
+// CreateVersion freezes the currently active rules into a new version
+func (h *RuleSetHandler) CreateVersion(c *fiber.Ctx) error {
+	userID, _, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+		}
+	}
+
+	version, _, err := h.ruleSetService.CaptureActive(&userID, req.Note)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create rule set version", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule set version created successfully", version)
+}
+
+// GetSessionRuns lists the processing runs of a session and the rule-set version each used
+func (h *RuleSetHandler) GetSessionRuns(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	runs, err := h.ruleSetRepo.GetRunsBySessionID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve processing runs", err)
+	}
+
+	return utils.SuccessResponse(c, "Processing runs retrieved successfully", runs)
+}
+
+// ReprocessSession queues a session again, optionally against a historical rule-set
+// version; the worker clears the outputs of the selected rows when the run starts
+func (h *RuleSetHandler) ReprocessSession(c *fiber.Ctx) error {
+	userID, role, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	var req models.ReprocessRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+		}
+	}
+
+	session, err := h.uploadRepo.GetSessionByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	if role != "admin" && session.UserID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only reprocess your own sessions", nil)
+	}
+	if session.Status == "processing" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is already being processed", nil)
+	}
+
+	if req.RuleSetVersionID > 0 {
+		if _, err := h.ruleSetRepo.GetVersionByID(req.RuleSetVersionID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Rule set version not found", err)
+		}
+	}
+
+	if h.asynqClient == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Background job processing is not available (Redis not connected)", nil)
+	}
+
+	// Only count the rows here; the worker resets them when it picks the run up, so
+	// a run that never gets queued leaves the current results in place
+	matchedRows, err := h.uploadRepo.CountReprocessRows(session.SessionCode, req.Filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count rows to reprocess", err)
+	}
+	if matchedRows == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "No rows match the reprocess filter", nil)
+	}
+
+	// Rows outside the filter keep their results and still count as processed. The
+	// conditional update makes sure only one request starts a run for the session.
+	previous := *session
+	processedRows := session.TotalRows - int(matchedRows)
+	if processedRows < 0 {
+		processedRows = 0
+	}
+	started, err := h.uploadRepo.StartSessionReprocess(session.ID, processedRows)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update session status", err)
+	}
+	if !started {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Session is already being processed", nil)
+	}
+	session.Status = "processing"
+	session.ProcessedRows = processedRows
+	session.FailedRows = 0
+	session.ErrorMessage = nil
+
+	// The worker snapshots the current results against this run before resetting them
+	run := &models.ProcessingRun{
+		SessionID:   session.ID,
+		SessionCode: session.SessionCode,
+		RunType:     "reprocess",
+		Status:      "queued",
+		TriggeredBy: &userID,
+	}
+	if req.RuleSetVersionID > 0 {
+		run.RuleSetVersionID = &req.RuleSetVersionID
+	}
+	if !req.Filter.IsEmpty() {
+		run.ResetFilter = req.Filter
+	}
+	if err := h.ruleSetRepo.CreateRun(run); err != nil {
+		h.uploadRepo.UpdateSession(&previous)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record processing run", err)
+	}
+
+	payload, _ := json.Marshal(fiber.Map{
+		"session_id":          session.ID,
+		"session_code":        session.SessionCode,
+		"rule_set_version_id": req.RuleSetVersionID,
+		"run_type":            "reprocess",
+		"run_id":              run.ID,
+		"triggered_by":        userID,
+	})
+
+	task := asynq.NewTask("transaction:process", payload)
+	info, err := h.asynqClient.Enqueue(task)
+	if err != nil {
+		// Nothing was reset yet; put the session back the way it was
+		h.uploadRepo.UpdateSession(&previous)
+		h.failRun(run, err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to queue processing task", err)
+	}
+
+	return utils.SuccessResponse(c, "Reprocessing started", fiber.Map{
+		"job_id":              info.ID,
+		"run_id":              run.ID,
+		"session":             session,
+		"reset_rows":          matchedRows,
+		"rule_set_version_id": req.RuleSetVersionID,
+	})
+}
+
+// GetRun returns a single processing run including its before/after change summary
+func (h *RuleSetHandler) GetRun(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid run ID", err)
+	}
+
+	run, err := h.ruleSetRepo.GetRunByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Processing run not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Processing run retrieved successfully", run)
+}
+
+// failRun marks a run that never reached the worker as failed
+func (h *RuleSetHandler) failRun(run *models.ProcessingRun, runErr error) {
+	finishedAt := time.Now()
+	errMsg := runErr.Error()
+	run.Status = "failed"
+	run.ErrorMessage = &errMsg
+	run.FinishedAt = &finishedAt
+	h.ruleSetRepo.UpdateRun(run)
+}
+
+// currentUser reads the authenticated user ID and role set by AuthMiddleware
+func currentUser(c *fiber.Ctx) (int, string, error) {
+	userIDInterface := c.Locals("user_id")
+	if userIDInterface == nil {
+		return 0, "", fmt.Errorf("User not authenticated")
+	}
+
+	var userID int
+	switch v := userIDInterface.(type) {
+	case int:
+		userID = v
+	case float64:
+		userID = int(v)
+	case string:
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, "", fmt.Errorf("Invalid user ID format")
+		}
+		userID = id
+	default:
+		return 0, "", fmt.Errorf("Invalid user ID type")
+	}
+
+	var role string
+	if roleInterface := c.Locals("role"); roleInterface != nil {
+		role = fmt.Sprintf("%v", roleInterface)
+	}
+
+	return userID, role, nil
+}
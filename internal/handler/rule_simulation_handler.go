@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RuleSimulationHandler struct {
+	uploadRepo        *repository.UploadRepository
+	simulationService *service.RuleSimulationService
+}
+
+func NewRuleSimulationHandler(uploadRepo *repository.UploadRepository, simulationService *service.RuleSimulationService) *RuleSimulationHandler {
+	return &RuleSimulationHandler{
+		uploadRepo:        uploadRepo,
+		simulationService: simulationService,
+	}
+}
+
+// SimulateSession runs a dry-run of the proposed rule changes over a session.
+// Nothing is persisted; the response lists the rows that would change. Long
+// sessions are only simulated up to max_scan_rows rows.
+func (h *RuleSimulationHandler) SimulateSession(c *fiber.Ctx) error {
+	userID, role, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	sessionCode := c.Params("session_code")
+	if sessionCode == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session code is required", nil)
+	}
+
+	var req models.RuleSimulationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+		}
+	}
+
+	session, err := h.uploadRepo.GetSessionByCode(sessionCode)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+	if role != "admin" && session.UserID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only simulate rules on your own sessions", nil)
+	}
+
+	result, err := h.simulationService.Simulate(sessionCode, req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "required") ||
+			strings.Contains(err.Error(), "invalid rule") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to simulate rules", err)
+	}
+	if result.TotalRows == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "No transactions found for this session", nil)
+	}
+
+	return utils.SuccessResponse(c, "Rule simulation completed", result)
+}
+
+// TestRules runs a single keterangan (with optional account, amounts and posting date)
+// through the active rules and shows its normalized form, the outputs and, for the
+// given keywords, whether and through which synonym variant they match
+func (h *RuleSimulationHandler) TestRules(c *fiber.Ctx) error {
+	var req models.RuleTestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if strings.TrimSpace(req.Keterangan) == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Keterangan is required", nil)
+	}
+
+	result, err := h.simulationService.TestRow(req)
+	if err != nil {
+		if strings.Contains(err.Error(), "posting_date") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to test rules", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule test completed", result)
+}
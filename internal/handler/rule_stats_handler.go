@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxUnmatchedKeterangan = 500
+
+type RuleStatsHandler struct {
+	ruleStatsRepo *repository.RuleStatsRepository
+}
+
+func NewRuleStatsHandler(ruleStatsRepo *repository.RuleStatsRepository) *RuleStatsHandler {
+	return &RuleStatsHandler{
+		ruleStatsRepo: ruleStatsRepo,
+	}
+}
+
+// GetRunRuleHits returns how many rows each rule filled in one processing run
+func (h *RuleStatsHandler) GetRunRuleHits(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid run ID", err)
+	}
+
+	stats, err := h.ruleStatsRepo.GetRuleHitStatsByRun(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve rule hits", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule hits retrieved successfully", stats)
+}
+
+// GetRuleHitSummary totals rule hits over the runs between ?from and ?to (YYYY-MM-DD,
+// both inclusive and optional), optionally for one ?type. Active rules without hits
+// are listed with zero.
+func (h *RuleStatsHandler) GetRuleHitSummary(c *fiber.Ctx) error {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+	if to != nil {
+		// Runs are recorded with a timestamp; include the whole last day
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	summary, err := h.ruleStatsRepo.GetRuleHitSummary(c.Query("type"), from, to)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve rule hit summary", err)
+	}
+
+	unused := 0
+	for _, s := range summary {
+		if s.Hits == 0 {
+			unused++
+		}
+	}
+
+	return utils.SuccessResponse(c, "Rule hit summary retrieved successfully", fiber.Map{
+		"rules":        summary,
+		"unused_rules": unused,
+	})
+}
+
+// GetUnmatchedKeterangan lists the most frequent keterangan that matched no koreksi
+// or obyek rule, for a ?session_code and/or a posting date range (?from, ?to)
+func (h *RuleStatsHandler) GetUnmatchedKeterangan(c *fiber.Ctx) error {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	filter := models.UnmatchedKeteranganFilter{
+		SessionCode: c.Query("session_code"),
+		From:        from,
+		To:          to,
+		Limit:       c.QueryInt("limit", 50),
+	}
+	if filter.SessionCode == "" && filter.From == nil && filter.To == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "session_code or a from/to date range is required", nil)
+	}
+	if filter.Limit < 1 || filter.Limit > maxUnmatchedKeterangan {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxUnmatchedKeterangan), nil)
+	}
+
+	rows, err := h.ruleStatsRepo.GetUnmatchedKeterangan(filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve unmatched keterangan", err)
+	}
+
+	return utils.SuccessResponse(c, "Unmatched keterangan retrieved successfully", rows)
+}
+
+// parseDateRange reads the optional ?from and ?to dates (YYYY-MM-DD)
+func parseDateRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	parse := func(name string) (*time.Time, error) {
+		value := c.Query(name)
+		if value == "" {
+			return nil, nil
+		}
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+		}
+		return &date, nil
+	}
+
+	from, err := parse("from")
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parse("to")
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, nil, fmt.Errorf("to cannot be before from")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxRuleSuggestions = 200
+
+type RuleSuggestionHandler struct {
+	suggestionService *service.RuleSuggestionService
+	rulesRepo         *repository.RulesRepository
+	ruleAnalyzer      *service.RuleAnalyzer
+}
+
+func NewRuleSuggestionHandler(suggestionService *service.RuleSuggestionService, rulesRepo *repository.RulesRepository, ruleAnalyzer *service.RuleAnalyzer) *RuleSuggestionHandler {
+	return &RuleSuggestionHandler{
+		suggestionService: suggestionService,
+		rulesRepo:         rulesRepo,
+		ruleAnalyzer:      ruleAnalyzer,
+	}
+}
+
+// GetSuggestions proposes ?type=koreksi|obyek keywords mined from the unclassified rows
+// of a ?session_code and/or posting date range (?from, ?to). ?min_support (default 2)
+// and ?min_confidence (0-1) filter the suggestions, ?limit caps them.
+func (h *RuleSuggestionHandler) GetSuggestions(c *fiber.Ctx) error {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	filter := models.RuleSuggestionFilter{
+		RuleType:      c.Query("type", models.RuleTypeKoreksi),
+		SessionCode:   c.Query("session_code"),
+		From:          from,
+		To:            to,
+		MinSupport:    c.QueryInt("min_support", 2),
+		MinConfidence: c.QueryFloat("min_confidence", 0),
+		Limit:         c.QueryInt("limit", 50),
+	}
+	if filter.RuleType != models.RuleTypeKoreksi && filter.RuleType != models.RuleTypeObyek {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Type must be koreksi or obyek", nil)
+	}
+	if filter.SessionCode == "" && filter.From == nil && filter.To == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "session_code or a from/to date range is required", nil)
+	}
+	if filter.MinSupport < 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "min_support must be at least 1", nil)
+	}
+	if filter.MinConfidence < 0 || filter.MinConfidence > 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "min_confidence must be between 0 and 1", nil)
+	}
+	if filter.Limit < 1 || filter.Limit > maxRuleSuggestions {
+		filter.Limit = 50
+	}
+
+	suggestions, err := h.suggestionService.Suggest(filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to build rule suggestions", err)
+	}
+
+	return utils.SuccessResponse(c, "Rule suggestions retrieved successfully", suggestions)
+}
+
+// AcceptSuggestion creates the koreksi or obyek rule of a suggestion, validated like a
+// rule created by hand. The response lists the analyzer findings involving the new rule.
+func (h *RuleSuggestionHandler) AcceptSuggestion(c *fiber.Ctx) error {
+	var req models.AcceptRuleSuggestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Value == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Value is required", nil)
+	}
+	if err := service.ValidateRuleMatch(req.Keyword, nil); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule: "+err.Error(), err)
+	}
+	notValue := sql.NullString{String: req.NotValue, Valid: req.NotValue != ""}
+
+	var rule interface{}
+	var ruleID int
+	switch req.RuleType {
+	case models.RuleTypeKoreksi:
+		koreksiRule := &models.KoreksiRule{
+			Keyword:  req.Keyword,
+			Value:    req.Value,
+			NotValue: notValue,
+			Priority: req.Priority,
+			IsActive: true,
+		}
+		if err := h.rulesRepo.CreateKoreksiRule(koreksiRule); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create koreksi rule", err)
+		}
+		rule, ruleID = koreksiRule, koreksiRule.ID
+	case models.RuleTypeObyek:
+		obyekRule := &models.ObyekRule{
+			Keyword:  req.Keyword,
+			Value:    req.Value,
+			NotValue: notValue,
+			Priority: req.Priority,
+			IsActive: true,
+		}
+		if err := h.rulesRepo.CreateObyekRule(obyekRule); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create obyek rule", err)
+		}
+		rule, ruleID = obyekRule, obyekRule.ID
+	default:
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Rule type must be koreksi or obyek", nil)
+	}
+
+	// The rule is created either way; findings only tell the admin what to review
+	findings := []models.RuleFinding{}
+	if report, err := h.ruleAnalyzer.Analyze(req.RuleType); err == nil {
+		for _, f := range report.Findings {
+			if f.RuleID == ruleID || f.RelatedRuleID == ruleID {
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	return utils.SuccessResponse(c, "Rule suggestion accepted successfully", fiber.Map{
+		"rule":          rule,
+		"rule_analysis": findings,
+	})
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionEventsHeartbeat keeps idle streams open through proxies that drop silent
+// connections
+const sessionEventsHeartbeat = 15 * time.Second
+
+type SessionEventsHandler struct {
+	hub        *service.SessionEventHub
+	uploadRepo *repository.UploadRepository
+}
+
+func NewSessionEventsHandler(hub *service.SessionEventHub, uploadRepo *repository.UploadRepository) *SessionEventsHandler {
+	return &SessionEventsHandler{
+		hub:        hub,
+		uploadRepo: uploadRepo,
+	}
+}
+
+// IssueStreamToken returns a short-lived, single-use token that opens the event stream
+// of a session the caller may follow
+func (h *SessionEventsHandler) IssueStreamToken(c *fiber.Ctx) error {
+	if h.hub == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Live progress is not available (Redis not connected)", nil)
+	}
+
+	userID, role, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	sessionCode := c.Params("session_code")
+	session, err := h.uploadRepo.GetSessionByCode(sessionCode)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+	if role != "admin" && session.UserID != userID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only follow your own sessions", nil)
+	}
+
+	token, err := h.hub.IssueStreamToken(c.Context(), service.SessionStreamGrant{
+		UserID:      userID,
+		Role:        role,
+		SessionCode: sessionCode,
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to issue stream token", err)
+	}
+
+	return utils.SuccessResponse(c, "Stream token issued", fiber.Map{
+		"stream_token": token,
+		"expires_in":   int(service.SessionStreamTokenTTL.Seconds()),
+	})
+}
+
+// StreamEvents streams the progress, status changes and batch errors of a session as
+// Server-Sent Events. It is opened with a stream token from IssueStreamToken, which
+// is used up by the request. A client reconnecting with Last-Event-ID (or the
+// last_event_id parameter) first receives the events it missed; a new client starts
+// with the latest event, or the stored state of the session when nothing was
+// published yet.
+func (h *SessionEventsHandler) StreamEvents(c *fiber.Ctx) error {
+	if h.hub == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Live progress is not available (Redis not connected)", nil)
+	}
+
+	sessionCode := c.Params("session_code")
+	grant, err := h.hub.RedeemStreamToken(c.Context(), c.Query("stream_token"), sessionCode)
+	if errors.Is(err, service.ErrStreamTokenInvalid) {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check stream token", err)
+	}
+
+	session, err := h.uploadRepo.GetSessionByCode(sessionCode)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+	if grant.Role != "admin" && session.UserID != grant.UserID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only follow your own sessions", nil)
+	}
+
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	sub := h.hub.Subscribe(sessionCode)
+	replay, err := h.hub.Replay(c.Context(), sessionCode, lastEventID)
+	if err != nil {
+		sub.Close()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read session events", err)
+	}
+	if len(replay) == 0 && lastEventID == "" {
+		replay = append(replay, sessionStateEvent(session))
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+		lastSent := lastEventID
+		for _, event := range replay {
+			writeSessionEvent(w, event)
+			if event.ID != "" {
+				lastSent = event.ID
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sessionEventsHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					return // fell behind; the client reconnects and replays
+				}
+				if !service.SessionEventAfter(event.ID, lastSent) {
+					continue // already sent with the replay
+				}
+				writeSessionEvent(w, event)
+				lastSent = event.ID
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix())
+			}
+			if err := w.Flush(); err != nil {
+				return // client disconnected
+			}
+		}
+	})
+	return nil
+}
+
+// sessionStateEvent describes the stored state of a session for a client connecting
+// before any event was published
+func sessionStateEvent(session *models.UploadSession) models.SessionEvent {
+	event := models.SessionEvent{
+		SessionCode:   session.SessionCode,
+		Type:          "status",
+		Status:        session.Status,
+		TotalRows:     session.TotalRows,
+		ProcessedRows: session.ProcessedRows,
+		FailedRows:    session.FailedRows,
+		At:            time.Now(),
+	}
+	if session.TotalRows > 0 {
+		event.ProgressPercentage = float64(session.ProcessedRows) / float64(session.TotalRows) * 100
+	}
+	if session.ErrorMessage != nil {
+		event.Error = *session.ErrorMessage
+	}
+	return event
+}
+
+func writeSessionEvent(w *bufio.Writer, event models.SessionEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if event.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
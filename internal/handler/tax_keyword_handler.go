@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TaxKeywordHandler struct {
+	rulesRepo *repository.RulesRepository
+}
+
+func NewTaxKeywordHandler(rulesRepo *repository.RulesRepository) *TaxKeywordHandler {
+	return &TaxKeywordHandler{
+		rulesRepo: rulesRepo,
+	}
+}
+
+func (h *TaxKeywordHandler) GetTaxKeywords(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	keywords, total, err := h.rulesRepo.GetTaxKeywords(params.Limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve tax keywords", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	responseData := fiber.Map{
+		"keywords":   keywords,
+		"pagination": pagination,
+	}
+
+	return utils.PaginatedResponseBuilder(c, "Tax keywords retrieved successfully", responseData, pagination)
+}
+
+func (h *TaxKeywordHandler) GetTaxKeyword(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid keyword ID", err)
+	}
+
+	keyword, err := h.rulesRepo.GetTaxKeywordByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tax keyword not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Tax keyword retrieved successfully", keyword)
+}
+
+func (h *TaxKeywordHandler) CreateTaxKeyword(c *fiber.Ctx) error {
+	var req models.TaxKeywordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateTaxKeywordRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+	validFrom, validTo, err := service.ParseValidityWindow(req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	keyword := &models.TaxKeyword{
+		Keyword:     req.Keyword,
+		TaxCategory: req.TaxCategory,
+		ValidFrom:   validFrom,
+		ValidTo:     validTo,
+		Priority:    req.Priority,
+		IsActive:    true, // Default to active
+	}
+
+	overlap, err := h.findOverlap(keyword)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check validity overlap", err)
+	}
+	if overlap != nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, taxKeywordOverlapMessage(keyword, overlap), nil)
+	}
+
+	if err := h.rulesRepo.CreateTaxKeyword(keyword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create tax keyword", err)
+	}
+
+	return utils.SuccessResponse(c, "Tax keyword created successfully", keyword)
+}
+
+func (h *TaxKeywordHandler) UpdateTaxKeyword(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid keyword ID", err)
+	}
+
+	var req models.TaxKeywordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateTaxKeywordRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+	validFrom, validTo, err := service.ParseValidityWindow(req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	keyword, err := h.rulesRepo.GetTaxKeywordByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Tax keyword not found", err)
+	}
+
+	keyword.Keyword = req.Keyword
+	keyword.TaxCategory = req.TaxCategory
+	keyword.ValidFrom = validFrom
+	keyword.ValidTo = validTo
+	keyword.Priority = req.Priority
+	keyword.IsActive = req.IsActive
+
+	overlap, err := h.findOverlap(keyword)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check validity overlap", err)
+	}
+	if overlap != nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, taxKeywordOverlapMessage(keyword, overlap), nil)
+	}
+
+	if err := h.rulesRepo.UpdateTaxKeyword(keyword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update tax keyword", err)
+	}
+
+	return utils.SuccessResponse(c, "Tax keyword updated successfully", keyword)
+}
+
+func (h *TaxKeywordHandler) DeleteTaxKeyword(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid keyword ID", err)
+	}
+
+	if err := h.rulesRepo.DeleteTaxKeyword(id); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete tax keyword", err)
+	}
+
+	return utils.SuccessResponse(c, "Tax keyword deleted successfully", nil)
+}
+
+// findOverlap returns another active version of the keyword (same keyword and category)
+// whose validity window overlaps the keyword's, or nil
+func (h *TaxKeywordHandler) findOverlap(keyword *models.TaxKeyword) (*models.TaxKeyword, error) {
+	if !keyword.IsActive {
+		return nil, nil
+	}
+
+	overlapping, err := h.rulesRepo.GetOverlappingTaxKeywords(keyword)
+	if err != nil || len(overlapping) == 0 {
+		return nil, err
+	}
+	return &overlapping[0], nil
+}
+
+func taxKeywordOverlapMessage(keyword, overlap *models.TaxKeyword) string {
+	return fmt.Sprintf("Validity %s overlaps tax keyword #%d (%s)",
+		service.FormatValidityWindow(keyword.ValidFrom, keyword.ValidTo), overlap.ID,
+		service.FormatValidityWindow(overlap.ValidFrom, overlap.ValidTo))
+}
+
+func validateTaxKeywordRequest(req *models.TaxKeywordRequest) error {
+	if req.Keyword == "" {
+		return fmt.Errorf("Keyword is required")
+	}
+	if req.TaxCategory != "input_tax" && req.TaxCategory != "output_tax" {
+		return fmt.Errorf("Tax category must be input_tax or output_tax")
+	}
+	return nil
+}
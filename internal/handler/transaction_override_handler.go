@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TransactionOverrideHandler struct {
+	overrideService *service.TransactionOverrideService
+}
+
+func NewTransactionOverrideHandler(overrideService *service.TransactionOverrideService) *TransactionOverrideHandler {
+	return &TransactionOverrideHandler{
+		overrideService: overrideService,
+	}
+}
+
+// ResetOverride drops the manual override of koreksi and/or obyek and restores the
+// rule-derived value. Without a body both fields are reset.
+func (h *TransactionOverrideHandler) ResetOverride(c *fiber.Ctx) error {
+	userID, role, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), nil)
+	}
+
+	transactionID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transaction ID", err)
+	}
+
+	var req struct {
+		Fields []string `json:"fields"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+		}
+	}
+	if len(req.Fields) == 0 {
+		req.Fields = []string{service.OverrideFieldKoreksi, service.OverrideFieldObyek}
+	}
+
+	transaction, err := h.overrideService.ResetToRuleValue(transactionID, req.Fields, userID, role)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "unknown override field"):
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+		case strings.Contains(err.Error(), "access denied"):
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error(), nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset override", err)
+	}
+
+	return utils.SuccessResponse(c, "Override reset to rule-derived value", transaction)
+}
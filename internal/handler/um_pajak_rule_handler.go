@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UmPajakRuleHandler struct {
+	rulesRepo    *repository.RulesRepository
+	excelService *service.ExcelService
+}
+
+func NewUmPajakRuleHandler(rulesRepo *repository.RulesRepository) *UmPajakRuleHandler {
+	return &UmPajakRuleHandler{
+		rulesRepo:    rulesRepo,
+		excelService: service.NewExcelService(),
+	}
+}
+
+func (h *UmPajakRuleHandler) GetUmPajakRules(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	rules, total, err := h.rulesRepo.GetUmPajakRules(params.Limit, offset, params.Search)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve UM Pajak rules", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	responseData := fiber.Map{
+		"rules":      rules,
+		"pagination": pagination,
+	}
+
+	return utils.PaginatedResponseBuilder(c, "UM Pajak rules retrieved successfully", responseData, pagination)
+}
+
+func (h *UmPajakRuleHandler) GetUmPajakRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	rule, err := h.rulesRepo.GetUmPajakRuleByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "UM Pajak rule not found", err)
+	}
+
+	return utils.SuccessResponse(c, "UM Pajak rule retrieved successfully", rule)
+}
+
+func (h *UmPajakRuleHandler) CreateUmPajakRule(c *fiber.Ctx) error {
+	var req models.UmPajakRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	debitCondition, err := validateUmPajakRuleRequest(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	rule := &models.UmPajakRule{
+		Keyword:        req.Keyword,
+		AccountPattern: sql.NullString{String: req.AccountPattern, Valid: req.AccountPattern != ""},
+		DebitCondition: debitCondition,
+		TaxType:        sql.NullString{String: req.TaxType, Valid: req.TaxType != ""},
+		Priority:       req.Priority,
+		IsActive:       true, // Default to active
+	}
+
+	if err := h.rulesRepo.CreateUmPajakRule(rule); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create UM Pajak rule", err)
+	}
+
+	return utils.SuccessResponse(c, "UM Pajak rule created successfully", rule)
+}
+
+func (h *UmPajakRuleHandler) UpdateUmPajakRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	var req models.UmPajakRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	debitCondition, err := validateUmPajakRuleRequest(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	rule, err := h.rulesRepo.GetUmPajakRuleByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "UM Pajak rule not found", err)
+	}
+
+	rule.Keyword = req.Keyword
+	rule.AccountPattern = sql.NullString{String: req.AccountPattern, Valid: req.AccountPattern != ""}
+	rule.DebitCondition = debitCondition
+	rule.TaxType = sql.NullString{String: req.TaxType, Valid: req.TaxType != ""}
+	rule.Priority = req.Priority
+	rule.IsActive = req.IsActive
+
+	if err := h.rulesRepo.UpdateUmPajakRule(rule); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update UM Pajak rule", err)
+	}
+
+	return utils.SuccessResponse(c, "UM Pajak rule updated successfully", rule)
+}
+
+func (h *UmPajakRuleHandler) DeleteUmPajakRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	if err := h.rulesRepo.DeleteUmPajakRule(id); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete UM Pajak rule", err)
+	}
+
+	return utils.SuccessResponse(c, "UM Pajak rule deleted successfully", nil)
+}
+
+func (h *UmPajakRuleHandler) ExportUmPajakRules(c *fiber.Ctx) error {
+	// Get all active rules
+	rules, err := h.rulesRepo.GetActiveUmPajakRules()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve UM Pajak rules", err)
+	}
+
+	// Generate export filename
+	exportFileName := fmt.Sprintf("um_pajak_rules_export_%s.xlsx", time.Now().Format("20060102_150405"))
+	exportPath := filepath.Join("./storage/exports", exportFileName)
+
+	// Export to Excel
+	if err := h.excelService.ExportUmPajakRules(rules, exportPath); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to export UM Pajak rules", err)
+	}
+
+	// Send file
+	return c.Download(exportPath, exportFileName)
+}
+
+func (h *UmPajakRuleHandler) DownloadTemplate(c *fiber.Ctx) error {
+	// Generate template filename
+	templateFileName := "um_pajak_rules_import_template.xlsx"
+	templatePath := filepath.Join("./storage/exports", templateFileName)
+
+	// Create template with sample data
+	sampleRules := []models.UmPajakRule{
+		{
+			Keyword:        "PPh 25",
+			AccountPattern: sql.NullString{String: "1151*", Valid: true},
+			DebitCondition: models.DebitConditionPositive,
+			TaxType:        sql.NullString{String: "PPh 25", Valid: true},
+			Priority:       100,
+			IsActive:       true,
+		},
+		{
+			Keyword:        "PPh 22",
+			AccountPattern: sql.NullString{String: "", Valid: false},
+			DebitCondition: models.DebitConditionPositive,
+			TaxType:        sql.NullString{String: "PPh 22", Valid: true},
+			Priority:       90,
+			IsActive:       true,
+		},
+	}
+
+	// Export template
+	if err := h.excelService.ExportUmPajakRules(sampleRules, templatePath); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate template", err)
+	}
+
+	// Send file
+	return c.Download(templatePath, templateFileName)
+}
+
+func (h *UmPajakRuleHandler) ImportUmPajakRules(c *fiber.Ctx) error {
+	// Get uploaded file
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "File is required", err)
+	}
+
+	// Validate file type
+	ext := filepath.Ext(file.Filename)
+	if ext != ".xlsx" && ext != ".xls" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only Excel files (.xlsx, .xls) are allowed", nil)
+	}
+
+	// Save file temporarily
+	tempPath := filepath.Join("./storage/temp", fmt.Sprintf("import_%d%s", time.Now().Unix(), ext))
+	if err := c.SaveFile(file, tempPath); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save file", err)
+	}
+	defer os.Remove(tempPath)
+
+	// Parse Excel file with validation
+	result, err := h.excelService.ParseUmPajakRulesWithValidation(tempPath)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to parse Excel file: "+err.Error(), err)
+	}
+
+	// If there are no valid rules
+	if result.ValidCount == 0 {
+		// Generate error report
+		if len(result.ValidationErrors) > 0 {
+			errorReportPath := filepath.Join("./storage/exports", fmt.Sprintf("import_errors_%s.xlsx", time.Now().Format("20060102_150405")))
+			if err := h.excelService.GenerateUmPajakRuleImportErrorReport(result, errorReportPath); err == nil {
+				result.ErrorReportPath = errorReportPath
+			}
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success":           false,
+			"message":           "No valid UM Pajak rules found in the file",
+			"total_rows":        result.TotalRows,
+			"valid_count":       result.ValidCount,
+			"error_count":       result.ErrorCount,
+			"errors":            result.ValidationErrors,
+			"error_report_path": result.ErrorReportPath,
+		})
+	}
+
+	// If there are validation errors but some valid rules, import the valid ones
+	if len(result.ValidationErrors) > 0 {
+		// Generate error report
+		errorReportPath := filepath.Join("./storage/exports", fmt.Sprintf("import_errors_%s.xlsx", time.Now().Format("20060102_150405")))
+		if err := h.excelService.GenerateUmPajakRuleImportErrorReport(result, errorReportPath); err == nil {
+			result.ErrorReportPath = errorReportPath
+		}
+
+		// Import only valid rules
+		if err := h.rulesRepo.BulkInsertUmPajakRules(result.ValidRules); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to import valid UM Pajak rules: "+err.Error(), err)
+		}
+
+		// Return partial success with error details
+		errors := result.ValidationErrors
+		if len(errors) > 10 {
+			errors = errors[:10] // Limit to first 10 errors for readability
+		}
+		return c.Status(fiber.StatusPartialContent).JSON(fiber.Map{
+			"success":           true,
+			"message":           fmt.Sprintf("Import completed with %d errors. %d UM Pajak rules imported successfully.", result.ErrorCount, result.ValidCount),
+			"total_rows":        result.TotalRows,
+			"valid_count":       result.ValidCount,
+			"error_count":       result.ErrorCount,
+			"errors":            errors,
+			"error_report_path": result.ErrorReportPath,
+			"total_imported":    result.ValidCount,
+		})
+	}
+
+	// If no validation errors, import all rules
+	if err := h.rulesRepo.BulkInsertUmPajakRules(result.ValidRules); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to import UM Pajak rules: "+err.Error(), err)
+	}
+
+	return utils.SuccessResponse(c, "All UM Pajak rules imported successfully", fiber.Map{
+		"total_rows":     result.TotalRows,
+		"valid_count":    result.ValidCount,
+		"error_count":    result.ErrorCount,
+		"total_imported": result.ValidCount,
+	})
+}
+
+// DownloadErrorReport downloads an import error report file
+func (h *UmPajakRuleHandler) DownloadErrorReport(c *fiber.Ctx) error {
+	filename := c.Params("filename")
+	if filename == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Filename is required", nil)
+	}
+
+	// Validate filename to prevent directory traversal
+	if !isValidObyekRuleFilename(filename) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid filename", nil)
+	}
+
+	filePath := filepath.Join("./storage/exports", filename)
+
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Error report file not found", err)
+	}
+
+	// Send file
+	return c.Download(filePath, filename)
+}
+
+// validateUmPajakRuleRequest checks a create/update request and returns the normalized debit condition
+func validateUmPajakRuleRequest(req *models.UmPajakRuleRequest) (string, error) {
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	req.AccountPattern = strings.TrimSpace(req.AccountPattern)
+
+	if req.Keyword == "" {
+		return "", fmt.Errorf("Keyword is required")
+	}
+	if err := service.ValidateAccountPattern(req.AccountPattern); err != nil {
+		return "", fmt.Errorf("Account pattern must be a valid pattern, e.g. 1151*")
+	}
+	return service.NormalizeDebitCondition(req.DebitCondition)
+}
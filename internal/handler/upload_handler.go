@@ -19,23 +19,26 @@ import (
 )
 
 type UploadHandler struct {
-	uploadRepo   *repository.UploadRepository
-	excelService *service.ExcelService
-	asynqClient  *asynq.Client
-	cfg          *config.Config
+	uploadRepo      *repository.UploadRepository
+	excelService    *service.ExcelService
+	overrideService *service.TransactionOverrideService
+	asynqClient     *asynq.Client
+	cfg             *config.Config
 }
 
 func NewUploadHandler(
 	uploadRepo *repository.UploadRepository,
 	excelService *service.ExcelService,
+	overrideService *service.TransactionOverrideService,
 	asynqClient *asynq.Client,
 	cfg *config.Config,
 ) *UploadHandler {
 	return &UploadHandler{
-		uploadRepo:   uploadRepo,
-		excelService: excelService,
-		asynqClient:  asynqClient,
-		cfg:          cfg,
+		uploadRepo:      uploadRepo,
+		excelService:    excelService,
+		overrideService: overrideService,
+		asynqClient:     asynqClient,
+		cfg:             cfg,
 	}
 }
 
@@ -96,6 +99,7 @@ func (h *UploadHandler) UploadMultipleFiles(c *fiber.Ctx) error {
 	sessionCode := fmt.Sprintf("BATCH-%s", uuid.New().String()[:8])
 	var uploadResults []map[string]interface{}
 	var allTransactions []models.TransactionData
+	var savedFiles []savedUpload
 	var totalRows int
 
 	// Check if this should be processed in background (large uploads)
@@ -155,6 +159,58 @@ func (h *UploadHandler) UploadMultipleFiles(c *fiber.Ctx) error {
 			continue
 		}
 
+		savedFiles = append(savedFiles, savedUpload{Filename: file.Filename, FilePath: filePath, Size: file.Size})
+	}
+
+	// Large uploads are counted first and parsed by the worker, so the request
+	// returns right away. A file that cannot be counted may be large, so it also
+	// goes to the worker rather than being parsed in the request.
+	if h.asynqClient != nil && len(savedFiles) > 0 {
+		estimatedRows := 0
+		uncounted := false
+		for i := range savedFiles {
+			rows, err := h.excelService.CountTransactionRows(savedFiles[i].FilePath)
+			if err != nil {
+				fmt.Printf("WARNING: Failed to count rows of %s, parsing it in background: %v\n", savedFiles[i].Filename, err)
+				uncounted = true
+				continue
+			}
+			savedFiles[i].Rows = rows
+			estimatedRows += rows
+		}
+
+		if estimatedRows > BACKGROUND_THRESHOLD || uncounted {
+			backgroundJob, err := h.processLargeUploadInBackground(session, userID, savedFiles, estimatedRows)
+			if err == nil {
+				for _, saved := range savedFiles {
+					uploadResults = append(uploadResults, map[string]interface{}{
+						"filename": saved.Filename,
+						"success":  true,
+						"rows":     saved.Rows,
+						"size":     saved.Size,
+					})
+				}
+
+				return utils.SuccessResponse(c, "Large upload queued for processing", fiber.Map{
+					"session_code":    sessionCode,
+					"session_id":      session.ID,
+					"total_files":     len(uploadResults),
+					"total_errors":    len(uploadResults) - len(savedFiles),
+					"total_rows":      estimatedRows,
+					"upload_results":  uploadResults,
+					"background_job":  backgroundJob,
+					"processing_mode": "background",
+					"message":         "File berhasil diupload dan akan diproses di background. Halaman ini dapat direfresh untuk melihat progress.",
+				})
+			}
+			fmt.Printf("WARNING: Failed to queue large upload %s, parsing in request: %v\n", sessionCode, err)
+		}
+	}
+
+	// Parse the saved files in the request
+	for _, file := range savedFiles {
+		filePath := file.FilePath
+
 		// Parse Excel file
 		fmt.Printf("Starting to parse file: %s (size: %d bytes, session_code: %s)\n", file.Filename, file.Size, sessionCode)
 		startTime := time.Now()
@@ -172,7 +228,7 @@ func (h *UploadHandler) UploadMultipleFiles(c *fiber.Ctx) error {
 		parseTime := time.Since(startTime)
 		fmt.Printf("Parsed %d rows in %v (session_code: %s, filename: %s)\n", len(transactions), parseTime, sessionCode, file.Filename)
 
-		// Add to total rows for background processing decision
+		// Add to total rows
 		totalRows += len(transactions)
 
 		// Prepare transactions for saving with session_code only (session_id = 0)
@@ -239,66 +295,59 @@ func (h *UploadHandler) UploadMultipleFiles(c *fiber.Ctx) error {
 		fmt.Printf("WARNING: Failed to update session: %v\n", err)
 	}
 
-	// Process immediately (faster - skip background processing for now)
+	// Smaller uploads are inserted in the request
 	return h.processUploadOptimized(c, sessionCode, session.ID, userID, session.Filename, totalRows, allTransactions, uploadResults)
 }
 
-// processLargeUploadInBackground handles large uploads with background processing
-func (h *UploadHandler) processLargeUploadInBackground(c *fiber.Ctx, sessionCode string, userID int, firstFileName string, totalRows int, transactions []models.TransactionData, uploadResults []map[string]interface{}) error {
-	fmt.Printf("Processing large upload in background: %s (%d rows)\n", sessionCode, totalRows)
+// savedUpload is an uploaded file saved to the upload path, not yet parsed
+type savedUpload struct {
+	Filename string
+	FilePath string
+	Size     int64
+	Rows     int // estimated data rows, counted before a background upload
+}
 
-	// Create background job record
-	backgroundJob, err := h.uploadRepo.CreateBackgroundJob(sessionCode, userID, firstFileName, totalRows)
-	if err != nil {
-		fmt.Printf("ERROR: Failed to create background job: %v\n", err)
-		// Fall back to immediate processing
-		return h.processUploadImmediately(c, sessionCode, userID, firstFileName, totalRows, transactions, uploadResults)
-	}
+// processLargeUploadInBackground records a background job for a large upload and
+// queues upload:process_large, which parses the saved files, inserts the rows and
+// starts rule processing
+func (h *UploadHandler) processLargeUploadInBackground(session *models.UploadSession, userID int, files []savedUpload, totalRows int) (*models.BackgroundJob, error) {
+	fmt.Printf("Processing large upload in background: %s (%d rows)\n", session.SessionCode, totalRows)
 
-	// Insert all transactions in chunks using the improved chunking function
-	fmt.Printf("Inserting %d transactions to database...\n", len(transactions))
-	insertStart := time.Now()
+	filename := files[0].Filename
+	if len(files) > 1 {
+		filename = fmt.Sprintf("Batch: %d files (%s)", len(files), files[0].Filename)
+	}
 
-	err = h.uploadRepo.CreateMultipleTransactions(transactions)
+	// Create background job record
+	backgroundJob, err := h.uploadRepo.CreateBackgroundJob(session.SessionCode, userID, filename, totalRows)
 	if err != nil {
-		// Update background job with error
-		errorMsg := fmt.Sprintf("Failed to insert transactions: %v", err)
-		h.uploadRepo.UpdateBackgroundJobProgress(backgroundJob.ID, 0, "failed", &errorMsg)
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save transactions", err)
+		return nil, fmt.Errorf("failed to create background job: %w", err)
 	}
 
-	insertTime := time.Since(insertStart)
-	fmt.Printf("Successfully inserted %d transactions in %v\n", len(transactions), insertTime)
-
-	// Update background job with initial progress
-	h.uploadRepo.UpdateBackgroundJobProgress(backgroundJob.ID, len(transactions), "uploaded", nil)
-
-	// Queue background processing task
-	if h.asynqClient != nil {
-		payload, _ := json.Marshal(fiber.Map{
-			"session_code": sessionCode,
-			"background_job_id": backgroundJob.ID,
-		})
-
-		task := asynq.NewTask("upload:process_large", payload, asynq.MaxRetry(3))
-		_, err = h.asynqClient.Enqueue(task)
-		if err != nil {
-			fmt.Printf("WARNING: Failed to queue background processing: %v\n", err)
-			// Don't fail the upload, transactions are already saved
+	taskFiles := make([]fiber.Map, len(files))
+	for i, file := range files {
+		taskFiles[i] = fiber.Map{
+			"file_path": file.FilePath,
+			"filename":  file.Filename,
 		}
 	}
-
-	// Return immediate response
-	return utils.SuccessResponse(c, "Large upload queued for processing", fiber.Map{
-		"session_code":   sessionCode,
-		"total_files":   len(uploadResults),
-		"total_errors":   0,
-		"total_rows":    totalRows,
-		"upload_results": uploadResults,
-		"background_job": backgroundJob,
-		"processing_mode": "background",
-		"message":        "File berhasil diupload dan akan diproses di background. Halaman ini dapat direfresh untuk melihat progress.",
+	payload, _ := json.Marshal(fiber.Map{
+		"session_id":        session.ID,
+		"session_code":      session.SessionCode,
+		"background_job_id": backgroundJob.ID,
+		"user_id":           userID,
+		"filename":          filename,
+		"files":             taskFiles,
 	})
+
+	task := asynq.NewTask("upload:process_large", payload, asynq.MaxRetry(3))
+	if _, err := h.asynqClient.Enqueue(task); err != nil {
+		errorMsg := fmt.Sprintf("Failed to queue background processing: %v", err)
+		h.uploadRepo.UpdateBackgroundJobProgress(backgroundJob.ID, 0, "failed", &errorMsg)
+		return nil, fmt.Errorf("failed to queue background processing: %w", err)
+	}
+
+	return backgroundJob, nil
 }
 
 // processUploadOptimized handles uploads with optimized processing using session_code only
@@ -702,7 +751,12 @@ func (h *UploadHandler) GetTransactionsBySessionCode(c *fiber.Ctx) error {
 		// Fallback to original offset-based pagination
 		offset := utils.GetOffset(params.Page, params.Limit)
 		var total int
-		transactions, total, err = h.uploadRepo.GetTransactionsBySessionCode(sessionCode, params.Limit, offset)
+		// filter_warning=true lists only rows with a processing warning (e.g. WHT conflicts)
+		if params.Filters["warning"] == "true" {
+			transactions, total, err = h.uploadRepo.GetWarningTransactionsBySessionCode(sessionCode, params.Limit, offset)
+		} else {
+			transactions, total, err = h.uploadRepo.GetTransactionsBySessionCode(sessionCode, params.Limit, offset)
+		}
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve transactions", err)
 		}
@@ -735,6 +789,7 @@ func (h *UploadHandler) UpdateTransaction(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transaction ID", err)
 	}
 
+	// Omitted fields are left unchanged; an empty string clears the field
 	var request struct {
 		Koreksi *string `json:"koreksi"`
 		Obyek   *string `json:"obyek"`
@@ -743,6 +798,9 @@ func (h *UploadHandler) UpdateTransaction(c *fiber.Ctx) error {
 	if err := c.BodyParser(&request); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
+	if request.Koreksi == nil && request.Obyek == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Koreksi or obyek is required", nil)
+	}
 
 	// Get user ID for authorization
 	userIDInterface := c.Locals("user_id")
@@ -774,7 +832,7 @@ func (h *UploadHandler) UpdateTransaction(c *fiber.Ctx) error {
 	}
 
 	// Update transaction
-	err = h.uploadRepo.UpdateTransactionKoreksiObyek(transactionID, request.Koreksi, request.Obyek, userID, role)
+	err = h.overrideService.SetOverride(transactionID, request.Koreksi, request.Obyek, userID, role)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update transaction", err)
 	}
@@ -799,7 +857,7 @@ func (h *UploadHandler) ProcessSession(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is already being processed", nil)
 	}
 	if session.Status == "completed" {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is already completed", nil)
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is already completed, use /reprocess to run it again", nil)
 	}
 
 	// Update status to processing
@@ -938,9 +996,10 @@ func (h *UploadHandler) GetUploadProgress(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session code is required", nil)
 	}
 
-	// Try to get background job first (for large uploads)
+	// Try to get background job first (for large uploads). Once its rows are inserted
+	// the session reports the progress of rule processing.
 	backgroundJob, err := h.uploadRepo.GetBackgroundJobBySessionCode(sessionCode)
-	if err == nil && backgroundJob != nil {
+	if err == nil && backgroundJob != nil && backgroundJob.Status != "completed" {
 		// Return background job progress
 		progress := backgroundJob.GetProgressPercentage()
 		return utils.SuccessResponse(c, "Progress retrieved successfully", fiber.Map{
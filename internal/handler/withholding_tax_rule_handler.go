@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"accounting-web/internal/utils"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// withholdingTaxTypes are the tax types a WHT rule can fill, one per output column
+var withholdingTaxTypes = []string{"wth_21", "wth_23", "wth_26", "wth_4_2", "wth_15"}
+
+type WithholdingTaxRuleHandler struct {
+	rulesRepo *repository.RulesRepository
+}
+
+func NewWithholdingTaxRuleHandler(rulesRepo *repository.RulesRepository) *WithholdingTaxRuleHandler {
+	return &WithholdingTaxRuleHandler{
+		rulesRepo: rulesRepo,
+	}
+}
+
+func (h *WithholdingTaxRuleHandler) GetWithholdingTaxRules(c *fiber.Ctx) error {
+	params := utils.GetPaginationParams(c)
+	offset := utils.GetOffset(params.Page, params.Limit)
+
+	rules, total, err := h.rulesRepo.GetWithholdingTaxRules(params.Limit, offset)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve withholding tax rules", err)
+	}
+
+	pagination := utils.CalculatePagination(params.Page, params.Limit, int64(total))
+
+	responseData := fiber.Map{
+		"rules":      rules,
+		"pagination": pagination,
+	}
+
+	return utils.PaginatedResponseBuilder(c, "Withholding tax rules retrieved successfully", responseData, pagination)
+}
+
+func (h *WithholdingTaxRuleHandler) GetWithholdingTaxRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	rule, err := h.rulesRepo.GetWithholdingTaxRuleByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Withholding tax rule not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Withholding tax rule retrieved successfully", rule)
+}
+
+func (h *WithholdingTaxRuleHandler) CreateWithholdingTaxRule(c *fiber.Ctx) error {
+	var req models.WithholdingTaxRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateWithholdingTaxRuleRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+	validFrom, validTo, err := service.ParseValidityWindow(req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	rule := &models.WithholdingTaxRule{
+		Keyword:         req.Keyword,
+		TaxType:         req.TaxType,
+		TaxRate:         req.TaxRate,
+		CalculationMode: req.CalculationMode,
+		VatRate:         req.VatRate,
+		Rounding:        req.Rounding,
+		Conditions:      req.Conditions,
+		ValidFrom:       validFrom,
+		ValidTo:         validTo,
+		Priority:        req.Priority,
+		IsActive:        true, // Default to active
+	}
+
+	overlap, err := h.findOverlap(rule)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check validity overlap", err)
+	}
+	if overlap != nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, fmt.Sprintf("Validity %s overlaps withholding tax rule #%d (%s)",
+			service.FormatValidityWindow(rule.ValidFrom, rule.ValidTo), overlap.ID,
+			service.FormatValidityWindow(overlap.ValidFrom, overlap.ValidTo)), nil)
+	}
+
+	if err := h.rulesRepo.CreateWithholdingTaxRule(rule); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create withholding tax rule", err)
+	}
+
+	return utils.SuccessResponse(c, "Withholding tax rule created successfully", rule)
+}
+
+func (h *WithholdingTaxRuleHandler) UpdateWithholdingTaxRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	var req models.WithholdingTaxRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if err := validateWithholdingTaxRuleRequest(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+	validFrom, validTo, err := service.ParseValidityWindow(req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), err)
+	}
+
+	rule, err := h.rulesRepo.GetWithholdingTaxRuleByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Withholding tax rule not found", err)
+	}
+
+	rule.Keyword = req.Keyword
+	rule.TaxType = req.TaxType
+	rule.TaxRate = req.TaxRate
+	rule.CalculationMode = req.CalculationMode
+	rule.VatRate = req.VatRate
+	rule.Rounding = req.Rounding
+	rule.Conditions = req.Conditions
+	rule.ValidFrom = validFrom
+	rule.ValidTo = validTo
+	rule.Priority = req.Priority
+	rule.IsActive = req.IsActive
+
+	overlap, err := h.findOverlap(rule)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check validity overlap", err)
+	}
+	if overlap != nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, fmt.Sprintf("Validity %s overlaps withholding tax rule #%d (%s)",
+			service.FormatValidityWindow(rule.ValidFrom, rule.ValidTo), overlap.ID,
+			service.FormatValidityWindow(overlap.ValidFrom, overlap.ValidTo)), nil)
+	}
+
+	if err := h.rulesRepo.UpdateWithholdingTaxRule(rule); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update withholding tax rule", err)
+	}
+
+	return utils.SuccessResponse(c, "Withholding tax rule updated successfully", rule)
+}
+
+func (h *WithholdingTaxRuleHandler) DeleteWithholdingTaxRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid rule ID", err)
+	}
+
+	if err := h.rulesRepo.DeleteWithholdingTaxRule(id); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete withholding tax rule", err)
+	}
+
+	return utils.SuccessResponse(c, "Withholding tax rule deleted successfully", nil)
+}
+
+func (h *WithholdingTaxRuleHandler) GetResolutionPolicies(c *fiber.Ctx) error {
+	policies, err := h.rulesRepo.GetWHTResolutionPolicies()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve WHT resolution policies", err)
+	}
+
+	return utils.SuccessResponse(c, "WHT resolution policies retrieved successfully", fiber.Map{
+		"policies":  policies,
+		"available": []string{models.WHTResolutionFirst, models.WHTResolutionDistinct, models.WHTResolutionConflict},
+	})
+}
+
+// SetResolutionPolicy sets the policy of one tax type, or the default policy when the
+// tax type is "*" (or "default")
+func (h *WithholdingTaxRuleHandler) SetResolutionPolicy(c *fiber.Ctx) error {
+	taxType := resolutionTaxTypeParam(c)
+	if !isResolutionTaxType(taxType) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Tax type must be * or one of %v", withholdingTaxTypes), nil)
+	}
+
+	var req models.WHTResolutionPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if !service.IsWHTResolutionPolicy(req.Policy) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Policy must be first, distinct or conflict", nil)
+	}
+
+	userID, _, err := currentUser(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error(), err)
+	}
+
+	policy := &models.WHTResolutionPolicy{
+		TaxType:   taxType,
+		Policy:    req.Policy,
+		UpdatedBy: &userID,
+	}
+	if err := h.rulesRepo.SaveWHTResolutionPolicy(policy); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save WHT resolution policy", err)
+	}
+
+	return utils.SuccessResponse(c, "WHT resolution policy saved successfully", policy)
+}
+
+// DeleteResolutionPolicy removes a tax type's own policy so it follows the default again
+func (h *WithholdingTaxRuleHandler) DeleteResolutionPolicy(c *fiber.Ctx) error {
+	taxType := resolutionTaxTypeParam(c)
+	if !isResolutionTaxType(taxType) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Tax type must be * or one of %v", withholdingTaxTypes), nil)
+	}
+
+	if err := h.rulesRepo.DeleteWHTResolutionPolicy(taxType); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete WHT resolution policy", err)
+	}
+
+	return utils.SuccessResponse(c, "WHT resolution policy deleted successfully", nil)
+}
+
+// resolutionTaxTypeParam reads the tax type from the path; "default" stands for "*",
+// which clients may not be able to put in a URL unescaped
+func resolutionTaxTypeParam(c *fiber.Ctx) string {
+	taxType := c.Params("tax_type")
+	if taxType == "default" || taxType == "%2A" {
+		return models.WHTResolutionDefaultTaxType
+	}
+	return taxType
+}
+
+func isResolutionTaxType(taxType string) bool {
+	if taxType == models.WHTResolutionDefaultTaxType {
+		return true
+	}
+	for _, t := range withholdingTaxTypes {
+		if taxType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// findOverlap returns another active version of the same rule (same keyword, tax type
+// and conditions) whose validity window overlaps the rule's, or nil
+func (h *WithholdingTaxRuleHandler) findOverlap(rule *models.WithholdingTaxRule) (*models.WithholdingTaxRule, error) {
+	if !rule.IsActive {
+		return nil, nil
+	}
+
+	overlapping, err := h.rulesRepo.GetOverlappingWithholdingTaxRules(rule)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overlapping {
+		if service.SameRuleConditions(rule.Conditions, overlapping[i].Conditions) {
+			return &overlapping[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// validateWithholdingTaxRuleRequest checks the tax type, the rate (a fraction, 0.02 for 2%),
+// the calculation mode and rounding (defaulting them to credit and none) and what the
+// rule matches on
+func validateWithholdingTaxRuleRequest(req *models.WithholdingTaxRuleRequest) error {
+	validType := false
+	for _, taxType := range withholdingTaxTypes {
+		if req.TaxType == taxType {
+			validType = true
+			break
+		}
+	}
+	if !validType {
+		return fmt.Errorf("Tax type must be one of %v", withholdingTaxTypes)
+	}
+	if req.TaxRate < 0 || req.TaxRate > 1 {
+		return fmt.Errorf("Tax rate must be between 0 and 1 (e.g. 0.02 for 2%%)")
+	}
+	if err := service.ValidateWHTCalculation(req.CalculationMode, req.VatRate, req.TaxRate, req.Rounding); err != nil {
+		return err
+	}
+	if req.CalculationMode == "" {
+		req.CalculationMode = models.WHTModeCredit
+	}
+	if req.Rounding == "" {
+		req.Rounding = models.WHTRoundingNone
+	}
+	if err := service.ValidateRuleMatch(req.Keyword, req.Conditions); err != nil {
+		return fmt.Errorf("Invalid rule: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"accounting-web/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BumpRuleVersion bumps the rule-set version after every successful change made
+// through the routes it guards, so cached rules in web and worker processes reload
+func BumpRuleVersion(notifier *service.RuleVersionNotifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		method := c.Method()
+		if err != nil || method == fiber.MethodGet || method == fiber.MethodHead || c.Response().StatusCode() >= 300 {
+			return err
+		}
+
+		var changedBy *int
+		if userID, ok := c.Locals("user_id").(int); ok {
+			changedBy = &userID
+		}
+		notifier.Bump(method+" "+c.Path(), changedBy)
+		return nil
+	}
+}
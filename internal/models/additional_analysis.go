@@ -8,6 +8,9 @@ type AdditionalAnalysis struct {
 	Account       *Account  `db:"-" json:"account,omitempty"` // For JOIN operations
 	AnalysisType  string    `db:"analysis_type" json:"analysis_type"` // revenue_recognition, tax_treatment, etc.
 	AnalysisTitle string    `db:"analysis_title" json:"analysis_title"`
+	// KeywordCondition limits the analysis to matching keterangan: comma-separated
+	// keywords, any of which must appear; terms prefixed with "!" exclude the row
+	KeywordCondition *string `db:"keyword_condition" json:"keyword_condition"`
 	Status        string    `db:"status" json:"status"` // active, inactive
 	Notes         *string   `db:"notes" json:"notes"`
 	CreatedBy     *int      `db:"created_by" json:"created_by"`
@@ -19,6 +22,7 @@ type AdditionalAnalysisRequest struct {
 	AccountCode   string `json:"account_code" validate:"required"`
 	AnalysisType  string `json:"analysis_type" validate:"required"`
 	AnalysisTitle string `json:"analysis_title" validate:"required"`
+	KeywordCondition string `json:"keyword_condition"`
 	Status        string `json:"status"`
 	Notes         string `json:"notes"`
 }
@@ -29,6 +33,7 @@ type AdditionalAnalysisResponse struct {
 	AccountName string    `json:"account_name,omitempty" db:"account_name"`
 	AnalysisType string   `json:"analysis_type" db:"analysis_type"`
 	AnalysisTitle string  `json:"analysis_title" db:"analysis_title"`
+	KeywordCondition *string `json:"keyword_condition" db:"keyword_condition"`
 	Status      string    `json:"status" db:"status"`
 	Notes       *string   `json:"notes" db:"notes"`
 	CreatedBy   *int      `json:"created_by" db:"created_by"`
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Classifier model statuses
+const (
+	ClassifierStatusQueued   = "queued"
+	ClassifierStatusTraining = "training"
+	ClassifierStatusReady    = "ready"
+	ClassifierStatusFailed   = "failed"
+)
+
+// ClassifierModel is one trained version of the koreksi/obyek suggestion classifier.
+// Model holds the serialized class and token counts; it is only loaded for the active
+// model and left out of listings.
+type ClassifierModel struct {
+	ID              int        `db:"id" json:"id"`
+	Version         int        `db:"version" json:"version"`
+	Status          string     `db:"status" json:"status"`
+	IsActive        bool       `db:"is_active" json:"is_active"`
+	TrainingRows    int        `db:"training_rows" json:"training_rows"`
+	KoreksiClasses  int        `db:"koreksi_classes" json:"koreksi_classes"`
+	ObyekClasses    int        `db:"obyek_classes" json:"obyek_classes"`
+	VocabularySize  int        `db:"vocabulary_size" json:"vocabulary_size"`
+	KoreksiAccuracy *float64   `db:"koreksi_accuracy" json:"koreksi_accuracy,omitempty"`
+	ObyekAccuracy   *float64   `db:"obyek_accuracy" json:"obyek_accuracy,omitempty"`
+	Model           *string    `db:"model" json:"-"`
+	ErrorMessage    *string    `db:"error_message" json:"error_message,omitempty"`
+	CreatedBy       *int       `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	TrainedAt       *time.Time `db:"trained_at" json:"trained_at,omitempty"`
+}
+
+// ClassifierTrainingRow is a processed row the classifier learns from: its koreksi and
+// obyek come from a rule or were corrected by hand
+type ClassifierTrainingRow struct {
+	Keterangan      string  `db:"keterangan"`
+	Account         string  `db:"account"`
+	DocumentType    string  `db:"document_type"`
+	Koreksi         *string `db:"koreksi"`
+	Obyek           *string `db:"obyek"`
+	KoreksiOverride bool    `db:"koreksi_override"`
+	ObyekOverride   bool    `db:"obyek_override"`
+}
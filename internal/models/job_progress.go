@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// JobProgress is the state of a queued processing job combined with the progress of
+// the session it processes
+type JobProgress struct {
+	JobID string `json:"job_id"`
+	Queue string `json:"queue,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// State is the asynq task state (pending, active, retry, archived, completed),
+	// or the final status the worker recorded once asynq no longer knows the task
+	State        string     `json:"state"`
+	Retried      int        `json:"retried"`
+	MaxRetry     int        `json:"max_retry"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastFailedAt *time.Time `json:"last_failed_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+
+	SessionID          int     `json:"session_id"`
+	SessionCode        string  `json:"session_code"`
+	SessionStatus      string  `json:"session_status,omitempty"`
+	TotalRows          int     `json:"total_rows"`
+	ProcessedRows      int     `json:"processed_rows"`
+	FailedRows         int     `json:"failed_rows"`
+	ProgressPercentage float64 `json:"progress_percentage"`
+
+	// Counters of this job only; a reprocess may start with rows already processed
+	CurrentBatch     int        `json:"current_batch"`
+	BatchSize        int        `json:"batch_size"`
+	JobProcessedRows int        `json:"job_processed_rows"`
+	RowsPerSecond    float64    `json:"rows_per_second"`
+	ETASeconds       *float64   `json:"eta_seconds,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// scanJSONColumn decodes a JSON/TEXT column into dest. It reports false for NULL or empty values.
+func scanJSONColumn(value interface{}, dest interface{}) (bool, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return false, fmt.Errorf("unsupported type for JSON column: %T", value)
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// jsonColumnValue encodes v for a JSON/TEXT column
+func jsonColumnValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ProcessingStep is the stored setting of one step of the processing pipeline. Steps
+// run by ascending StepOrder; a disabled step leaves its output fields untouched.
+type ProcessingStep struct {
+	ID          int        `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	Description string     `db:"description" json:"description"`
+	Enabled     bool       `db:"enabled" json:"enabled"`
+	StepOrder   int        `db:"step_order" json:"step_order"`
+	Config      StepConfig `db:"config" json:"config"`
+	UpdatedBy   *int       `db:"updated_by" json:"updated_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+type ProcessingStepRequest struct {
+	Enabled   bool       `json:"enabled"`
+	StepOrder int        `json:"step_order"`
+	Config    StepConfig `json:"config"`
+}
+
+// StepConfig is the JSON configuration of a processing step; each step decodes its own
+type StepConfig json.RawMessage
+
+// IsEmpty reports whether no configuration is set
+func (c StepConfig) IsEmpty() bool {
+	trimmed := bytes.TrimSpace(c)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// Scan implements sql.Scanner interface for StepConfig
+func (c *StepConfig) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+	case []byte:
+		*c = append(StepConfig{}, v...)
+	case string:
+		*c = StepConfig(v)
+	default:
+		return fmt.Errorf("unsupported type for step config: %T", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer interface for StepConfig
+func (c StepConfig) Value() (driver.Value, error) {
+	if c.IsEmpty() {
+		return nil, nil
+	}
+	return string(c), nil
+}
+
+func (c StepConfig) MarshalJSON() ([]byte, error) {
+	if c.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return c, nil
+}
+
+func (c *StepConfig) UnmarshalJSON(data []byte) error {
+	*c = append(StepConfig{}, data...)
+	return nil
+}
+
+// ProcessingStepStat is the work one step did over a batch or, once summed, a run:
+// the rows it processed, the output fields it changed and the time it took
+type ProcessingStepStat struct {
+	ID            int64     `db:"id" json:"id,omitempty"`
+	RunID         int       `db:"run_id" json:"run_id,omitempty"`
+	StepName      string    `db:"step_name" json:"step_name"`
+	StepOrder     int       `db:"step_order" json:"step_order"`
+	Transactions  int       `db:"transactions" json:"transactions"`
+	FieldsChanged int       `db:"fields_changed" json:"fields_changed"`
+	Errors        int       `db:"errors" json:"errors"`
+	DurationMs    float64   `db:"duration_ms" json:"duration_ms"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
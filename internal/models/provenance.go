@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule types recorded in RuleProvenance
+const (
+	RuleTypeKoreksi    = "koreksi"
+	RuleTypeObyek      = "obyek"
+	RuleTypeWHT        = "withholding_tax"
+	RuleTypeTaxKeyword = "tax_keyword"
+	RuleTypeUmPajak    = "um_pajak"
+
+	RuleTypeAdditionalAnalysis = "additional_analysis"
+)
+
+// RuleProvenance identifies the rule that produced an output field
+type RuleProvenance struct {
+	RuleType string `json:"rule_type"`
+	RuleID   int    `json:"rule_id"`
+	Keyword  string `json:"keyword"`
+	// Mode is how the value was calculated, e.g. "dpp 11%, round down" for WHT rules
+	Mode string `json:"mode,omitempty"`
+}
+
+// String renders the provenance as "#<id> <keyword>" for explain columns, followed
+// by the calculation mode in brackets when there is one
+func (p RuleProvenance) String() string {
+	if p.Mode != "" {
+		return fmt.Sprintf("#%d %s [%s]", p.RuleID, p.Keyword, p.Mode)
+	}
+	return fmt.Sprintf("#%d %s", p.RuleID, p.Keyword)
+}
+
+// RuleProvenanceMap maps an output column (koreksi, obyek, wth_23_cr, pm_db, ...)
+// to the rule that filled it. It is stored as JSON in transaction_data.rule_provenance.
+type RuleProvenanceMap map[string]RuleProvenance
+
+// Scan implements sql.Scanner interface for RuleProvenanceMap
+func (m *RuleProvenanceMap) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for RuleProvenanceMap: %T", value)
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		*m = nil
+		return nil
+	}
+
+	result := RuleProvenanceMap{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	*m = result
+	return nil
+}
+
+// Value implements driver.Valuer interface for RuleProvenanceMap
+func (m RuleProvenanceMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Explain returns the provenance of a single field, or "" when no rule produced it
+func (m RuleProvenanceMap) Explain(field string) string {
+	if p, ok := m[field]; ok {
+		return p.String()
+	}
+	return ""
+}
+
+// ExplainPrefix joins the provenance of every field starting with prefix,
+// e.g. "wth_" gives "wth_23_cr: #4 PPh 23"
+func (m RuleProvenanceMap) ExplainPrefix(prefix string) string {
+	var fields []string
+	for field := range m {
+		if strings.HasPrefix(field, prefix) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, m[field].String()))
+	}
+	return strings.Join(parts, "; ")
+}
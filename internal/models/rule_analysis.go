@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Kinds of rule analysis findings
+const (
+	RuleFindingShadowed          = "shadowed"              // an earlier rule's keyword is a substring of this rule's
+	RuleFindingDuplicate         = "duplicate"             // same keyword and value as an earlier rule
+	RuleFindingConflict          = "conflicting_duplicate" // same keyword as an earlier rule, different value
+	RuleFindingKeywordInNotValue = "keyword_in_not_value"  // the keyword appears in a not_value list
+	RuleFindingInactiveDuplicate = "inactive_duplicate"    // an inactive rule with the keyword of another rule
+)
+
+// Severities of rule analysis findings. Errors are rules that can never fire or
+// contradict another rule; import rejects rows with error findings.
+const (
+	RuleFindingError   = "error"
+	RuleFindingWarning = "warning"
+	RuleFindingInfo    = "info"
+)
+
+// RuleFinding is one problem found by the rule analyzer. Rules that are not saved yet
+// (rows of an import file) are identified by Row instead of RuleID.
+type RuleFinding struct {
+	Kind           string `json:"kind"`
+	Severity       string `json:"severity"`
+	RuleType       string `json:"rule_type"` // koreksi or obyek
+	RuleID         int    `json:"rule_id,omitempty"`
+	Row            int    `json:"row,omitempty"`
+	Keyword        string `json:"keyword"`
+	RelatedRuleID  int    `json:"related_rule_id,omitempty"`
+	RelatedRow     int    `json:"related_row,omitempty"`
+	RelatedKeyword string `json:"related_keyword,omitempty"`
+	Message        string `json:"message"`
+}
+
+// RuleAnalysisReport is the result of analyzing the koreksi and obyek rules
+type RuleAnalysisReport struct {
+	Findings      []RuleFinding  `json:"findings"`
+	Summary       map[string]int `json:"summary"` // finding count per kind
+	RulesAnalyzed int            `json:"rules_analyzed"`
+	AnalyzedAt    time.Time      `json:"analyzed_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RuleInvalidation is published whenever a rule or account changes
+type RuleInvalidation struct {
+	Version   int64     `json:"version"`
+	Reason    string    `json:"reason"`
+	ChangedBy *int      `json:"changed_by,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// RuleCacheHolder is the rule-set version one web or worker process has loaded
+type RuleCacheHolder struct {
+	Instance      string     `json:"instance"`
+	Role          string     `json:"role"`
+	HeldVersion   int64      `json:"held_version"`
+	LatestVersion int64      `json:"latest_version"`
+	LoadedAt      *time.Time `json:"loaded_at,omitempty"`
+	HeartbeatAt   time.Time  `json:"heartbeat_at"`
+	Stale         bool       `json:"stale"`   // no heartbeat for a while; the process is probably gone
+	Current       bool       `json:"current"` // holds the current version; others reload on their next use
+}
+
+// RuleCacheStatus is the current rule-set version and what every process holds
+type RuleCacheStatus struct {
+	Version int64             `json:"version"`
+	Holders []RuleCacheHolder `json:"holders"`
+}
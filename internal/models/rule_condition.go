@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+)
+
+// Condition fields a rule can test, besides its keyword
+const (
+	ConditionFieldAccount      = "account"
+	ConditionFieldAccountName  = "account_name"
+	ConditionFieldDocumentType = "document_type"
+	ConditionFieldKeterangan   = "keterangan"
+	ConditionFieldSide         = "side" // "debit" or "credit"
+	ConditionFieldDebet        = "debet"
+	ConditionFieldCredit       = "credit"
+	ConditionFieldNet          = "net"
+	ConditionFieldAmount       = "amount" // debet on the debit side, credit on the credit side
+)
+
+// RuleCondition is a structured condition over transaction fields, stored as JSON on
+// koreksi, obyek and withholding tax rules. A node is either a group (all, any or
+// not) or a leaf comparing one field, e.g.
+//
+//	{"all": [{"field": "account", "op": "prefix", "value": "61"},
+//	         {"not": {"field": "document_type", "op": "in", "values": ["JV", "RV"]}},
+//	         {"field": "amount", "op": "gte", "value": 1000000}]}
+type RuleCondition struct {
+	All []RuleCondition `json:"all,omitempty"`
+	Any []RuleCondition `json:"any,omitempty"`
+	Not *RuleCondition  `json:"not,omitempty"`
+
+	Field    string           `json:"field,omitempty"`
+	Op       string           `json:"op,omitempty"`
+	Operand  ConditionValue   `json:"value,omitempty"`
+	Operands []ConditionValue `json:"values,omitempty"`
+	From     ConditionValue   `json:"from,omitempty"`
+	To       ConditionValue   `json:"to,omitempty"`
+}
+
+// Scan implements sql.Scanner interface for RuleCondition
+func (rc *RuleCondition) Scan(value interface{}) error {
+	_, err := scanJSONColumn(value, rc)
+	return err
+}
+
+// Value implements driver.Valuer interface for RuleCondition
+func (rc *RuleCondition) Value() (driver.Value, error) {
+	if rc == nil {
+		return nil, nil
+	}
+	return jsonColumnValue(rc)
+}
+
+// OptionalRuleCondition is the conditions field of a rule update. Set tells an
+// absent field, which keeps the stored conditions, from an explicit null, which
+// removes them.
+type OptionalRuleCondition struct {
+	Set       bool
+	Condition *RuleCondition
+}
+
+// UnmarshalJSON implements json.Unmarshaler; it is only called when the field is present
+func (o *OptionalRuleCondition) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	o.Condition = nil
+	if string(data) == "null" {
+		return nil
+	}
+	var condition RuleCondition
+	if err := json.Unmarshal(data, &condition); err != nil {
+		return err
+	}
+	o.Condition = &condition
+	return nil
+}
+
+// Or returns the conditions of the request, or stored when the field was absent
+func (o OptionalRuleCondition) Or(stored *RuleCondition) *RuleCondition {
+	if !o.Set {
+		return stored
+	}
+	return o.Condition
+}
+
+// ConditionValue is a condition operand. It accepts both JSON strings and numbers so
+// account codes and amounts can be written either way.
+type ConditionValue string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (v *ConditionValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = ConditionValue(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*v = ConditionValue(n.String())
+	return nil
+}
+
+// Float64 parses the value as a number
+func (v ConditionValue) Float64() (float64, error) {
+	return strconv.ParseFloat(string(v), 64)
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// RuleSetSnapshot is the full set of rules the processing engine works with
+type RuleSetSnapshot struct {
+	Accounts            []Account            `json:"accounts"`
+	KoreksiRules        []KoreksiRule        `json:"koreksi_rules"`
+	ObyekRules          []ObyekRule          `json:"obyek_rules"`
+	WithholdingTaxRules []WithholdingTaxRule `json:"withholding_tax_rules"`
+	TaxKeywords         []TaxKeyword         `json:"tax_keywords"`
+	UmPajakRules        []UmPajakRule        `json:"um_pajak_rules"`
+	AdditionalAnalyses  []AdditionalAnalysis `json:"additional_analyses"`
+	// WHTResolutionPolicies is omitted from snapshots stored before policies existed
+	WHTResolutionPolicies []WHTResolutionPolicy `json:"wht_resolution_policies,omitempty"`
+	// KeywordSynonyms is omitted from snapshots stored before synonyms existed
+	KeywordSynonyms []KeywordSynonym `json:"keyword_synonyms,omitempty"`
+}
+
+// RuleSetVersion is an immutable, stored RuleSetSnapshot
+type RuleSetVersion struct {
+	ID                      int       `db:"id" json:"id"`
+	Checksum                string    `db:"checksum" json:"checksum"`
+	Snapshot                string    `db:"snapshot" json:"-"`
+	AccountCount            int       `db:"account_count" json:"account_count"`
+	KoreksiRuleCount        int       `db:"koreksi_rule_count" json:"koreksi_rule_count"`
+	ObyekRuleCount          int       `db:"obyek_rule_count" json:"obyek_rule_count"`
+	WHTRuleCount            int       `db:"wht_rule_count" json:"wht_rule_count"`
+	TaxKeywordCount         int       `db:"tax_keyword_count" json:"tax_keyword_count"`
+	UmPajakRuleCount        int       `db:"um_pajak_rule_count" json:"um_pajak_rule_count"`
+	AdditionalAnalysisCount int       `db:"additional_analysis_count" json:"additional_analysis_count"`
+	Note                    *string   `db:"note" json:"note,omitempty"`
+	CreatedBy               *int      `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt               time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProcessingRun records a single processing run of a session
+type ProcessingRun struct {
+	ID               int               `db:"id" json:"id"`
+	SessionID        int               `db:"session_id" json:"session_id"`
+	SessionCode      string            `db:"session_code" json:"session_code"`
+	RuleSetVersionID *int              `db:"rule_set_version_id" json:"rule_set_version_id,omitempty"`
+	RunType          string            `db:"run_type" json:"run_type"` // process, reprocess
+	Status           string            `db:"status" json:"status"`     // queued, running, completed, failed
+	ProcessedRows    int               `db:"processed_rows" json:"processed_rows"`
+	FailedRows       int               `db:"failed_rows" json:"failed_rows"`
+	ErrorMessage     *string           `db:"error_message" json:"error_message,omitempty"`
+	ChangeSummary    *RunChangeSummary `db:"change_summary" json:"change_summary,omitempty"`
+	ResetFilter      *ReprocessFilter  `db:"reset_filter" json:"reset_filter,omitempty"`
+	TriggeredBy      *int              `db:"triggered_by" json:"triggered_by,omitempty"`
+	StartedAt        *time.Time        `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt       *time.Time        `db:"finished_at" json:"finished_at,omitempty"`
+	CreatedAt        time.Time         `db:"created_at" json:"created_at"`
+}
+
+type ReprocessRequest struct {
+	RuleSetVersionID int              `json:"rule_set_version_id"`
+	Filter           *ReprocessFilter `json:"filter"`
+}
+
+// ReprocessFilter limits a reprocess to matching rows. Empty fields are ignored;
+// the remaining conditions are combined with AND.
+type ReprocessFilter struct {
+	TransactionIDs     []int64 `json:"transaction_ids,omitempty"`
+	Account            string  `json:"account,omitempty"`
+	AccountPrefix      string  `json:"account_prefix,omitempty"`
+	DocumentType       string  `json:"document_type,omitempty"`
+	KeteranganContains string  `json:"keterangan_contains,omitempty"`
+	Koreksi            string  `json:"koreksi,omitempty"`
+	Obyek              string  `json:"obyek,omitempty"`
+	OnlyUnclassified   bool    `json:"only_unclassified,omitempty"` // koreksi and obyek both empty
+}
+
+// IsEmpty reports whether the filter matches every row
+func (f *ReprocessFilter) IsEmpty() bool {
+	return f == nil || (len(f.TransactionIDs) == 0 && f.Account == "" && f.AccountPrefix == "" &&
+		f.DocumentType == "" && f.KeteranganContains == "" && f.Koreksi == "" && f.Obyek == "" && !f.OnlyUnclassified)
+}
+
+// Scan implements sql.Scanner interface for ReprocessFilter
+func (f *ReprocessFilter) Scan(value interface{}) error {
+	_, err := scanJSONColumn(value, f)
+	return err
+}
+
+// Value implements driver.Valuer interface for ReprocessFilter
+func (f *ReprocessFilter) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return jsonColumnValue(f)
+}
+
+// ValueTransition counts rows whose field moved from one value to another
+type ValueTransition struct {
+	Field    string `db:"field" json:"field"`
+	OldValue string `db:"old_value" json:"old_value"`
+	NewValue string `db:"new_value" json:"new_value"`
+	Rows     int    `db:"row_count" json:"rows"`
+}
+
+// RunChangeSummary is the before/after comparison of a reprocess run
+type RunChangeSummary struct {
+	TotalRows    int               `json:"total_rows"`
+	ChangedRows  int               `json:"changed_rows"`
+	FieldChanges map[string]int    `json:"field_changes"`
+	Transitions  []ValueTransition `json:"transitions"`
+}
+
+// Scan implements sql.Scanner interface for RunChangeSummary
+func (s *RunChangeSummary) Scan(value interface{}) error {
+	_, err := scanJSONColumn(value, s)
+	return err
+}
+
+// Value implements driver.Valuer interface for RunChangeSummary
+func (s *RunChangeSummary) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return jsonColumnValue(s)
+}
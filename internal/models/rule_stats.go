@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// RuleHitStat is the number of rows a rule filled during one processing run
+type RuleHitStat struct {
+	ID          int64     `db:"id" json:"id"`
+	RunID       int       `db:"run_id" json:"run_id"`
+	SessionCode string    `db:"session_code" json:"session_code"`
+	RuleType    string    `db:"rule_type" json:"rule_type"`
+	RuleID      int       `db:"rule_id" json:"rule_id"`
+	Hits        int       `db:"hits" json:"hits"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// RuleHitSummary totals a rule's hits over the runs of a period. Active rules without
+// hits are listed with zero so unused rules can be retired.
+type RuleHitSummary struct {
+	RuleType  string     `db:"rule_type" json:"rule_type"`
+	RuleID    int        `db:"rule_id" json:"rule_id"`
+	Keyword   string     `db:"keyword" json:"keyword"`
+	IsActive  bool       `db:"is_active" json:"is_active"`
+	Hits      int        `db:"hits" json:"hits"`
+	Runs      int        `db:"runs" json:"runs"`
+	LastHitAt *time.Time `db:"last_hit_at" json:"last_hit_at,omitempty"`
+}
+
+// UnmatchedKeterangan is a keterangan (lower-cased and trimmed) that matched neither a
+// koreksi nor an obyek rule, with the rows and amounts it covers
+type UnmatchedKeterangan struct {
+	Keterangan  string  `db:"keterangan" json:"keterangan"`
+	RowCount    int     `db:"row_count" json:"row_count"`
+	TotalDebet  float64 `db:"total_debet" json:"total_debet"`
+	TotalCredit float64 `db:"total_credit" json:"total_credit"`
+	TotalNet    float64 `db:"total_net" json:"total_net"`
+	Sessions    int     `db:"sessions" json:"sessions"`
+}
+
+// UnmatchedKeteranganFilter selects the rows of the unmatched keterangan report: a
+// session, a posting date range, or both
+type UnmatchedKeteranganFilter struct {
+	SessionCode string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// RuleSuggestion is a candidate koreksi or obyek keyword mined from the keterangan of
+// unclassified rows. Value is the value users most often set manually on rows
+// containing the keyword; it is empty when no such row exists yet.
+type RuleSuggestion struct {
+	RuleType    string   `json:"rule_type"`
+	Keyword     string   `json:"keyword"`
+	Value       string   `json:"value,omitempty"`
+	Support     int      `json:"support"`      // unclassified rows containing the keyword
+	TotalNet    float64  `json:"total_net"`    // net amount of those rows
+	LabeledRows int      `json:"labeled_rows"` // manually classified rows containing the keyword
+	ValueRows   int      `json:"value_rows"`   // labeled rows set to Value
+	Confidence  float64  `json:"confidence"`   // ValueRows / LabeledRows, 0 without labeled rows
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// RuleSuggestionFilter selects the unclassified rows suggestions are mined from and
+// limits the suggestions returned
+type RuleSuggestionFilter struct {
+	RuleType      string
+	SessionCode   string
+	From          *time.Time
+	To            *time.Time
+	MinSupport    int
+	MinConfidence float64
+	Limit         int
+}
+
+// SuggestionSourceRow is a processed row read for rule suggestions: either an
+// unclassified row or one whose koreksi/obyek was set manually
+type SuggestionSourceRow struct {
+	Keterangan      string  `db:"keterangan"`
+	Net             float64 `db:"net"`
+	Koreksi         *string `db:"koreksi"`
+	Obyek           *string `db:"obyek"`
+	KoreksiOverride bool    `db:"koreksi_override"`
+	ObyekOverride   bool    `db:"obyek_override"`
+}
+
+// AcceptRuleSuggestionRequest turns a suggestion into a koreksi or obyek rule
+type AcceptRuleSuggestionRequest struct {
+	RuleType string `json:"rule_type"`
+	Keyword  string `json:"keyword"`
+	Value    string `json:"value"`
+	NotValue string `json:"not_value"`
+	Priority int    `json:"priority"`
+}
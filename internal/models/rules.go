@@ -10,6 +10,8 @@ type KoreksiRule struct {
 	Keyword   string    `db:"keyword" json:"keyword"`
 	Value     string    `db:"value" json:"value"`
 	NotValue  sql.NullString `db:"not_value" json:"not_value"`
+	Conditions *RuleCondition `db:"conditions" json:"conditions,omitempty"`
+	Priority  int       `db:"priority" json:"priority"`
 	IsActive  bool      `db:"is_active" json:"is_active"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
@@ -20,6 +22,8 @@ type ObyekRule struct {
 	Keyword   string    `db:"keyword" json:"keyword"`
 	Value     string    `db:"value" json:"value"`
 	NotValue  sql.NullString `db:"not_value" json:"not_value"`
+	Conditions *RuleCondition `db:"conditions" json:"conditions,omitempty"`
+	Priority  int       `db:"priority" json:"priority"`
 	IsActive  bool      `db:"is_active" json:"is_active"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
@@ -30,22 +34,102 @@ type WithholdingTaxRule struct {
 	Keyword   string    `db:"keyword" json:"keyword"`
 	TaxType   string    `db:"tax_type" json:"tax_type"`
 	TaxRate   float64   `db:"tax_rate" json:"tax_rate"`
+	// How the tax base is derived (WHTMode*) and how the tax is rounded (WHTRounding*)
+	CalculationMode string  `db:"calculation_mode" json:"calculation_mode"`
+	VatRate         float64 `db:"vat_rate" json:"vat_rate"` // VAT factor for the dpp mode, e.g. 0.11
+	Rounding        string  `db:"rounding" json:"rounding"`
+	Conditions *RuleCondition `db:"conditions" json:"conditions,omitempty"`
+	// Validity window on the posting date; nil leaves that side open
+	ValidFrom *time.Time `db:"valid_from" json:"valid_from,omitempty"`
+	ValidTo   *time.Time `db:"valid_to" json:"valid_to,omitempty"`
 	Priority  int       `db:"priority" json:"priority"`
 	IsActive  bool      `db:"is_active" json:"is_active"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// Withholding tax calculation modes: the amount the rate is applied to
+const (
+	WHTModeCredit  = "credit"   // the credit amount
+	WHTModeDebit   = "debit"    // the debit amount
+	WHTModeDPP     = "dpp"      // the DPP of a credit that includes VAT: credit / (1 + vat_rate)
+	WHTModeGrossUp = "gross_up" // the credit is the net payment: credit / (1 - tax_rate)
+)
+
+// Withholding tax rounding policies, to whole rupiah
+const (
+	WHTRoundingNone    = "none"
+	WHTRoundingNearest = "nearest"
+	WHTRoundingDown    = "down"
+	WHTRoundingUp      = "up"
+)
+
+// Policies for a row that matches more than one withholding tax rule
+const (
+	WHTResolutionFirst    = "first"    // only the highest-priority matching rule applies
+	WHTResolutionDistinct = "distinct" // the highest-priority rule of each tax type applies
+	WHTResolutionConflict = "conflict" // several rules of one tax type cancel out and the row gets a warning
+
+	// WHTResolutionDefaultTaxType keys the policy used by tax types without their own
+	WHTResolutionDefaultTaxType = "*"
+)
+
+// WHTResolutionPolicy selects how multiple matching WHT rules are resolved, for one
+// tax type or, with TaxType "*", for all of them
+type WHTResolutionPolicy struct {
+	ID        int       `db:"id" json:"id"`
+	TaxType   string    `db:"tax_type" json:"tax_type"`
+	Policy    string    `db:"policy" json:"policy"`
+	UpdatedBy *int      `db:"updated_by" json:"updated_by,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
 type TaxKeyword struct {
 	ID          int       `db:"id" json:"id"`
 	Keyword     string    `db:"keyword" json:"keyword"`
 	TaxCategory string    `db:"tax_category" json:"tax_category"`
+	// Validity window on the posting date; nil leaves that side open
+	ValidFrom   *time.Time `db:"valid_from" json:"valid_from,omitempty"`
+	ValidTo     *time.Time `db:"valid_to" json:"valid_to,omitempty"`
 	Priority    int       `db:"priority" json:"priority"`
 	IsActive    bool      `db:"is_active" json:"is_active"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// KeywordSynonym makes Term interchangeable with Canonical in rule keywords, e.g. the
+// abbreviation "sw gdg" for "sewa gedung". Both are compared in normalized form.
+type KeywordSynonym struct {
+	ID        int       `db:"id" json:"id"`
+	Term      string    `db:"term" json:"term"`
+	Canonical string    `db:"canonical" json:"canonical"`
+	IsActive  bool      `db:"is_active" json:"is_active"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Debit conditions of a UM Pajak rule
+const (
+	DebitConditionPositive = "positive" // debet > 0
+	DebitConditionNegative = "negative" // debet < 0
+	DebitConditionNonZero  = "nonzero"  // debet != 0
+)
+
+// UmPajakRule detects prepaid tax (uang muka pajak) postings. AccountPattern is an
+// optional glob matched against the account code, e.g. "1151*".
+type UmPajakRule struct {
+	ID             int            `db:"id" json:"id"`
+	Keyword        string         `db:"keyword" json:"keyword"`
+	AccountPattern sql.NullString `db:"account_pattern" json:"account_pattern"`
+	DebitCondition string         `db:"debit_condition" json:"debit_condition"`
+	TaxType        sql.NullString `db:"tax_type" json:"tax_type"`
+	Priority       int            `db:"priority" json:"priority"`
+	IsActive       bool           `db:"is_active" json:"is_active"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+}
+
 type RuleRequest struct {
 	Keyword     string  `json:"keyword" validate:"required"`
 	Value       string  `json:"value"`
@@ -57,10 +141,15 @@ type RuleRequest struct {
 }
 
 type KoreksiRuleRequest struct {
-	Keyword  string `json:"keyword" validate:"required"`
+	Keyword  string `json:"keyword"`
 	Value    string `json:"value" validate:"required"`
 	NotValue string `json:"not_value"`
-	IsActive bool   `json:"is_active"`
+	// Priority is optional; an update without it keeps the stored priority
+	Priority *int `json:"priority"`
+	IsActive bool `json:"is_active"`
+	// Conditions are optional; the keyword may be empty when they are set. An update
+	// without the field keeps the stored conditions, null removes them.
+	Conditions OptionalRuleCondition `json:"conditions"`
 }
 
 type KoreksiRuleValidationError struct {
@@ -78,13 +167,22 @@ type KoreksiRuleImportResult struct {
 	ErrorCount       int                           `json:"error_count"`
 	ErrorReportPath  string                        `json:"error_report_path,omitempty"`
 	ImportTime       time.Time                     `json:"import_time"`
+	// ValidRows is the file row of each valid rule
+	ValidRows []int `json:"-"`
+	// Analysis holds the rule analyzer findings that involve the imported rows
+	Analysis []RuleFinding `json:"rule_analysis,omitempty"`
 }
 
 type ObyekRuleRequest struct {
-	Keyword  string `json:"keyword" validate:"required"`
+	Keyword  string `json:"keyword"`
 	Value    string `json:"value" validate:"required"`
 	NotValue string `json:"not_value"`
-	IsActive bool   `json:"is_active"`
+	// Priority is optional; an update without it keeps the stored priority
+	Priority *int `json:"priority"`
+	IsActive bool `json:"is_active"`
+	// Conditions are optional; the keyword may be empty when they are set. An update
+	// without the field keeps the stored conditions, null removes them.
+	Conditions OptionalRuleCondition `json:"conditions"`
 }
 
 type ObyekRuleValidationError struct {
@@ -102,4 +200,77 @@ type ObyekRuleImportResult struct {
 	ErrorCount       int                         `json:"error_count"`
 	ErrorReportPath  string                      `json:"error_report_path,omitempty"`
 	ImportTime       time.Time                   `json:"import_time"`
+	// ValidRows is the file row of each valid rule
+	ValidRows []int `json:"-"`
+	// Analysis holds the rule analyzer findings that involve the imported rows
+	Analysis []RuleFinding `json:"rule_analysis,omitempty"`
+}
+
+// RulePriorityUpdate sets the priority of a single rule in a bulk reorder
+type RulePriorityUpdate struct {
+	ID       int `json:"id" db:"id"`
+	Priority int `json:"priority" db:"priority"`
+}
+
+type RuleReorderRequest struct {
+	Rules []RulePriorityUpdate `json:"rules" validate:"required"`
+}
+
+type WithholdingTaxRuleRequest struct {
+	Keyword    string         `json:"keyword"`
+	TaxType    string         `json:"tax_type" validate:"required"`
+	TaxRate    float64        `json:"tax_rate"`
+	CalculationMode string    `json:"calculation_mode"` // empty = credit
+	VatRate    float64        `json:"vat_rate"`
+	Rounding   string         `json:"rounding"` // empty = none
+	Priority   int            `json:"priority"`
+	IsActive   bool           `json:"is_active"`
+	Conditions *RuleCondition `json:"conditions"`
+	ValidFrom  string         `json:"valid_from"` // YYYY-MM-DD, empty = open
+	ValidTo    string         `json:"valid_to"`
+}
+
+type WHTResolutionPolicyRequest struct {
+	Policy string `json:"policy" validate:"required"`
+}
+
+type TaxKeywordRequest struct {
+	Keyword     string `json:"keyword" validate:"required"`
+	TaxCategory string `json:"tax_category" validate:"required"`
+	Priority    int    `json:"priority"`
+	IsActive    bool   `json:"is_active"`
+	ValidFrom   string `json:"valid_from"` // YYYY-MM-DD, empty = open
+	ValidTo     string `json:"valid_to"`
+}
+
+type KeywordSynonymRequest struct {
+	Term      string `json:"term" validate:"required"`
+	Canonical string `json:"canonical" validate:"required"`
+	IsActive  bool   `json:"is_active"`
+}
+
+type UmPajakRuleRequest struct {
+	Keyword        string `json:"keyword" validate:"required"`
+	AccountPattern string `json:"account_pattern"`
+	DebitCondition string `json:"debit_condition"`
+	TaxType        string `json:"tax_type"`
+	Priority       int    `json:"priority"`
+	IsActive       bool   `json:"is_active"`
+}
+
+type UmPajakRuleValidationError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+type UmPajakRuleImportResult struct {
+	ValidRules       []UmPajakRule                `json:"valid_rules"`
+	ValidationErrors []UmPajakRuleValidationError `json:"validation_errors"`
+	TotalRows        int                          `json:"total_rows"`
+	ValidCount       int                          `json:"valid_count"`
+	ErrorCount       int                          `json:"error_count"`
+	ErrorReportPath  string                       `json:"error_report_path,omitempty"`
+	ImportTime       time.Time                    `json:"import_time"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SessionEvent is a progress update of an upload session, published by the worker
+// and streamed to the browser
+type SessionEvent struct {
+	ID                 string    `json:"id,omitempty"`
+	SessionCode        string    `json:"session_code"`
+	Type               string    `json:"type"`            // progress, status, batch_error
+	Stage              string    `json:"stage,omitempty"` // upload or processing
+	Status             string    `json:"status,omitempty"`
+	TotalRows          int       `json:"total_rows"`
+	ProcessedRows      int       `json:"processed_rows"`
+	FailedRows         int       `json:"failed_rows"`
+	ProgressPercentage float64   `json:"progress_percentage"`
+	Batch              int       `json:"batch,omitempty"`
+	Error              string    `json:"error,omitempty"`
+	At                 time.Time `json:"at"`
+}
@@ -0,0 +1,102 @@
+package models
+
+// SimulatedRule is a proposed koreksi/obyek rule sent to the simulation endpoint.
+// For updates, ID refers to an existing rule; IsActive=false removes it from the run.
+// Priority and Conditions behave as in a rule update: when left out, the stored
+// values are kept.
+type SimulatedRule struct {
+	ID         int                   `json:"id"`
+	Keyword    string                `json:"keyword"`
+	Value      string                `json:"value"`
+	NotValue   string                `json:"not_value"`
+	Priority   *int                  `json:"priority"`
+	IsActive   *bool                 `json:"is_active"`
+	Conditions OptionalRuleCondition `json:"conditions"`
+}
+
+// RuleSimulationChanges lists proposed changes for one rule table
+type RuleSimulationChanges struct {
+	Add    []SimulatedRule `json:"add"`
+	Update []SimulatedRule `json:"update"`
+	Delete []int           `json:"delete"`
+}
+
+type RuleSimulationRequest struct {
+	KoreksiRules RuleSimulationChanges `json:"koreksi_rules"`
+	ObyekRules   RuleSimulationChanges `json:"obyek_rules"`
+	MaxRows      int                   `json:"max_rows"`      // Maximum changed rows returned in the diff
+	MaxScanRows  int                   `json:"max_scan_rows"` // Maximum transactions run through the rules
+}
+
+// SimulationFieldChange is a single old -> new value change on an output field
+type SimulationFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SimulationRowDiff lists the field changes for one transaction
+type SimulationRowDiff struct {
+	TransactionID  int64                   `json:"transaction_id"`
+	DocumentNumber string                  `json:"document_number"`
+	Account        string                  `json:"account"`
+	Keterangan     string                  `json:"keterangan"`
+	Changes        []SimulationFieldChange `json:"changes"`
+}
+
+// SimulationRuleCount summarises the impact of a single rule in a simulation.
+// Proposed (not yet saved) rules have negative IDs.
+type SimulationRuleCount struct {
+	RuleType    string `json:"rule_type"`
+	RuleID      int    `json:"rule_id"`
+	Keyword     string `json:"keyword"`
+	Proposed    bool   `json:"proposed"`
+	MatchedRows int    `json:"matched_rows"` // Rows where the rule produced a field in the simulation
+	ChangedRows int    `json:"changed_rows"` // Of those, rows where the field value changes
+	LostRows    int    `json:"lost_rows"`    // Rows currently attributed to the rule whose value would change
+}
+
+// RuleSimulationResult is the outcome of a simulation. Only the first MaxScanRows
+// transactions of the session are simulated; ScanTruncated tells the session has more.
+type RuleSimulationResult struct {
+	SessionCode       string                `json:"session_code"`
+	TotalRows         int                   `json:"total_rows"`
+	ScanTruncated     bool                  `json:"scan_truncated"`
+	ChangedRows       int                   `json:"changed_rows"`
+	FieldChangeCounts map[string]int        `json:"field_change_counts"`
+	RuleCounts        []SimulationRuleCount `json:"rule_counts"`
+	Rows              []SimulationRowDiff   `json:"rows"`
+	RowsTruncated     bool                  `json:"rows_truncated"`
+}
+
+// RuleTestRequest is a single made-up row run through the active rules. Keywords are
+// optional keywords to check against its keterangan.
+type RuleTestRequest struct {
+	Keterangan   string   `json:"keterangan"`
+	Account      string   `json:"account"`
+	DocumentType string   `json:"document_type"`
+	PostingDate  string   `json:"posting_date"` // YYYY-MM-DD, empty = today
+	Debet        float64  `json:"debet"`
+	Credit       float64  `json:"credit"`
+	Keywords     []string `json:"keywords"`
+}
+
+// RuleTestKeyword shows how a keyword is matched: its normalized form, the variants
+// the keyword synonyms add, and the variant found in the keterangan
+type RuleTestKeyword struct {
+	Keyword        string   `json:"keyword"`
+	Normalized     string   `json:"normalized"`
+	Variants       []string `json:"variants,omitempty"`
+	Matched        bool     `json:"matched"`
+	MatchedVariant string   `json:"matched_variant,omitempty"`
+}
+
+type RuleTestResult struct {
+	Keterangan           string            `json:"keterangan"`
+	NormalizedKeterangan string            `json:"normalized_keterangan"`
+	Outputs              map[string]string `json:"outputs"`
+	RuleProvenance       RuleProvenanceMap `json:"rule_provenance"`
+	ProcessingNotes      string            `json:"processing_notes,omitempty"`
+	ProcessingWarning    string            `json:"processing_warning,omitempty"`
+	Keywords             []RuleTestKeyword `json:"keywords,omitempty"`
+}
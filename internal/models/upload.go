@@ -245,6 +245,26 @@ type TransactionData struct {
 	// Processing
 	IsProcessed      bool       `db:"is_processed" json:"is_processed"`
 	ProcessingError  *string    `db:"processing_error" json:"processing_error,omitempty"`
+	ProcessingNotes  *string    `db:"processing_notes" json:"processing_notes,omitempty"`
+	// ProcessingWarning flags rows that need review, e.g. conflicting WHT rules
+	ProcessingWarning *string   `db:"processing_warning" json:"processing_warning,omitempty"`
+	RuleProvenance   RuleProvenanceMap `db:"rule_provenance" json:"rule_provenance,omitempty"`
+
+	// Manual overrides: an overridden field keeps its value on reprocess
+	KoreksiOverride   bool       `db:"koreksi_override" json:"koreksi_override"`
+	KoreksiOverrideBy *int       `db:"koreksi_override_by" json:"koreksi_override_by,omitempty"`
+	KoreksiOverrideAt *time.Time `db:"koreksi_override_at" json:"koreksi_override_at,omitempty"`
+	ObyekOverride     bool       `db:"obyek_override" json:"obyek_override"`
+	ObyekOverrideBy   *int       `db:"obyek_override_by" json:"obyek_override_by,omitempty"`
+	ObyekOverrideAt   *time.Time `db:"obyek_override_at" json:"obyek_override_at,omitempty"`
+
+	// Classifier suggestions for rows no koreksi/obyek rule matched; never applied to koreksi/obyek
+	SuggestedKoreksi           *string  `db:"suggested_koreksi" json:"suggested_koreksi,omitempty"`
+	SuggestedKoreksiConfidence *float64 `db:"suggested_koreksi_confidence" json:"suggested_koreksi_confidence,omitempty"`
+	SuggestedObyek             *string  `db:"suggested_obyek" json:"suggested_obyek,omitempty"`
+	SuggestedObyekConfidence   *float64 `db:"suggested_obyek_confidence" json:"suggested_obyek_confidence,omitempty"`
+	SuggestionModelVersion     *int     `db:"suggestion_model_version" json:"suggestion_model_version,omitempty"`
+
 	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
 }
@@ -276,6 +296,8 @@ func (td *TransactionData) MarshalJSON() ([]byte, error) {
 		PkCr                 *float64               `json:"pk_cr,omitempty"`
 		AnalisaTambahan      *string                `json:"analisa_tambahan,omitempty"`
 		ProcessingError      *string                `json:"processing_error,omitempty"`
+		ProcessingNotes      *string                `json:"processing_notes,omitempty"`
+		ProcessingWarning    *string                `json:"processing_warning,omitempty"`
 		*Alias
 	}{
 		Alias: (*Alias)(td),
@@ -345,6 +367,8 @@ func (td *TransactionData) MarshalJSON() ([]byte, error) {
 	aux.PkCr = pkCr
 	aux.AnalisaTambahan = td.AnalisaTambahan
 	aux.ProcessingError = td.ProcessingError
+	aux.ProcessingNotes = td.ProcessingNotes
+	aux.ProcessingWarning = td.ProcessingWarning
 
 	return json.Marshal(aux)
 }
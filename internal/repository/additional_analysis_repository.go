@@ -22,15 +22,15 @@ func NewAdditionalAnalysisRepository(db *sqlx.DB) *AdditionalAnalysisRepository
 func (r *AdditionalAnalysisRepository) Create(analysis *models.AdditionalAnalysis) error {
 	query := `
 		INSERT INTO additional_analyses (
-			account_code, analysis_type, analysis_title, status, notes, created_by, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			account_code, analysis_type, analysis_title, keyword_condition, status, notes, created_by, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	createdAt := time.Now()
 	updatedAt := createdAt
 
 	result, err := r.db.Exec(query,
-		analysis.AccountCode, analysis.AnalysisType, analysis.AnalysisTitle, analysis.Status,
+		analysis.AccountCode, analysis.AnalysisType, analysis.AnalysisTitle, analysis.KeywordCondition, analysis.Status,
 		analysis.Notes, analysis.CreatedBy, createdAt, updatedAt,
 	)
 	if err != nil {
@@ -54,7 +54,7 @@ func (r *AdditionalAnalysisRepository) GetByID(id int) (*models.AdditionalAnalys
 	query := `
 		SELECT
 			aa.id, aa.account_code, a.account_name,
-			aa.analysis_type, aa.analysis_title, aa.status, aa.notes, aa.created_by,
+			aa.analysis_type, aa.analysis_title, aa.keyword_condition, aa.status, aa.notes, aa.created_by,
 			aa.created_at, aa.updated_at
 		FROM additional_analyses aa
 		LEFT JOIN accounts a ON aa.account_code = a.account_code
@@ -144,7 +144,7 @@ func (r *AdditionalAnalysisRepository) GetAll(filter models.AdditionalAnalysisFi
 	query := fmt.Sprintf(`
 		SELECT
 			aa.id, aa.account_code, a.account_name,
-			aa.analysis_type, aa.analysis_title, aa.status, aa.notes, aa.created_by,
+			aa.analysis_type, aa.analysis_title, aa.keyword_condition, aa.status, aa.notes, aa.created_by,
 			aa.created_at, aa.updated_at
 		FROM additional_analyses aa
 		LEFT JOIN accounts a ON aa.account_code = a.account_code
@@ -168,7 +168,7 @@ func (r *AdditionalAnalysisRepository) GetAll(filter models.AdditionalAnalysisFi
 func (r *AdditionalAnalysisRepository) Update(id int, analysis *models.AdditionalAnalysis) error {
 	query := `
 		UPDATE additional_analyses
-		SET account_code = ?, analysis_type = ?, analysis_title = ?, status = ?, notes = ?, updated_at = ?
+		SET account_code = ?, analysis_type = ?, analysis_title = ?, keyword_condition = ?, status = ?, notes = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -176,7 +176,7 @@ func (r *AdditionalAnalysisRepository) Update(id int, analysis *models.Additiona
 	analysis.UpdatedAt = updatedAt
 
 	result, err := r.db.Exec(query,
-		analysis.AccountCode, analysis.AnalysisType, analysis.AnalysisTitle, analysis.Status, analysis.Notes,
+		analysis.AccountCode, analysis.AnalysisType, analysis.AnalysisTitle, analysis.KeywordCondition, analysis.Status, analysis.Notes,
 		updatedAt, id,
 	)
 	if err != nil {
@@ -220,7 +220,7 @@ func (r *AdditionalAnalysisRepository) Delete(id int) error {
 // GetByAccountCode retrieves all additional analyses for a specific account
 func (r *AdditionalAnalysisRepository) GetByAccountCode(accountCode string) ([]models.AdditionalAnalysis, error) {
 	query := `
-		SELECT id, account_code, analysis_type, analysis_title, status, notes, created_by, created_at, updated_at
+		SELECT id, account_code, analysis_type, analysis_title, keyword_condition, status, notes, created_by, created_at, updated_at
 		FROM additional_analyses
 		WHERE account_code = ? AND status = 'active'
 		ORDER BY created_at DESC
@@ -235,6 +235,24 @@ func (r *AdditionalAnalysisRepository) GetByAccountCode(accountCode string) ([]m
 	return analyses, nil
 }
 
+// GetAllActive retrieves every active additional analysis, ordered for the processing engine
+func (r *AdditionalAnalysisRepository) GetAllActive() ([]models.AdditionalAnalysis, error) {
+	query := `
+		SELECT id, account_code, analysis_type, analysis_title, keyword_condition, status, notes, created_by, created_at, updated_at
+		FROM additional_analyses
+		WHERE status = 'active'
+		ORDER BY account_code, analysis_type, id
+	`
+
+	var analyses []models.AdditionalAnalysis
+	err := r.db.Select(&analyses, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active additional analyses: %w", err)
+	}
+
+	return analyses, nil
+}
+
 // HardDelete permanently deletes an additional analysis by ID
 func (r *AdditionalAnalysisRepository) HardDelete(id int) error {
 	query := "DELETE FROM additional_analyses WHERE id = ?"
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"accounting-web/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ClassifierRepository struct {
+	db *sqlx.DB
+}
+
+func NewClassifierRepository(db *sqlx.DB) *ClassifierRepository {
+	return &ClassifierRepository{db: db}
+}
+
+// CreateModel queues a new model version, numbered after the latest one
+func (r *ClassifierRepository) CreateModel(model *models.ClassifierModel) error {
+	query := `INSERT INTO classifier_models (version, status, created_by)
+	          SELECT COALESCE(MAX(version), 0) + 1, ?, ? FROM classifier_models`
+	result, err := r.db.Exec(query, models.ClassifierStatusQueued, model.CreatedBy)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+
+	created, err := r.GetModelByID(int(id))
+	if err != nil {
+		return err
+	}
+	*model = *created
+	return nil
+}
+
+// UpdateModel stores the status, statistics and trained counts of a model
+func (r *ClassifierRepository) UpdateModel(model *models.ClassifierModel) error {
+	query := `UPDATE classifier_models SET status = :status, training_rows = :training_rows,
+	          koreksi_classes = :koreksi_classes, obyek_classes = :obyek_classes,
+	          vocabulary_size = :vocabulary_size, koreksi_accuracy = :koreksi_accuracy,
+	          obyek_accuracy = :obyek_accuracy, model = :model, error_message = :error_message,
+	          trained_at = :trained_at
+	          WHERE id = :id`
+	_, err := r.db.NamedExec(query, model)
+	return err
+}
+
+// GetModelByID returns a model without its serialized counts
+func (r *ClassifierRepository) GetModelByID(id int) (*models.ClassifierModel, error) {
+	var model models.ClassifierModel
+	query := `SELECT id, version, status, is_active, training_rows, koreksi_classes, obyek_classes,
+	          vocabulary_size, koreksi_accuracy, obyek_accuracy, NULL AS model, error_message,
+	          created_by, created_at, trained_at
+	          FROM classifier_models WHERE id = ?`
+	err := r.db.Get(&model, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// GetModels lists model versions newest first, without their serialized counts
+func (r *ClassifierRepository) GetModels(limit, offset int) ([]models.ClassifierModel, int, error) {
+	var list []models.ClassifierModel
+	var total int
+
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM classifier_models")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, version, status, is_active, training_rows, koreksi_classes, obyek_classes,
+	          vocabulary_size, koreksi_accuracy, obyek_accuracy, NULL AS model, error_message,
+	          created_by, created_at, trained_at
+	          FROM classifier_models ORDER BY version DESC LIMIT ? OFFSET ?`
+	err = r.db.Select(&list, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+// GetActiveModel returns the active model including its serialized counts, or
+// sql.ErrNoRows when none is active
+func (r *ClassifierRepository) GetActiveModel() (*models.ClassifierModel, error) {
+	var model models.ClassifierModel
+	query := "SELECT * FROM classifier_models WHERE is_active = TRUE AND status = ? LIMIT 1"
+	err := r.db.Get(&model, query, models.ClassifierStatusReady)
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// ActivateModel makes a ready model the one used for suggestions
+func (r *ClassifierRepository) ActivateModel(id int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE classifier_models SET is_active = FALSE WHERE is_active = TRUE AND id <> ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE classifier_models SET is_active = TRUE WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTrainingRows returns up to limit processed rows without errors that have a koreksi
+// or obyek, most recent first
+func (r *ClassifierRepository) GetTrainingRows(limit int) ([]models.ClassifierTrainingRow, error) {
+	var rows []models.ClassifierTrainingRow
+	query := `SELECT COALESCE(keterangan, '') AS keterangan, COALESCE(account, '') AS account,
+	          COALESCE(document_type, '') AS document_type, koreksi, obyek, koreksi_override, obyek_override
+	          FROM transaction_data
+	          WHERE is_processed = TRUE AND processing_error IS NULL
+	          AND (koreksi IS NOT NULL OR obyek IS NOT NULL)
+	          ORDER BY id DESC
+	          LIMIT ?`
+	err := r.db.Select(&rows, query, limit)
+	return rows, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"accounting-web/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ProcessingStepRepository struct {
+	db *sqlx.DB
+}
+
+func NewProcessingStepRepository(db *sqlx.DB) *ProcessingStepRepository {
+	return &ProcessingStepRepository{db: db}
+}
+
+// GetSteps returns the stored step settings in pipeline order
+func (r *ProcessingStepRepository) GetSteps() ([]models.ProcessingStep, error) {
+	var steps []models.ProcessingStep
+	query := "SELECT * FROM processing_steps ORDER BY step_order, name"
+	err := r.db.Select(&steps, query)
+	return steps, err
+}
+
+// SaveStep creates or replaces the settings of the step with step.Name
+func (r *ProcessingStepRepository) SaveStep(step *models.ProcessingStep) error {
+	query := `INSERT INTO processing_steps (name, description, enabled, step_order, config, updated_by)
+	          VALUES (:name, :description, :enabled, :step_order, :config, :updated_by)
+	          ON DUPLICATE KEY UPDATE enabled = VALUES(enabled), step_order = VALUES(step_order),
+	          config = VALUES(config), updated_by = VALUES(updated_by)`
+	if _, err := r.db.NamedExec(query, step); err != nil {
+		return err
+	}
+
+	saved, err := r.GetStepByName(step.Name)
+	if err != nil {
+		return err
+	}
+	*step = *saved
+	return nil
+}
+
+func (r *ProcessingStepRepository) GetStepByName(name string) (*models.ProcessingStep, error) {
+	var step models.ProcessingStep
+	query := "SELECT * FROM processing_steps WHERE name = ?"
+	err := r.db.Get(&step, query, name)
+	if err != nil {
+		return nil, err
+	}
+	return &step, nil
+}
+
+// SaveStepStats stores the per-step totals of a run, replacing totals saved earlier
+// for the same run and step
+func (r *ProcessingStepRepository) SaveStepStats(stats []models.ProcessingStepStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO processing_step_stats (run_id, step_name, step_order, transactions, fields_changed, errors, duration_ms)
+	          VALUES (:run_id, :step_name, :step_order, :transactions, :fields_changed, :errors, :duration_ms)
+	          ON DUPLICATE KEY UPDATE step_order = VALUES(step_order), transactions = VALUES(transactions),
+	          fields_changed = VALUES(fields_changed), errors = VALUES(errors), duration_ms = VALUES(duration_ms)`
+
+	for _, stat := range stats {
+		if _, err := tx.NamedExec(query, stat); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetStepStatsByRun returns the per-step totals of a run in pipeline order
+func (r *ProcessingStepRepository) GetStepStatsByRun(runID int) ([]models.ProcessingStepStat, error) {
+	var stats []models.ProcessingStepStat
+	query := "SELECT * FROM processing_step_stats WHERE run_id = ? ORDER BY step_order, step_name"
+	err := r.db.Select(&stats, query, runID)
+	return stats, err
+}
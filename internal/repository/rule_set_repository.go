@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RuleSetRepository struct {
+	db *sqlx.DB
+}
+
+func NewRuleSetRepository(db *sqlx.DB) *RuleSetRepository {
+	return &RuleSetRepository{db: db}
+}
+
+// CreateVersion stores a rule-set version. Versions are immutable: when a version
+// with the same checksum already exists, its ID is returned instead.
+func (r *RuleSetRepository) CreateVersion(version *models.RuleSetVersion) error {
+	query := `INSERT INTO rule_set_versions (checksum, snapshot, account_count, koreksi_rule_count,
+	          obyek_rule_count, wht_rule_count, tax_keyword_count, um_pajak_rule_count, additional_analysis_count,
+	          note, created_by)
+	          VALUES (:checksum, :snapshot, :account_count, :koreksi_rule_count,
+	          :obyek_rule_count, :wht_rule_count, :tax_keyword_count, :um_pajak_rule_count, :additional_analysis_count,
+	          :note, :created_by)
+	          ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`
+	result, err := r.db.NamedExec(query, version)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	version.ID = int(id)
+	return nil
+}
+
+// GetVersionByID returns a version including its snapshot
+func (r *RuleSetRepository) GetVersionByID(id int) (*models.RuleSetVersion, error) {
+	var version models.RuleSetVersion
+	query := "SELECT * FROM rule_set_versions WHERE id = ?"
+	err := r.db.Get(&version, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetVersions lists versions newest first, without their snapshots
+func (r *RuleSetRepository) GetVersions(limit, offset int) ([]models.RuleSetVersion, int, error) {
+	var versions []models.RuleSetVersion
+	var total int
+
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM rule_set_versions")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, checksum, '' AS snapshot, account_count, koreksi_rule_count, obyek_rule_count,
+	          wht_rule_count, tax_keyword_count, um_pajak_rule_count, additional_analysis_count,
+	          note, created_by, created_at
+	          FROM rule_set_versions ORDER BY id DESC LIMIT ? OFFSET ?`
+	err = r.db.Select(&versions, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return versions, total, nil
+}
+
+// Processing runs
+func (r *RuleSetRepository) CreateRun(run *models.ProcessingRun) error {
+	query := `INSERT INTO processing_runs (session_id, session_code, rule_set_version_id, run_type,
+	          status, reset_filter, triggered_by, started_at)
+	          VALUES (:session_id, :session_code, :rule_set_version_id, :run_type,
+	          :status, :reset_filter, :triggered_by, :started_at)`
+	result, err := r.db.NamedExec(query, run)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	run.ID = int(id)
+	return nil
+}
+
+func (r *RuleSetRepository) GetRunByID(id int) (*models.ProcessingRun, error) {
+	var run models.ProcessingRun
+	query := "SELECT * FROM processing_runs WHERE id = ?"
+	err := r.db.Get(&run, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// UpdateRun saves the progress and outcome of a run
+func (r *RuleSetRepository) UpdateRun(run *models.ProcessingRun) error {
+	query := `UPDATE processing_runs SET rule_set_version_id = :rule_set_version_id, status = :status,
+	          processed_rows = :processed_rows, failed_rows = :failed_rows, error_message = :error_message,
+	          started_at = :started_at, finished_at = :finished_at
+	          WHERE id = :id`
+	_, err := r.db.NamedExec(query, run)
+	return err
+}
+
+func (r *RuleSetRepository) GetRunsBySessionID(sessionID int) ([]models.ProcessingRun, error) {
+	var runs []models.ProcessingRun
+	query := "SELECT * FROM processing_runs WHERE session_id = ? ORDER BY id DESC"
+	err := r.db.Select(&runs, query, sessionID)
+	return runs, err
+}
+
+// BuildChangeSummary compares the snapshot taken before a reprocess run with the
+// current values of the same rows
+func (r *RuleSetRepository) BuildChangeSummary(runID int) (*models.RunChangeSummary, error) {
+	var counts struct {
+		TotalRows           int `db:"total_rows"`
+		ChangedRows         int `db:"changed_rows"`
+		AnalisaNatureAkun   int `db:"analisa_nature_akun"`
+		AnalisaKoreksiObyek int `db:"analisa_koreksi_obyek"`
+		Koreksi             int `db:"koreksi"`
+		Obyek               int `db:"obyek"`
+		UmPajakDB           int `db:"um_pajak_db"`
+		PmDB                int `db:"pm_db"`
+		Wth21Cr             int `db:"wth_21_cr"`
+		Wth23Cr             int `db:"wth_23_cr"`
+		Wth26Cr             int `db:"wth_26_cr"`
+		Wth42Cr             int `db:"wth_4_2_cr"`
+		Wth15Cr             int `db:"wth_15_cr"`
+		PkCr                int `db:"pk_cr"`
+		AnalisaTambahan     int `db:"analisa_tambahan"`
+	}
+
+	query := `SELECT
+	          COUNT(*) AS total_rows,
+	          COALESCE(SUM(
+	              NOT (s.analisa_nature_akun <=> td.analisa_nature_akun) OR NOT (s.analisa_koreksi_obyek <=> td.analisa_koreksi_obyek) OR
+	              NOT (s.koreksi <=> td.koreksi) OR NOT (s.obyek <=> td.obyek) OR
+	              NOT (s.um_pajak_db <=> td.um_pajak_db) OR NOT (s.pm_db <=> td.pm_db) OR
+	              NOT (s.wth_21_cr <=> td.wth_21_cr) OR NOT (s.wth_23_cr <=> td.wth_23_cr) OR
+	              NOT (s.wth_26_cr <=> td.wth_26_cr) OR NOT (s.wth_4_2_cr <=> td.wth_4_2_cr) OR
+	              NOT (s.wth_15_cr <=> td.wth_15_cr) OR NOT (s.pk_cr <=> td.pk_cr) OR
+	              NOT (COALESCE(s.analisa_tambahan, '') <=> COALESCE(td.analisa_tambahan, ''))
+	          ), 0) AS changed_rows,
+	          COALESCE(SUM(NOT (s.analisa_nature_akun <=> td.analisa_nature_akun)), 0) AS analisa_nature_akun,
+	          COALESCE(SUM(NOT (s.analisa_koreksi_obyek <=> td.analisa_koreksi_obyek)), 0) AS analisa_koreksi_obyek,
+	          COALESCE(SUM(NOT (s.koreksi <=> td.koreksi)), 0) AS koreksi,
+	          COALESCE(SUM(NOT (s.obyek <=> td.obyek)), 0) AS obyek,
+	          COALESCE(SUM(NOT (s.um_pajak_db <=> td.um_pajak_db)), 0) AS um_pajak_db,
+	          COALESCE(SUM(NOT (s.pm_db <=> td.pm_db)), 0) AS pm_db,
+	          COALESCE(SUM(NOT (s.wth_21_cr <=> td.wth_21_cr)), 0) AS wth_21_cr,
+	          COALESCE(SUM(NOT (s.wth_23_cr <=> td.wth_23_cr)), 0) AS wth_23_cr,
+	          COALESCE(SUM(NOT (s.wth_26_cr <=> td.wth_26_cr)), 0) AS wth_26_cr,
+	          COALESCE(SUM(NOT (s.wth_4_2_cr <=> td.wth_4_2_cr)), 0) AS wth_4_2_cr,
+	          COALESCE(SUM(NOT (s.wth_15_cr <=> td.wth_15_cr)), 0) AS wth_15_cr,
+	          COALESCE(SUM(NOT (s.pk_cr <=> td.pk_cr)), 0) AS pk_cr,
+	          COALESCE(SUM(NOT (COALESCE(s.analisa_tambahan, '') <=> COALESCE(td.analisa_tambahan, ''))), 0) AS analisa_tambahan
+	          FROM processing_run_snapshots s
+	          JOIN transaction_data td ON td.id = s.transaction_id
+	          WHERE s.run_id = ?`
+	if err := r.db.Get(&counts, query, runID); err != nil {
+		return nil, err
+	}
+
+	summary := &models.RunChangeSummary{
+		TotalRows:   counts.TotalRows,
+		ChangedRows: counts.ChangedRows,
+		FieldChanges: map[string]int{
+			"analisa_nature_akun":   counts.AnalisaNatureAkun,
+			"analisa_koreksi_obyek": counts.AnalisaKoreksiObyek,
+			"koreksi":               counts.Koreksi,
+			"obyek":                 counts.Obyek,
+			"um_pajak_db":           counts.UmPajakDB,
+			"pm_db":                 counts.PmDB,
+			"wth_21_cr":             counts.Wth21Cr,
+			"wth_23_cr":             counts.Wth23Cr,
+			"wth_26_cr":             counts.Wth26Cr,
+			"wth_4_2_cr":            counts.Wth42Cr,
+			"wth_15_cr":             counts.Wth15Cr,
+			"pk_cr":                 counts.PkCr,
+			"analisa_tambahan":      counts.AnalisaTambahan,
+		},
+		Transitions: []models.ValueTransition{},
+	}
+
+	// Most frequent old -> new moves for the classification fields
+	for _, field := range []string{"koreksi", "obyek"} {
+		var transitions []models.ValueTransition
+		transitionQuery := fmt.Sprintf(`SELECT '%[1]s' AS field, COALESCE(s.%[1]s, '') AS old_value,
+		          COALESCE(td.%[1]s, '') AS new_value, COUNT(*) AS row_count
+		          FROM processing_run_snapshots s
+		          JOIN transaction_data td ON td.id = s.transaction_id
+		          WHERE s.run_id = ? AND NOT (s.%[1]s <=> td.%[1]s)
+		          GROUP BY old_value, new_value
+		          ORDER BY row_count DESC
+		          LIMIT 20`, field)
+		if err := r.db.Select(&transitions, transitionQuery, runID); err != nil {
+			return nil, err
+		}
+		summary.Transitions = append(summary.Transitions, transitions...)
+	}
+
+	return summary, nil
+}
+
+// SaveChangeSummary stores the before/after summary on the run
+func (r *RuleSetRepository) SaveChangeSummary(runID int, summary *models.RunChangeSummary) error {
+	query := "UPDATE processing_runs SET change_summary = ? WHERE id = ?"
+	_, err := r.db.Exec(query, summary, runID)
+	return err
+}
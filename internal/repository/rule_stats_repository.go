@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RuleStatsRepository struct {
+	db *sqlx.DB
+}
+
+func NewRuleStatsRepository(db *sqlx.DB) *RuleStatsRepository {
+	return &RuleStatsRepository{db: db}
+}
+
+// SaveRuleHitStats stores the hit counts of a run, replacing counts saved earlier for
+// the same run and rule
+func (r *RuleStatsRepository) SaveRuleHitStats(stats []models.RuleHitStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO rule_hit_stats (run_id, session_code, rule_type, rule_id, hits)
+	          VALUES (:run_id, :session_code, :rule_type, :rule_id, :hits)
+	          ON DUPLICATE KEY UPDATE hits = VALUES(hits)`
+
+	for _, stat := range stats {
+		if _, err := tx.NamedExec(query, stat); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRuleHitStatsByRun returns the hit counts of one run, most used rules first
+func (r *RuleStatsRepository) GetRuleHitStatsByRun(runID int) ([]models.RuleHitStat, error) {
+	var stats []models.RuleHitStat
+	query := "SELECT * FROM rule_hit_stats WHERE run_id = ? ORDER BY hits DESC, rule_type, rule_id"
+	err := r.db.Select(&stats, query, runID)
+	return stats, err
+}
+
+// GetRuleHitSummary totals the hits of every koreksi, obyek, WHT and tax keyword rule
+// (or those of ruleType) over the runs recorded between from and to (either may be nil).
+// Active rules without hits are included with zero, inactive rules only with hits.
+func (r *RuleStatsRepository) GetRuleHitSummary(ruleType string, from, to *time.Time) ([]models.RuleHitSummary, error) {
+	var summary []models.RuleHitSummary
+
+	var hitFilters []string
+	var args []interface{}
+	if from != nil {
+		hitFilters = append(hitFilters, "created_at >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		hitFilters = append(hitFilters, "created_at < ?")
+		args = append(args, *to)
+	}
+	hitWhere := ""
+	if len(hitFilters) > 0 {
+		hitWhere = "WHERE " + strings.Join(hitFilters, " AND ")
+	}
+	hits := `(SELECT rule_type, rule_id, SUM(hits) AS hits, COUNT(DISTINCT run_id) AS runs,
+	          MAX(created_at) AS last_hit_at
+	          FROM rule_hit_stats ` + hitWhere + `
+	          GROUP BY rule_type, rule_id)`
+
+	// One SELECT per rule table; the rule type is compared as a literal so the join
+	// follows the rule_hit_stats column collation
+	tables := []struct{ ruleType, table string }{
+		{models.RuleTypeKoreksi, "koreksi_rules"},
+		{models.RuleTypeObyek, "obyek_rules"},
+		{models.RuleTypeWHT, "withholding_tax_rules"},
+		{models.RuleTypeTaxKeyword, "tax_keywords"},
+	}
+	var selects []string
+	var queryArgs []interface{}
+	for _, t := range tables {
+		if ruleType != "" && ruleType != t.ruleType {
+			continue
+		}
+		selects = append(selects, `SELECT '`+t.ruleType+`' AS rule_type, rules.id AS rule_id, rules.keyword,
+		          rules.is_active, COALESCE(h.hits, 0) AS hits, COALESCE(h.runs, 0) AS runs, h.last_hit_at
+		          FROM `+t.table+` rules
+		          LEFT JOIN `+hits+` h ON h.rule_type = '`+t.ruleType+`' AND h.rule_id = rules.id
+		          WHERE rules.is_active = TRUE OR h.hits > 0`)
+		queryArgs = append(queryArgs, args...)
+	}
+	if len(selects) == 0 {
+		return []models.RuleHitSummary{}, nil
+	}
+	query := strings.Join(selects, " UNION ALL ") + " ORDER BY hits DESC, rule_type, rule_id"
+
+	err := r.db.Select(&summary, query, queryArgs...)
+	return summary, err
+}
+
+// GetUnmatchedKeterangan groups the processed rows that got neither koreksi nor obyek
+// by keterangan, most frequent first
+func (r *RuleStatsRepository) GetUnmatchedKeterangan(filter models.UnmatchedKeteranganFilter) ([]models.UnmatchedKeterangan, error) {
+	var rows []models.UnmatchedKeterangan
+
+	conditions := []string{"is_processed = TRUE", "koreksi IS NULL", "obyek IS NULL"}
+	var args []interface{}
+	if filter.SessionCode != "" {
+		conditions = append(conditions, "session_code = ?")
+		args = append(args, filter.SessionCode)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "posting_date >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "posting_date <= ?")
+		args = append(args, *filter.To)
+	}
+
+	query := `SELECT LOWER(TRIM(COALESCE(keterangan, ''))) AS keterangan, COUNT(*) AS row_count,
+	          COALESCE(SUM(debet), 0) AS total_debet, COALESCE(SUM(credit), 0) AS total_credit,
+	          COALESCE(SUM(net), 0) AS total_net, COUNT(DISTINCT session_code) AS sessions
+	          FROM transaction_data
+	          WHERE ` + strings.Join(conditions, " AND ") + `
+	          GROUP BY LOWER(TRIM(COALESCE(keterangan, '')))
+	          ORDER BY row_count DESC, SUM(ABS(net)) DESC
+	          LIMIT ?`
+	args = append(args, filter.Limit)
+
+	err := r.db.Select(&rows, query, args...)
+	return rows, err
+}
+
+// GetUnclassifiedRows returns up to limit processed rows of the session and/or posting
+// date range that got neither koreksi nor obyek, newest first
+func (r *RuleStatsRepository) GetUnclassifiedRows(sessionCode string, from, to *time.Time, limit int) ([]models.SuggestionSourceRow, error) {
+	var rows []models.SuggestionSourceRow
+
+	conditions := []string{"is_processed = TRUE", "koreksi IS NULL", "obyek IS NULL"}
+	var args []interface{}
+	if sessionCode != "" {
+		conditions = append(conditions, "session_code = ?")
+		args = append(args, sessionCode)
+	}
+	if from != nil {
+		conditions = append(conditions, "posting_date >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		conditions = append(conditions, "posting_date <= ?")
+		args = append(args, *to)
+	}
+
+	query := `SELECT COALESCE(keterangan, '') AS keterangan, net, koreksi, obyek, koreksi_override, obyek_override
+	          FROM transaction_data
+	          WHERE ` + strings.Join(conditions, " AND ") + `
+	          ORDER BY id DESC
+	          LIMIT ?`
+	args = append(args, limit)
+
+	err := r.db.Select(&rows, query, args...)
+	return rows, err
+}
+
+// GetManuallyClassifiedRows returns up to limit rows, across all sessions, whose
+// koreksi ("koreksi") or obyek ("obyek") a user set manually, most recent first
+func (r *RuleStatsRepository) GetManuallyClassifiedRows(field string, limit int) ([]models.SuggestionSourceRow, error) {
+	var rows []models.SuggestionSourceRow
+
+	var condition, order string
+	switch field {
+	case models.RuleTypeKoreksi:
+		condition, order = "koreksi_override = TRUE AND koreksi IS NOT NULL", "koreksi_override_at"
+	case models.RuleTypeObyek:
+		condition, order = "obyek_override = TRUE AND obyek IS NOT NULL", "obyek_override_at"
+	default:
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+
+	query := `SELECT COALESCE(keterangan, '') AS keterangan, net, koreksi, obyek, koreksi_override, obyek_override
+	          FROM transaction_data
+	          WHERE ` + condition + `
+	          ORDER BY ` + order + ` DESC
+	          LIMIT ?`
+
+	err := r.db.Select(&rows, query, limit)
+	return rows, err
+}
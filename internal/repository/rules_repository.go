@@ -2,6 +2,7 @@ package repository
 
 import (
 	"accounting-web/internal/models"
+	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -48,7 +49,7 @@ func (r *RulesRepository) GetKoreksiRules(limit, offset int, search string) ([]m
 	}
 
 	// Get paginated results
-	selectQuery += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	selectQuery += " ORDER BY priority DESC, id LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	err := r.db.Select(&rules, selectQuery, args...)
@@ -61,14 +62,14 @@ func (r *RulesRepository) GetKoreksiRules(limit, offset int, search string) ([]m
 
 func (r *RulesRepository) GetActiveKoreksiRules() ([]models.KoreksiRule, error) {
 	var rules []models.KoreksiRule
-	query := "SELECT * FROM koreksi_rules WHERE is_active = TRUE ORDER BY id DESC"
+	query := "SELECT * FROM koreksi_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
 	err := r.db.Select(&rules, query)
 	return rules, err
 }
 
 func (r *RulesRepository) CreateKoreksiRule(rule *models.KoreksiRule) error {
-	query := `INSERT INTO koreksi_rules (keyword, value, not_value, is_active)
-	          VALUES (:keyword, :value, :not_value, :is_active)`
+	query := `INSERT INTO koreksi_rules (keyword, value, not_value, conditions, priority, is_active)
+	          VALUES (:keyword, :value, :not_value, :conditions, :priority, :is_active)`
 	result, err := r.db.NamedExec(query, rule)
 	if err != nil {
 		return err
@@ -80,7 +81,7 @@ func (r *RulesRepository) CreateKoreksiRule(rule *models.KoreksiRule) error {
 
 func (r *RulesRepository) UpdateKoreksiRule(rule *models.KoreksiRule) error {
 	query := `UPDATE koreksi_rules SET keyword = :keyword, value = :value, not_value = :not_value,
-	          is_active = :is_active WHERE id = :id`
+	          conditions = :conditions, priority = :priority, is_active = :is_active WHERE id = :id`
 	_, err := r.db.NamedExec(query, rule)
 	return err
 }
@@ -112,7 +113,7 @@ func (r *RulesRepository) BulkInsertKoreksiRules(rules []models.KoreksiRule) err
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO koreksi_rules (keyword, value, not_value, is_active) VALUES (:keyword, :value, :not_value, :is_active)`
+	query := `INSERT INTO koreksi_rules (keyword, value, not_value, conditions, priority, is_active) VALUES (:keyword, :value, :not_value, :conditions, :priority, :is_active)`
 
 	for _, rule := range rules {
 		_, err := tx.NamedExec(query, rule)
@@ -126,11 +127,52 @@ func (r *RulesRepository) BulkInsertKoreksiRules(rules []models.KoreksiRule) err
 
 func (r *RulesRepository) GetAllActiveKoreksiRules() ([]models.KoreksiRule, error) {
 	var rules []models.KoreksiRule
-	query := "SELECT * FROM koreksi_rules WHERE is_active = TRUE ORDER BY id DESC"
+	query := "SELECT * FROM koreksi_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
+	err := r.db.Select(&rules, query)
+	return rules, err
+}
+
+// GetAllKoreksiRules returns every koreksi rule, active or not, in matching order
+func (r *RulesRepository) GetAllKoreksiRules() ([]models.KoreksiRule, error) {
+	var rules []models.KoreksiRule
+	query := "SELECT * FROM koreksi_rules ORDER BY priority DESC, id"
 	err := r.db.Select(&rules, query)
 	return rules, err
 }
 
+// ReorderKoreksiRules updates the priority of several rules in a single transaction
+func (r *RulesRepository) ReorderKoreksiRules(updates []models.RulePriorityUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE koreksi_rules SET priority = ? WHERE id = ?`
+
+	for _, update := range updates {
+		result, err := tx.Exec(query, update.Priority, update.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			var exists int
+			if err := tx.Get(&exists, "SELECT COUNT(*) FROM koreksi_rules WHERE id = ?", update.ID); err != nil {
+				return err
+			}
+			if exists == 0 {
+				return fmt.Errorf("koreksi rule %d not found", update.ID)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
 // Obyek Rules
 func (r *RulesRepository) GetObyekRules(limit, offset int, search string) ([]models.ObyekRule, int, error) {
 	var rules []models.ObyekRule
@@ -165,7 +207,7 @@ func (r *RulesRepository) GetObyekRules(limit, offset int, search string) ([]mod
 	}
 
 	// Get paginated results
-	selectQuery += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	selectQuery += " ORDER BY priority DESC, id LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	err := r.db.Select(&rules, selectQuery, args...)
@@ -178,14 +220,14 @@ func (r *RulesRepository) GetObyekRules(limit, offset int, search string) ([]mod
 
 func (r *RulesRepository) GetActiveObyekRules() ([]models.ObyekRule, error) {
 	var rules []models.ObyekRule
-	query := "SELECT * FROM obyek_rules WHERE is_active = TRUE ORDER BY id DESC"
+	query := "SELECT * FROM obyek_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
 	err := r.db.Select(&rules, query)
 	return rules, err
 }
 
 func (r *RulesRepository) CreateObyekRule(rule *models.ObyekRule) error {
-	query := `INSERT INTO obyek_rules (keyword, value, not_value, is_active)
-	          VALUES (:keyword, :value, :not_value, :is_active)`
+	query := `INSERT INTO obyek_rules (keyword, value, not_value, conditions, priority, is_active)
+	          VALUES (:keyword, :value, :not_value, :conditions, :priority, :is_active)`
 	result, err := r.db.NamedExec(query, rule)
 	if err != nil {
 		return err
@@ -197,7 +239,7 @@ func (r *RulesRepository) CreateObyekRule(rule *models.ObyekRule) error {
 
 func (r *RulesRepository) UpdateObyekRule(rule *models.ObyekRule) error {
 	query := `UPDATE obyek_rules SET keyword = :keyword, value = :value, not_value = :not_value,
-	          is_active = :is_active WHERE id = :id`
+	          conditions = :conditions, priority = :priority, is_active = :is_active WHERE id = :id`
 	_, err := r.db.NamedExec(query, rule)
 	return err
 }
@@ -229,7 +271,7 @@ func (r *RulesRepository) BulkInsertObyekRules(rules []models.ObyekRule) error {
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO obyek_rules (keyword, value, not_value, is_active) VALUES (:keyword, :value, :not_value, :is_active)`
+	query := `INSERT INTO obyek_rules (keyword, value, not_value, conditions, priority, is_active) VALUES (:keyword, :value, :not_value, :conditions, :priority, :is_active)`
 
 	for _, rule := range rules {
 		_, err := tx.NamedExec(query, rule)
@@ -243,11 +285,52 @@ func (r *RulesRepository) BulkInsertObyekRules(rules []models.ObyekRule) error {
 
 func (r *RulesRepository) GetAllActiveObyekRules() ([]models.ObyekRule, error) {
 	var rules []models.ObyekRule
-	query := "SELECT * FROM obyek_rules WHERE is_active = TRUE ORDER BY id DESC"
+	query := "SELECT * FROM obyek_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
+	err := r.db.Select(&rules, query)
+	return rules, err
+}
+
+// GetAllObyekRules returns every obyek rule, active or not, in matching order
+func (r *RulesRepository) GetAllObyekRules() ([]models.ObyekRule, error) {
+	var rules []models.ObyekRule
+	query := "SELECT * FROM obyek_rules ORDER BY priority DESC, id"
 	err := r.db.Select(&rules, query)
 	return rules, err
 }
 
+// ReorderObyekRules updates the priority of several rules in a single transaction
+func (r *RulesRepository) ReorderObyekRules(updates []models.RulePriorityUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE obyek_rules SET priority = ? WHERE id = ?`
+
+	for _, update := range updates {
+		result, err := tx.Exec(query, update.Priority, update.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			var exists int
+			if err := tx.Get(&exists, "SELECT COUNT(*) FROM obyek_rules WHERE id = ?", update.ID); err != nil {
+				return err
+			}
+			if exists == 0 {
+				return fmt.Errorf("obyek rule %d not found", update.ID)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
 // Withholding Tax Rules
 func (r *RulesRepository) GetWithholdingTaxRules(limit, offset int) ([]models.WithholdingTaxRule, int, error) {
 	var rules []models.WithholdingTaxRule
@@ -270,14 +353,16 @@ func (r *RulesRepository) GetWithholdingTaxRules(limit, offset int) ([]models.Wi
 
 func (r *RulesRepository) GetActiveWithholdingTaxRules() ([]models.WithholdingTaxRule, error) {
 	var rules []models.WithholdingTaxRule
-	query := "SELECT * FROM withholding_tax_rules WHERE is_active = TRUE ORDER BY priority DESC"
+	query := "SELECT * FROM withholding_tax_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
 	err := r.db.Select(&rules, query)
 	return rules, err
 }
 
 func (r *RulesRepository) CreateWithholdingTaxRule(rule *models.WithholdingTaxRule) error {
-	query := `INSERT INTO withholding_tax_rules (keyword, tax_type, tax_rate, priority, is_active)
-	          VALUES (:keyword, :tax_type, :tax_rate, :priority, :is_active)`
+	query := `INSERT INTO withholding_tax_rules (keyword, tax_type, tax_rate, calculation_mode, vat_rate,
+	          rounding, conditions, valid_from, valid_to, priority, is_active)
+	          VALUES (:keyword, :tax_type, :tax_rate, :calculation_mode, :vat_rate,
+	          :rounding, :conditions, :valid_from, :valid_to, :priority, :is_active)`
 	result, err := r.db.NamedExec(query, rule)
 	if err != nil {
 		return err
@@ -289,17 +374,65 @@ func (r *RulesRepository) CreateWithholdingTaxRule(rule *models.WithholdingTaxRu
 
 func (r *RulesRepository) UpdateWithholdingTaxRule(rule *models.WithholdingTaxRule) error {
 	query := `UPDATE withholding_tax_rules SET keyword = :keyword, tax_type = :tax_type,
-	          tax_rate = :tax_rate, priority = :priority, is_active = :is_active WHERE id = :id`
+	          tax_rate = :tax_rate, calculation_mode = :calculation_mode, vat_rate = :vat_rate,
+	          rounding = :rounding, conditions = :conditions, valid_from = :valid_from,
+	          valid_to = :valid_to, priority = :priority, is_active = :is_active WHERE id = :id`
 	_, err := r.db.NamedExec(query, rule)
 	return err
 }
 
+func (r *RulesRepository) GetWithholdingTaxRuleByID(id int) (*models.WithholdingTaxRule, error) {
+	var rule models.WithholdingTaxRule
+	query := "SELECT * FROM withholding_tax_rules WHERE id = ?"
+	err := r.db.Get(&rule, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetOverlappingWithholdingTaxRules returns the other active rules with the same keyword
+// and tax type whose validity window overlaps the given rule's
+func (r *RulesRepository) GetOverlappingWithholdingTaxRules(rule *models.WithholdingTaxRule) ([]models.WithholdingTaxRule, error) {
+	var rules []models.WithholdingTaxRule
+	query := `SELECT * FROM withholding_tax_rules
+	          WHERE is_active = TRUE AND id <> ? AND LOWER(keyword) = LOWER(?) AND tax_type = ?
+	          AND COALESCE(valid_from, '1000-01-01') <= COALESCE(?, '9999-12-31')
+	          AND COALESCE(valid_to, '9999-12-31') >= COALESCE(?, '1000-01-01')
+	          ORDER BY valid_from, id`
+	err := r.db.Select(&rules, query, rule.ID, rule.Keyword, rule.TaxType, rule.ValidTo, rule.ValidFrom)
+	return rules, err
+}
+
 func (r *RulesRepository) DeleteWithholdingTaxRule(id int) error {
 	query := "DELETE FROM withholding_tax_rules WHERE id = ?"
 	_, err := r.db.Exec(query, id)
 	return err
 }
 
+// WHT resolution policies
+func (r *RulesRepository) GetWHTResolutionPolicies() ([]models.WHTResolutionPolicy, error) {
+	var policies []models.WHTResolutionPolicy
+	query := "SELECT * FROM wht_resolution_policies ORDER BY tax_type"
+	err := r.db.Select(&policies, query)
+	return policies, err
+}
+
+// SaveWHTResolutionPolicy inserts the policy for its tax type, or replaces the existing one
+func (r *RulesRepository) SaveWHTResolutionPolicy(policy *models.WHTResolutionPolicy) error {
+	query := `INSERT INTO wht_resolution_policies (tax_type, policy, updated_by)
+	          VALUES (:tax_type, :policy, :updated_by)
+	          ON DUPLICATE KEY UPDATE policy = VALUES(policy), updated_by = VALUES(updated_by)`
+	_, err := r.db.NamedExec(query, policy)
+	return err
+}
+
+func (r *RulesRepository) DeleteWHTResolutionPolicy(taxType string) error {
+	query := "DELETE FROM wht_resolution_policies WHERE tax_type = ?"
+	_, err := r.db.Exec(query, taxType)
+	return err
+}
+
 // Tax Keywords
 func (r *RulesRepository) GetTaxKeywords(limit, offset int) ([]models.TaxKeyword, int, error) {
 	var keywords []models.TaxKeyword
@@ -322,14 +455,14 @@ func (r *RulesRepository) GetTaxKeywords(limit, offset int) ([]models.TaxKeyword
 
 func (r *RulesRepository) GetActiveTaxKeywords() ([]models.TaxKeyword, error) {
 	var keywords []models.TaxKeyword
-	query := "SELECT * FROM tax_keywords WHERE is_active = TRUE ORDER BY priority DESC"
+	query := "SELECT * FROM tax_keywords WHERE is_active = TRUE ORDER BY priority DESC, id"
 	err := r.db.Select(&keywords, query)
 	return keywords, err
 }
 
 func (r *RulesRepository) CreateTaxKeyword(keyword *models.TaxKeyword) error {
-	query := `INSERT INTO tax_keywords (keyword, tax_category, priority, is_active)
-	          VALUES (:keyword, :tax_category, :priority, :is_active)`
+	query := `INSERT INTO tax_keywords (keyword, tax_category, valid_from, valid_to, priority, is_active)
+	          VALUES (:keyword, :tax_category, :valid_from, :valid_to, :priority, :is_active)`
 	result, err := r.db.NamedExec(query, keyword)
 	if err != nil {
 		return err
@@ -341,13 +474,207 @@ func (r *RulesRepository) CreateTaxKeyword(keyword *models.TaxKeyword) error {
 
 func (r *RulesRepository) UpdateTaxKeyword(keyword *models.TaxKeyword) error {
 	query := `UPDATE tax_keywords SET keyword = :keyword, tax_category = :tax_category,
-	          priority = :priority, is_active = :is_active WHERE id = :id`
+	          valid_from = :valid_from, valid_to = :valid_to, priority = :priority, is_active = :is_active WHERE id = :id`
 	_, err := r.db.NamedExec(query, keyword)
 	return err
 }
 
+func (r *RulesRepository) GetTaxKeywordByID(id int) (*models.TaxKeyword, error) {
+	var keyword models.TaxKeyword
+	query := "SELECT * FROM tax_keywords WHERE id = ?"
+	err := r.db.Get(&keyword, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &keyword, nil
+}
+
+// GetOverlappingTaxKeywords returns the other active keywords with the same keyword and
+// category whose validity window overlaps the given keyword's
+func (r *RulesRepository) GetOverlappingTaxKeywords(keyword *models.TaxKeyword) ([]models.TaxKeyword, error) {
+	var keywords []models.TaxKeyword
+	query := `SELECT * FROM tax_keywords
+	          WHERE is_active = TRUE AND id <> ? AND LOWER(keyword) = LOWER(?) AND tax_category = ?
+	          AND COALESCE(valid_from, '1000-01-01') <= COALESCE(?, '9999-12-31')
+	          AND COALESCE(valid_to, '9999-12-31') >= COALESCE(?, '1000-01-01')
+	          ORDER BY valid_from, id`
+	err := r.db.Select(&keywords, query, keyword.ID, keyword.Keyword, keyword.TaxCategory, keyword.ValidTo, keyword.ValidFrom)
+	return keywords, err
+}
+
 func (r *RulesRepository) DeleteTaxKeyword(id int) error {
 	query := "DELETE FROM tax_keywords WHERE id = ?"
 	_, err := r.db.Exec(query, id)
 	return err
 }
+
+// Keyword Synonyms
+func (r *RulesRepository) GetKeywordSynonyms(limit, offset int, search string) ([]models.KeywordSynonym, int, error) {
+	var synonyms []models.KeywordSynonym
+	var total int
+
+	where := ""
+	var args []interface{}
+	if search != "" {
+		where = " WHERE term LIKE ? OR canonical LIKE ?"
+		args = append(args, "%"+search+"%", "%"+search+"%")
+	}
+
+	err := r.db.Get(&total, "SELECT COUNT(*) FROM keyword_synonyms"+where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT * FROM keyword_synonyms" + where + " ORDER BY canonical, term LIMIT ? OFFSET ?"
+	err = r.db.Select(&synonyms, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return synonyms, total, nil
+}
+
+func (r *RulesRepository) GetActiveKeywordSynonyms() ([]models.KeywordSynonym, error) {
+	var synonyms []models.KeywordSynonym
+	query := "SELECT * FROM keyword_synonyms WHERE is_active = TRUE ORDER BY id"
+	err := r.db.Select(&synonyms, query)
+	return synonyms, err
+}
+
+func (r *RulesRepository) CreateKeywordSynonym(synonym *models.KeywordSynonym) error {
+	query := `INSERT INTO keyword_synonyms (term, canonical, is_active)
+	          VALUES (:term, :canonical, :is_active)`
+	result, err := r.db.NamedExec(query, synonym)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	synonym.ID = int(id)
+	return nil
+}
+
+func (r *RulesRepository) UpdateKeywordSynonym(synonym *models.KeywordSynonym) error {
+	query := `UPDATE keyword_synonyms SET term = :term, canonical = :canonical, is_active = :is_active
+	          WHERE id = :id`
+	_, err := r.db.NamedExec(query, synonym)
+	return err
+}
+
+func (r *RulesRepository) GetKeywordSynonymByID(id int) (*models.KeywordSynonym, error) {
+	var synonym models.KeywordSynonym
+	query := "SELECT * FROM keyword_synonyms WHERE id = ?"
+	err := r.db.Get(&synonym, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &synonym, nil
+}
+
+func (r *RulesRepository) DeleteKeywordSynonym(id int) error {
+	query := "DELETE FROM keyword_synonyms WHERE id = ?"
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// UM Pajak Rules
+func (r *RulesRepository) GetUmPajakRules(limit, offset int, search string) ([]models.UmPajakRule, int, error) {
+	var rules []models.UmPajakRule
+	var total int
+
+	// Build base queries
+	countQuery := "SELECT COUNT(*) FROM um_pajak_rules"
+	selectQuery := "SELECT * FROM um_pajak_rules"
+
+	// Add search condition if provided
+	args := []interface{}{}
+	if search != "" {
+		whereClause := " WHERE keyword LIKE ? OR account_pattern LIKE ? OR tax_type LIKE ?"
+		searchParam := "%" + search + "%"
+		args = append(args, searchParam, searchParam, searchParam)
+		countQuery += whereClause
+		selectQuery += whereClause
+	}
+
+	// Get total count
+	if err := r.db.Get(&total, countQuery, args...); err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	selectQuery += " ORDER BY priority DESC, id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	err := r.db.Select(&rules, selectQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rules, total, nil
+}
+
+func (r *RulesRepository) GetActiveUmPajakRules() ([]models.UmPajakRule, error) {
+	var rules []models.UmPajakRule
+	query := "SELECT * FROM um_pajak_rules WHERE is_active = TRUE ORDER BY priority DESC, id"
+	err := r.db.Select(&rules, query)
+	return rules, err
+}
+
+func (r *RulesRepository) CreateUmPajakRule(rule *models.UmPajakRule) error {
+	query := `INSERT INTO um_pajak_rules (keyword, account_pattern, debit_condition, tax_type, priority, is_active)
+	          VALUES (:keyword, :account_pattern, :debit_condition, :tax_type, :priority, :is_active)`
+	result, err := r.db.NamedExec(query, rule)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	rule.ID = int(id)
+	return nil
+}
+
+func (r *RulesRepository) UpdateUmPajakRule(rule *models.UmPajakRule) error {
+	query := `UPDATE um_pajak_rules SET keyword = :keyword, account_pattern = :account_pattern,
+	          debit_condition = :debit_condition, tax_type = :tax_type, priority = :priority,
+	          is_active = :is_active WHERE id = :id`
+	_, err := r.db.NamedExec(query, rule)
+	return err
+}
+
+func (r *RulesRepository) GetUmPajakRuleByID(id int) (*models.UmPajakRule, error) {
+	var rule models.UmPajakRule
+	query := "SELECT * FROM um_pajak_rules WHERE id = ?"
+	err := r.db.Get(&rule, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *RulesRepository) DeleteUmPajakRule(id int) error {
+	query := "DELETE FROM um_pajak_rules WHERE id = ?"
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *RulesRepository) BulkInsertUmPajakRules(rules []models.UmPajakRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO um_pajak_rules (keyword, account_pattern, debit_condition, tax_type, priority, is_active)
+	          VALUES (:keyword, :account_pattern, :debit_condition, :tax_type, :priority, :is_active)`
+
+	for _, rule := range rules {
+		_, err := tx.NamedExec(query, rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
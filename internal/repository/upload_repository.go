@@ -301,6 +301,15 @@ func (r *UploadRepository) UpdateSessionStatus(id int, status string) error {
 	return err
 }
 
+// UpdateSessionUpload stores the filename, row count and status of a session once
+// its files have been parsed
+func (r *UploadRepository) UpdateSessionUpload(session *models.UploadSession) error {
+	query := `UPDATE upload_sessions SET filename = :filename, total_rows = :total_rows, status = :status
+	          WHERE id = :id`
+	_, err := r.db.NamedExec(query, session)
+	return err
+}
+
 // Transaction Data - Optimized for session_code only
 func (r *UploadRepository) CreateMultipleTransactions(transactions []models.TransactionData) error {
 	if len(transactions) == 0 {
@@ -491,6 +500,14 @@ func (r *UploadRepository) UpdateBackgroundJobProgress(jobID int, processedRows
 	return err
 }
 
+// UpdateBackgroundJobTotalRows replaces the estimated row count of a job with the
+// number of rows actually parsed
+func (r *UploadRepository) UpdateBackgroundJobTotalRows(jobID int, totalRows int) error {
+	query := `UPDATE background_jobs SET total_rows = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.Exec(query, totalRows, jobID)
+	return err
+}
+
 // GetBackgroundJobBySessionCode retrieves background job by session code
 func (r *UploadRepository) GetBackgroundJobBySessionCode(sessionCode string) (*models.BackgroundJob, error) {
 	var job models.BackgroundJob
@@ -523,10 +540,25 @@ func (r *UploadRepository) GetTransactionsBySession(sessionID int, limit, offset
 
 // GetTransactionsBySessionCode retrieves transactions using session_code with JOIN to accounts
 func (r *UploadRepository) GetTransactionsBySessionCode(sessionCode string, limit, offset int) ([]models.TransactionData, int, error) {
+	return r.getTransactionsBySessionCode(sessionCode, false, limit, offset)
+}
+
+// GetWarningTransactionsBySessionCode retrieves only the transactions of a session that
+// have a processing warning, e.g. conflicting WHT rules
+func (r *UploadRepository) GetWarningTransactionsBySessionCode(sessionCode string, limit, offset int) ([]models.TransactionData, int, error) {
+	return r.getTransactionsBySessionCode(sessionCode, true, limit, offset)
+}
+
+func (r *UploadRepository) getTransactionsBySessionCode(sessionCode string, warningsOnly bool, limit, offset int) ([]models.TransactionData, int, error) {
 	var transactions []models.TransactionData
 	var total int
 
 	countQuery := "SELECT COUNT(*) FROM transaction_data WHERE session_code = ?"
+	whereClause := "WHERE td.session_code = ?"
+	if warningsOnly {
+		countQuery += " AND processing_warning IS NOT NULL"
+		whereClause += " AND td.processing_warning IS NOT NULL"
+	}
 	err := r.db.Get(&total, countQuery, sessionCode)
 	if err != nil {
 		return nil, 0, err
@@ -565,6 +597,20 @@ func (r *UploadRepository) GetTransactionsBySessionCode(sessionCode string, limi
 				td.analisa_tambahan,
 				td.is_processed,
 				td.processing_error,
+				td.processing_notes,
+				td.processing_warning,
+				td.rule_provenance,
+				td.koreksi_override,
+				td.koreksi_override_by,
+				td.koreksi_override_at,
+				td.obyek_override,
+				td.obyek_override_by,
+				td.obyek_override_at,
+				td.suggested_koreksi,
+				td.suggested_koreksi_confidence,
+				td.suggested_obyek,
+				td.suggested_obyek_confidence,
+				td.suggestion_model_version,
 				td.created_at,
 				td.updated_at,
 				accounts.nature as nature_akun,
@@ -613,7 +659,7 @@ func (r *UploadRepository) GetTransactionsBySessionCode(sessionCode string, limi
 				) as pm_db_account
 			  FROM transaction_data td
 			  LEFT JOIN accounts ON td.account = accounts.account_code
-			  WHERE td.session_code = ?
+			  ` + whereClause + `
 			  ORDER BY td.id
 			  LIMIT ? OFFSET ?`
 
@@ -634,6 +680,15 @@ func (r *UploadRepository) GetUnprocessedTransactionsBySessionCode(sessionCode s
 	return transactions, err
 }
 
+// GetTransactionsBySessionCodeAfterID pages through all transactions of a session in id order
+func (r *UploadRepository) GetTransactionsBySessionCodeAfterID(sessionCode string, afterID int64, limit int) ([]models.TransactionData, error) {
+	var transactions []models.TransactionData
+	query := `SELECT * FROM transaction_data WHERE session_code = ? AND id > ?
+	          ORDER BY id LIMIT ?`
+	err := r.db.Select(&transactions, query, sessionCode, afterID, limit)
+	return transactions, err
+}
+
 func (r *UploadRepository) GetUnprocessedTransactions(sessionID int, limit int) ([]models.TransactionData, error) {
 	var transactions []models.TransactionData
 	query := `SELECT * FROM transaction_data WHERE session_id = ? AND is_processed = FALSE
@@ -658,93 +713,117 @@ func (r *UploadRepository) UpdateTransaction(transaction *models.TransactionData
 	          pk_cr = :pk_cr,
 	          analisa_tambahan = :analisa_tambahan,
 	          is_processed = :is_processed,
-	          processing_error = :processing_error
+	          processing_error = :processing_error,
+	          processing_notes = :processing_notes,
+	          processing_warning = :processing_warning,
+	          rule_provenance = :rule_provenance,
+	          suggested_koreksi = :suggested_koreksi,
+	          suggested_koreksi_confidence = :suggested_koreksi_confidence,
+	          suggested_obyek = :suggested_obyek,
+	          suggested_obyek_confidence = :suggested_obyek_confidence,
+	          suggestion_model_version = :suggestion_model_version
 	          WHERE id = :id`
 	_, err := r.db.NamedExec(query, transaction)
 	return err
 }
 
+// processedOutputColumns are the columns BulkUpdateTransactions writes, in the order
+// of processedOutputValues
+var processedOutputColumns = []string{
+	"analisa_nature_akun", "analisa_koreksi_obyek", "koreksi", "obyek",
+	"um_pajak_db", "pm_db", "wth_21_cr", "wth_23_cr", "wth_26_cr", "wth_4_2_cr", "wth_15_cr", "pk_cr",
+	"analisa_tambahan", "is_processed", "processing_error", "processing_notes", "processing_warning",
+	"rule_provenance", "suggested_koreksi", "suggested_koreksi_confidence", "suggested_obyek",
+	"suggested_obyek_confidence", "suggestion_model_version",
+}
+
+// bulkUpdateChunkSize keeps a multi-row INSERT of the staging table under the MySQL
+// placeholder limit (65535): 24 placeholders per row
+const bulkUpdateChunkSize = 2000
+
+// processedOutputValues returns the values of processedOutputColumns for a row, with
+// empty amounts as NULL
+func processedOutputValues(tx *models.TransactionData) []interface{} {
+	amount := func(n models.NullableNumericFloat64) interface{} {
+		if !n.Valid {
+			return nil
+		}
+		return n.Value
+	}
+	return []interface{}{
+		tx.AnalisaNatureAkun, tx.AnalisaKoreksiObyek, tx.Koreksi, tx.Obyek,
+		amount(tx.UmPajakDB), amount(tx.PmDB), amount(tx.Wth21Cr), amount(tx.Wth23Cr),
+		amount(tx.Wth26Cr), amount(tx.Wth42Cr), amount(tx.Wth15Cr), amount(tx.PkCr),
+		tx.AnalisaTambahan, tx.IsProcessed, tx.ProcessingError, tx.ProcessingNotes, tx.ProcessingWarning,
+		tx.RuleProvenance, tx.SuggestedKoreksi, tx.SuggestedKoreksiConfidence, tx.SuggestedObyek,
+		tx.SuggestedObyekConfidence, tx.SuggestionModelVersion,
+	}
+}
+
+// BulkUpdateTransactions stores the processing results of a batch in one database
+// transaction: the rows are written to a temporary staging table with multi-row
+// INSERTs and copied into transaction_data with a single UPDATE ... JOIN. Rows deleted
+// in the meantime are skipped rather than inserted again.
 func (r *UploadRepository) BulkUpdateTransactions(transactions []models.TransactionData) error {
 	if len(transactions) == 0 {
 		return nil
 	}
 
-	// Update each transaction individually to handle NullableNumericFloat64 properly
-	for _, tx := range transactions {
-		query := `UPDATE transaction_data SET
-		          analisa_nature_akun = ?,
-		          analisa_koreksi_obyek = ?,
-		          koreksi = ?,
-		          obyek = ?,
-		          um_pajak_db = ?,
-		          pm_db = ?,
-		          wth_21_cr = ?,
-		          wth_23_cr = ?,
-		          wth_26_cr = ?,
-		          wth_4_2_cr = ?,
-		          wth_15_cr = ?,
-		          pk_cr = ?,
-		          analisa_tambahan = ?,
-		          is_processed = ?,
-		          processing_error = ?
-		          WHERE id = ?`
-
-		// Convert NullableNumericFloat64 to proper SQL types
-		var umPajakDB, pmDB, wth21Cr, wth23Cr, wth26Cr, wth42Cr, wth15Cr, pkCr interface{}
-
-		if tx.UmPajakDB.Valid {
-			umPajakDB = tx.UmPajakDB.Value
-		}
-		if tx.PmDB.Valid {
-			pmDB = tx.PmDB.Value
-		}
-		if tx.Wth21Cr.Valid {
-			wth21Cr = tx.Wth21Cr.Value
-		}
-		if tx.Wth23Cr.Valid {
-			wth23Cr = tx.Wth23Cr.Value
-		}
-		if tx.Wth26Cr.Valid {
-			wth26Cr = tx.Wth26Cr.Value
-		}
-		if tx.Wth42Cr.Valid {
-			wth42Cr = tx.Wth42Cr.Value
-		}
-		if tx.Wth15Cr.Valid {
-			wth15Cr = tx.Wth15Cr.Value
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The staging table lives on the connection of the transaction; a copy left behind
+	// by a failed batch on the same pooled connection is dropped first
+	columns := strings.Join(processedOutputColumns, ", ")
+	if _, err := tx.Exec("DROP TEMPORARY TABLE IF EXISTS transaction_results"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("CREATE TEMPORARY TABLE transaction_results (PRIMARY KEY (id)) SELECT id, " + columns + " FROM transaction_data LIMIT 0"); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	rowPlaceholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(processedOutputColumns)+1), ", ") + ")"
+	for i := 0; i < len(transactions); i += bulkUpdateChunkSize {
+		end := i + bulkUpdateChunkSize
+		if end > len(transactions) {
+			end = len(transactions)
 		}
-		if tx.PkCr.Valid {
-			pkCr = tx.PkCr.Value
+
+		placeholders := make([]string, 0, end-i)
+		args := make([]interface{}, 0, (end-i)*(len(processedOutputColumns)+1))
+		for j := i; j < end; j++ {
+			placeholders = append(placeholders, rowPlaceholders)
+			args = append(args, transactions[j].ID)
+			args = append(args, processedOutputValues(&transactions[j])...)
 		}
 
-		_, err := r.db.Exec(query,
-			tx.AnalisaNatureAkun,
-			tx.AnalisaKoreksiObyek,
-			tx.Koreksi,
-			tx.Obyek,
-			umPajakDB,
-			pmDB,
-			wth21Cr,
-			wth23Cr,
-			wth26Cr,
-			wth42Cr,
-			wth15Cr,
-			pkCr,
-			tx.AnalisaTambahan,
-			tx.IsProcessed,
-			tx.ProcessingError,
-			tx.ID,
-		)
-		if err != nil {
-			return err
+		query := "INSERT INTO transaction_results (id, " + columns + ") VALUES " + strings.Join(placeholders, ", ")
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to stage rows %d-%d: %w", i+1, end, err)
 		}
 	}
 
-	return nil
+	assignments := make([]string, len(processedOutputColumns))
+	for i, column := range processedOutputColumns {
+		assignments[i] = "t." + column + " = s." + column
+	}
+	query := "UPDATE transaction_data t JOIN transaction_results s ON s.id = t.id SET " + strings.Join(assignments, ", ")
+	if _, err := tx.Exec(query); err != nil {
+		return fmt.Errorf("failed to update transactions from staging table: %w", err)
+	}
+
+	if _, err := tx.Exec("DROP TEMPORARY TABLE transaction_results"); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // UpdateTransactionKoreksiObyek updates koreksi and obyek fields of a transaction
-func (r *UploadRepository) UpdateTransactionKoreksiObyek(transactionID int64, koreksi, obyek *string, userID int, userRole string) error {
+// together with analisa_koreksi_obyek, which the caller combines from the new values
+func (r *UploadRepository) UpdateTransactionKoreksiObyek(transactionID int64, koreksi, obyek, analisaKoreksiObyek *string, userID int, userRole string) error {
 	// First check if user has permission to update this transaction
 	if userRole != "admin" {
 		// For non-admin users, check if they own this transaction
@@ -759,43 +838,230 @@ func (r *UploadRepository) UpdateTransactionKoreksiObyek(transactionID int64, ko
 		}
 	}
 
-	// Update koreksi and obyek fields
-	query := `
-		UPDATE transaction_data
-		SET
-			koreksi = ?,
-			obyek = ?,
-			analisa_koreksi_obyek = CASE
-				WHEN ? IS NOT NULL AND ? IS NOT NULL THEN CONCAT(?, ' - ', ?)
-				WHEN ? IS NOT NULL AND ? IS NULL THEN ?
-				WHEN ? IS NULL AND ? IS NOT NULL THEN ?
-				ELSE NULL
-			END,
-			updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`
+	// Only the fields present in the request are changed. They are flagged as manual
+	// overrides so reprocessing keeps them, and their rule provenance is dropped.
+	// An empty string clears the field.
+	var setClauses []string
+	var args []interface{}
 
-	var koreksiValue, obyekValue interface{}
-	if koreksi != nil && *koreksi != "" {
-		koreksiValue = *koreksi
+	if koreksi != nil {
+		var koreksiValue interface{}
+		if *koreksi != "" {
+			koreksiValue = *koreksi
+		}
+		setClauses = append(setClauses, "koreksi = ?", "koreksi_override = TRUE", "koreksi_override_by = ?", "koreksi_override_at = CURRENT_TIMESTAMP")
+		args = append(args, koreksiValue, userID)
+	}
+	if obyek != nil {
+		var obyekValue interface{}
+		if *obyek != "" {
+			obyekValue = *obyek
+		}
+		setClauses = append(setClauses, "obyek = ?", "obyek_override = TRUE", "obyek_override_by = ?", "obyek_override_at = CURRENT_TIMESTAMP")
+		args = append(args, obyekValue, userID)
 	}
-	if obyek != nil && *obyek != "" {
-		obyekValue = *obyek
+	if len(setClauses) == 0 {
+		return fmt.Errorf("no fields to update")
 	}
 
-	_, err := r.db.Exec(query,
-		koreksiValue, obyekValue, // 1-2: SET koreksi = ?, obyek = ?
-		koreksiValue, obyekValue, koreksiValue, obyekValue, // 3-6: WHEN both not null THEN CONCAT
-		koreksiValue, obyekValue, // 7-8: WHEN koreksi not null and obyek null
-		koreksiValue,             // 9: THEN koreksi
-		koreksiValue, obyekValue, // 10-11: WHEN koreksi null and obyek not null
-		obyekValue,    // 12: THEN obyek
-		transactionID, // 13: WHERE id = ?
-	)
+	setClauses = append(setClauses, "analisa_koreksi_obyek = ?")
+	args = append(args, analisaKoreksiObyek)
+
+	var removedPaths []string
+	if koreksi != nil {
+		removedPaths = append(removedPaths, "'$.koreksi'")
+	}
+	if obyek != nil {
+		removedPaths = append(removedPaths, "'$.obyek'")
+	}
+	setClauses = append(setClauses,
+		fmt.Sprintf("rule_provenance = JSON_REMOVE(COALESCE(rule_provenance, '{}'), %s)", strings.Join(removedPaths, ", ")),
+		"updated_at = CURRENT_TIMESTAMP")
 
+	query := fmt.Sprintf("UPDATE transaction_data SET %s WHERE id = ?", strings.Join(setClauses, ", "))
+	args = append(args, transactionID)
+
+	_, err := r.db.Exec(query, args...)
+	return err
+}
+
+// GetTransactionByID returns a single transaction row
+func (r *UploadRepository) GetTransactionByID(id int64) (*models.TransactionData, error) {
+	var transaction models.TransactionData
+	query := "SELECT * FROM transaction_data WHERE id = ?"
+	err := r.db.Get(&transaction, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// UpdateTransactionOverrides stores the koreksi/obyek values together with their
+// override flags, e.g. after a field was reset to its rule-derived value
+func (r *UploadRepository) UpdateTransactionOverrides(transaction *models.TransactionData) error {
+	query := `UPDATE transaction_data SET
+	          koreksi = :koreksi,
+	          obyek = :obyek,
+	          analisa_koreksi_obyek = :analisa_koreksi_obyek,
+	          rule_provenance = :rule_provenance,
+	          koreksi_override = :koreksi_override,
+	          koreksi_override_by = :koreksi_override_by,
+	          koreksi_override_at = :koreksi_override_at,
+	          obyek_override = :obyek_override,
+	          obyek_override_by = :obyek_override_by,
+	          obyek_override_at = :obyek_override_at,
+	          updated_at = CURRENT_TIMESTAMP
+	          WHERE id = :id`
+	_, err := r.db.NamedExec(query, transaction)
 	return err
 }
 
+// ResetSessionOutputs clears the rule-derived outputs of a session so the rows are
+// processed again; the worker calls it when a reprocess run starts. Manually overridden koreksi/obyek values are kept.
+// The filter limits which rows are reset. When
+// snapshotRunID is set, the current values are first copied into
+// processing_run_snapshots so the run can report a before/after summary.
+func (r *UploadRepository) ResetSessionOutputs(sessionCode string, filter *models.ReprocessFilter, snapshotRunID int) (int64, error) {
+	whereClause, args := buildReprocessFilter(sessionCode, filter)
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if snapshotRunID > 0 {
+		snapshotQuery := `INSERT INTO processing_run_snapshots (run_id, transaction_id, analisa_nature_akun,
+		          analisa_koreksi_obyek, koreksi, obyek, um_pajak_db, pm_db, wth_21_cr, wth_23_cr, wth_26_cr,
+		          wth_4_2_cr, wth_15_cr, pk_cr, analisa_tambahan)
+		          SELECT ?, td.id, td.analisa_nature_akun, td.analisa_koreksi_obyek, td.koreksi, td.obyek,
+		          td.um_pajak_db, td.pm_db, td.wth_21_cr, td.wth_23_cr, td.wth_26_cr, td.wth_4_2_cr,
+		          td.wth_15_cr, td.pk_cr, td.analisa_tambahan
+		          FROM transaction_data td ` + whereClause
+		snapshotArgs := append([]interface{}{snapshotRunID}, args...)
+		if _, err := tx.Exec(snapshotQuery, snapshotArgs...); err != nil {
+			return 0, fmt.Errorf("failed to snapshot current results: %w", err)
+		}
+	}
+
+	query := `UPDATE transaction_data td SET
+	          td.analisa_nature_akun = NULL,
+	          td.analisa_koreksi_obyek = NULL,
+	          td.koreksi = CASE WHEN td.koreksi_override THEN td.koreksi ELSE NULL END,
+	          td.obyek = CASE WHEN td.obyek_override THEN td.obyek ELSE NULL END,
+	          td.um_pajak_db = NULL,
+	          td.pm_db = NULL,
+	          td.wth_21_cr = NULL,
+	          td.wth_23_cr = NULL,
+	          td.wth_26_cr = NULL,
+	          td.wth_4_2_cr = NULL,
+	          td.wth_15_cr = NULL,
+	          td.pk_cr = NULL,
+	          td.analisa_tambahan = NULL,
+	          td.is_processed = FALSE,
+	          td.processing_error = NULL,
+	          td.processing_notes = NULL,
+	          td.processing_warning = NULL,
+	          td.rule_provenance = NULL,
+	          td.suggested_koreksi = NULL,
+	          td.suggested_koreksi_confidence = NULL,
+	          td.suggested_obyek = NULL,
+	          td.suggested_obyek_confidence = NULL,
+	          td.suggestion_model_version = NULL ` + whereClause
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, tx.Commit()
+}
+
+// buildReprocessFilter builds the WHERE clause selecting the rows of a session to reprocess
+func buildReprocessFilter(sessionCode string, filter *models.ReprocessFilter) (string, []interface{}) {
+	conditions := []string{"td.session_code = ?"}
+	args := []interface{}{sessionCode}
+
+	if filter != nil {
+		if len(filter.TransactionIDs) > 0 {
+			placeholders := make([]string, len(filter.TransactionIDs))
+			for i, id := range filter.TransactionIDs {
+				placeholders[i] = "?"
+				args = append(args, id)
+			}
+			conditions = append(conditions, fmt.Sprintf("td.id IN (%s)", strings.Join(placeholders, ", ")))
+		}
+		if filter.Account != "" {
+			conditions = append(conditions, "td.account = ?")
+			args = append(args, filter.Account)
+		}
+		if filter.AccountPrefix != "" {
+			conditions = append(conditions, "td.account LIKE ?")
+			args = append(args, escapeLike(filter.AccountPrefix)+"%")
+		}
+		if filter.DocumentType != "" {
+			conditions = append(conditions, "td.document_type = ?")
+			args = append(args, filter.DocumentType)
+		}
+		if filter.KeteranganContains != "" {
+			conditions = append(conditions, "td.keterangan LIKE ?")
+			args = append(args, "%"+escapeLike(filter.KeteranganContains)+"%")
+		}
+		if filter.Koreksi != "" {
+			conditions = append(conditions, "td.koreksi = ?")
+			args = append(args, filter.Koreksi)
+		}
+		if filter.Obyek != "" {
+			conditions = append(conditions, "td.obyek = ?")
+			args = append(args, filter.Obyek)
+		}
+		if filter.OnlyUnclassified {
+			conditions = append(conditions, "(td.koreksi IS NULL OR td.koreksi = '') AND (td.obyek IS NULL OR td.obyek = '')")
+		}
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the LIKE wildcards of user input; backslash is MySQL's
+// default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes s match itself literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// CountReprocessRows counts the rows of a session a reprocess with filter resets
+func (r *UploadRepository) CountReprocessRows(sessionCode string, filter *models.ReprocessFilter) (int64, error) {
+	whereClause, args := buildReprocessFilter(sessionCode, filter)
+
+	var count int64
+	query := "SELECT COUNT(*) FROM transaction_data td " + whereClause
+	err := r.db.Get(&count, query, args...)
+	return count, err
+}
+
+// StartSessionReprocess moves a session to processing for a reprocess, with
+// processedRows rows outside the reprocess counted as done. It returns false when the
+// session is already processing, so two requests cannot both start a run.
+func (r *UploadRepository) StartSessionReprocess(id, processedRows int) (bool, error) {
+	query := `UPDATE upload_sessions SET status = 'processing', processed_rows = ?, failed_rows = 0,
+	          error_message = NULL WHERE id = ? AND status <> 'processing'`
+	result, err := r.db.Exec(query, processedRows, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
 // Delete operations
 func (r *UploadRepository) DeleteSession(id int) error {
 	query := "DELETE FROM upload_sessions WHERE id = ?"
@@ -951,6 +1217,7 @@ func (r *UploadRepository) GetSessionsWithCursor(params utils.PaginationParams,
 }
 
 // GetTransactionsBySessionCodeWithCursor - Cursor-based pagination for transactions
+// filter_warning=true (params.Filters["warning"]) limits it to rows with a processing warning
 func (r *UploadRepository) GetTransactionsBySessionCodeWithCursor(
 	sessionCode string,
 	params utils.PaginationParams,
@@ -966,7 +1233,11 @@ func (r *UploadRepository) GetTransactionsBySessionCodeWithCursor(
 	limit := utils.ValidatePaginationLimit(params.Limit, maxRecords)
 
 	// Get total count - no max limit for session detail to show all records
+	warningsOnly := params.Filters["warning"] == "true"
 	countQuery := "SELECT COUNT(*) FROM transaction_data WHERE session_code = ?"
+	if warningsOnly {
+		countQuery += " AND processing_warning IS NOT NULL"
+	}
 	err := r.db.Get(&total, countQuery, sessionCode)
 	if err != nil {
 		return nil, utils.PaginationMeta{}, err
@@ -979,6 +1250,9 @@ func (r *UploadRepository) GetTransactionsBySessionCodeWithCursor(
 	var cursor *utils.Cursor
 	whereClause := "WHERE td.session_code = ?"
 	args := []interface{}{sessionCode}
+	if warningsOnly {
+		whereClause += " AND td.processing_warning IS NOT NULL"
+	}
 
 	if params.Cursor != "" && params.Mode == "cursor" {
 		var err error
@@ -1047,6 +1321,20 @@ func (r *UploadRepository) GetTransactionsBySessionCodeWithCursor(
 			td.analisa_tambahan,
 			td.is_processed,
 			td.processing_error,
+			td.processing_notes,
+			td.processing_warning,
+			td.rule_provenance,
+			td.koreksi_override,
+			td.koreksi_override_by,
+			td.koreksi_override_at,
+			td.obyek_override,
+			td.obyek_override_by,
+			td.obyek_override_at,
+			td.suggested_koreksi,
+			td.suggested_koreksi_confidence,
+			td.suggested_obyek,
+			td.suggested_obyek_confidence,
+			td.suggestion_model_version,
 			td.created_at,
 			td.updated_at,
 			accounts.nature as nature_akun,
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"accounting-web/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestBuildReprocessFilterEscapesLike(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   *models.ReprocessFilter
+		wantArgs []interface{}
+	}{
+		{"no filter", nil, []interface{}{"S1"}},
+		{"plain prefix", &models.ReprocessFilter{AccountPrefix: "61"}, []interface{}{"S1", "61%"}},
+		{"prefix wildcards", &models.ReprocessFilter{AccountPrefix: "6_%"}, []interface{}{"S1", `6\_\%%`}},
+		{"keterangan wildcards", &models.ReprocessFilter{KeteranganContains: `50% c:\tmp`}, []interface{}{"S1", `%50\% c:\\tmp%`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, args := buildReprocessFilter("S1", tt.filter)
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("buildReprocessFilter() args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"accounting-web/internal/repository"
 	"accounting-web/internal/service"
 	"accounting-web/internal/utils"
+	"context"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/hibiken/asynq"
@@ -26,30 +27,65 @@ func SetupAPIRoutes(
 	uploadRepo := repository.NewUploadRepository(db)
 	rulesRepo := repository.NewRulesRepository(db)
 	additionalAnalysisRepo := repository.NewAdditionalAnalysisRepository(db)
+	ruleSetRepo := repository.NewRuleSetRepository(db)
+	ruleStatsRepo := repository.NewRuleStatsRepository(db)
+	classifierRepo := repository.NewClassifierRepository(db)
+	processingStepRepo := repository.NewProcessingStepRepository(db)
+
+	// Cached rules reload when a rule or account change bumps the version in Redis
+	ruleCache := service.NewRuleCache("web", redis, accountRepo, rulesRepo, additionalAnalysisRepo)
+	ruleCache.Start(context.Background())
+	ruleVersionNotifier := service.NewRuleVersionNotifier(redis, ruleCache)
+	bumpRuleVersion := middleware.BumpRuleVersion(ruleVersionNotifier)
 
 	// Initialize services
 	authService := service.NewAuthService(userRepo, cfg)
 	excelService := service.NewExcelService()
 	additionalAnalysisService := service.NewAdditionalAnalysisService(additionalAnalysisRepo, accountRepo, utils.GetLogger())
+	ruleSimulationService := service.NewRuleSimulationService(accountRepo, rulesRepo, additionalAnalysisRepo, uploadRepo, processingStepRepo, ruleCache)
+	ruleSetService := service.NewRuleSetService(accountRepo, rulesRepo, additionalAnalysisRepo, ruleSetRepo, ruleCache)
+	transactionOverrideService := service.NewTransactionOverrideService(accountRepo, rulesRepo, additionalAnalysisRepo, uploadRepo, processingStepRepo, ruleCache)
+	ruleAnalyzer := service.NewRuleAnalyzer(rulesRepo)
+	ruleSuggestionService := service.NewRuleSuggestionService(ruleStatsRepo, rulesRepo)
 
 	// Initialize Asynq client (optional - only if Redis is available)
 	var asynqClient *asynq.Client
+	var jobProgressService *service.JobProgressService
+	var sessionEventHub *service.SessionEventHub
 	if redis != nil {
-		asynqClient = asynq.NewClient(asynq.RedisClientOpt{
+		asynqRedis := asynq.RedisClientOpt{
 			Addr:     cfg.AsynqRedisAddr,
 			Password: cfg.AsynqRedisPassword,
 			DB:       cfg.AsynqRedisDB,
-		})
+		}
+		asynqClient = asynq.NewClient(asynqRedis)
+		jobProgressService = service.NewJobProgressService(asynq.NewInspector(asynqRedis), redis, uploadRepo)
+		sessionEventHub = service.NewSessionEventHub(redis)
+		sessionEventHub.Start(context.Background())
 	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	accountHandler := handler.NewAccountHandler(accountRepo)
-	uploadHandler := handler.NewUploadHandler(uploadRepo, excelService, asynqClient, cfg)
+	uploadHandler := handler.NewUploadHandler(uploadRepo, excelService, transactionOverrideService, asynqClient, cfg)
 	koreksiRuleHandler := handler.NewKoreksiRuleHandler(rulesRepo)
 	obyekRuleHandler := handler.NewObyekRuleHandler(rulesRepo)
-	ruleHandler := handler.NewGenericRuleHandler()
+	umPajakRuleHandler := handler.NewUmPajakRuleHandler(rulesRepo)
+	withholdingTaxRuleHandler := handler.NewWithholdingTaxRuleHandler(rulesRepo)
+	taxKeywordHandler := handler.NewTaxKeywordHandler(rulesRepo)
+	keywordSynonymHandler := handler.NewKeywordSynonymHandler(rulesRepo)
 	additionalAnalysisHandler := handler.NewAdditionalAnalysisHandler(additionalAnalysisService)
+	ruleSimulationHandler := handler.NewRuleSimulationHandler(uploadRepo, ruleSimulationService)
+	ruleSetHandler := handler.NewRuleSetHandler(uploadRepo, ruleSetRepo, ruleSetService, asynqClient)
+	transactionOverrideHandler := handler.NewTransactionOverrideHandler(transactionOverrideService)
+	ruleAnalysisHandler := handler.NewRuleAnalysisHandler(ruleAnalyzer)
+	ruleStatsHandler := handler.NewRuleStatsHandler(ruleStatsRepo)
+	ruleCacheHandler := handler.NewRuleCacheHandler(redis)
+	ruleSuggestionHandler := handler.NewRuleSuggestionHandler(ruleSuggestionService, rulesRepo, ruleAnalyzer)
+	classifierHandler := handler.NewClassifierHandler(classifierRepo, asynqClient)
+	processingStepHandler := handler.NewProcessingStepHandler(processingStepRepo)
+	jobProgressHandler := handler.NewJobProgressHandler(jobProgressService)
+	sessionEventsHandler := handler.NewSessionEventsHandler(sessionEventHub, uploadRepo)
 
 	// Public routes
 	auth := router.Group("/auth")
@@ -57,6 +93,11 @@ func SetupAPIRoutes(
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/logout", authHandler.Logout)
 
+	// Live session progress (Server-Sent Events). EventSource cannot send headers, so
+	// the stream is opened with a single-use stream token (see the events/token route)
+	// instead of the access token; registered before the protected group.
+	router.Get("/uploads/session/:session_code/events", sessionEventsHandler.StreamEvents)
+
 	// Protected routes
 	protected := router.Group("", middleware.AuthMiddleware(cfg))
 
@@ -77,7 +118,7 @@ func SetupAPIRoutes(
 	})
 
 	// Account routes
-	accounts := protected.Group("/accounts")
+	accounts := protected.Group("/accounts", bumpRuleVersion)
 	accounts.Get("/", accountHandler.GetAccounts)
 	accounts.Get("/export", accountHandler.ExportAccounts)
 	accounts.Get("/template", accountHandler.DownloadTemplate)
@@ -89,7 +130,7 @@ func SetupAPIRoutes(
 	accounts.Delete("/:id", accountHandler.DeleteAccount)
 
 	// Additional Analysis routes
-	additionalAnalysis := protected.Group("/additional-analyses")
+	additionalAnalysis := protected.Group("/additional-analyses", bumpRuleVersion)
 	additionalAnalysis.Get("/", additionalAnalysisHandler.GetAll)
 	additionalAnalysis.Get("/export", additionalAnalysisHandler.ExportToExcel)
 	additionalAnalysis.Get("/template", additionalAnalysisHandler.DownloadTemplate)
@@ -103,42 +144,111 @@ func SetupAPIRoutes(
 	additionalAnalysis.Get("/account/:accountCode", additionalAnalysisHandler.GetByAccountCode)
 
 	// Koreksi Rules routes
-	koreksi := protected.Group("/koreksi-rules")
+	koreksi := protected.Group("/koreksi-rules", bumpRuleVersion)
 	koreksi.Get("/", koreksiRuleHandler.GetKoreksiRules)
 	koreksi.Get("/export", koreksiRuleHandler.ExportKoreksiRules)
 	koreksi.Get("/template", koreksiRuleHandler.DownloadTemplate)
 	koreksi.Post("/import", koreksiRuleHandler.ImportKoreksiRules)
 	koreksi.Get("/error-report/:filename", koreksiRuleHandler.DownloadErrorReport)
+	koreksi.Put("/reorder", koreksiRuleHandler.ReorderKoreksiRules)
 	koreksi.Get("/:id", koreksiRuleHandler.GetKoreksiRule)
 	koreksi.Post("/", koreksiRuleHandler.CreateKoreksiRule)
 	koreksi.Put("/:id", koreksiRuleHandler.UpdateKoreksiRule)
 	koreksi.Delete("/:id", koreksiRuleHandler.DeleteKoreksiRule)
 
 	// Obyek Rules routes
-	obyek := protected.Group("/obyek-rules")
+	obyek := protected.Group("/obyek-rules", bumpRuleVersion)
 	obyek.Get("/", obyekRuleHandler.GetObyekRules)
 	obyek.Get("/export", obyekRuleHandler.ExportObyekRules)
 	obyek.Get("/template", obyekRuleHandler.DownloadTemplate)
 	obyek.Post("/import", obyekRuleHandler.ImportObyekRules)
 	obyek.Get("/error-report/:filename", obyekRuleHandler.DownloadErrorReport)
+	obyek.Put("/reorder", obyekRuleHandler.ReorderObyekRules)
 	obyek.Get("/:id", obyekRuleHandler.GetObyekRule)
 	obyek.Post("/", obyekRuleHandler.CreateObyekRule)
 	obyek.Put("/:id", obyekRuleHandler.UpdateObyekRule)
 	obyek.Delete("/:id", obyekRuleHandler.DeleteObyekRule)
 
+	// UM Pajak Rules routes
+	umPajak := protected.Group("/um-pajak-rules", bumpRuleVersion)
+	umPajak.Get("/", umPajakRuleHandler.GetUmPajakRules)
+	umPajak.Get("/export", umPajakRuleHandler.ExportUmPajakRules)
+	umPajak.Get("/template", umPajakRuleHandler.DownloadTemplate)
+	umPajak.Post("/import", umPajakRuleHandler.ImportUmPajakRules)
+	umPajak.Get("/error-report/:filename", umPajakRuleHandler.DownloadErrorReport)
+	umPajak.Get("/:id", umPajakRuleHandler.GetUmPajakRule)
+	umPajak.Post("/", umPajakRuleHandler.CreateUmPajakRule)
+	umPajak.Put("/:id", umPajakRuleHandler.UpdateUmPajakRule)
+	umPajak.Delete("/:id", umPajakRuleHandler.DeleteUmPajakRule)
+
 	// Withholding Tax Rules routes
-	wht := protected.Group("/withholding-tax-rules")
-	wht.Get("/", ruleHandler.GetWithholdingTaxRules)
-	wht.Post("/", ruleHandler.CreateWithholdingTaxRule)
-	wht.Put("/:id", ruleHandler.UpdateWithholdingTaxRule)
-	wht.Delete("/:id", ruleHandler.DeleteWithholdingTaxRule)
+	wht := protected.Group("/withholding-tax-rules", bumpRuleVersion)
+	wht.Get("/", withholdingTaxRuleHandler.GetWithholdingTaxRules)
+	wht.Get("/resolution-policies", withholdingTaxRuleHandler.GetResolutionPolicies)
+	wht.Put("/resolution-policies/:tax_type", withholdingTaxRuleHandler.SetResolutionPolicy)
+	wht.Delete("/resolution-policies/:tax_type", withholdingTaxRuleHandler.DeleteResolutionPolicy)
+	wht.Get("/:id", withholdingTaxRuleHandler.GetWithholdingTaxRule)
+	wht.Post("/", withholdingTaxRuleHandler.CreateWithholdingTaxRule)
+	wht.Put("/:id", withholdingTaxRuleHandler.UpdateWithholdingTaxRule)
+	wht.Delete("/:id", withholdingTaxRuleHandler.DeleteWithholdingTaxRule)
 
 	// Tax Keywords routes
-	taxKeywords := protected.Group("/tax-keywords")
-	taxKeywords.Get("/", ruleHandler.GetTaxKeywords)
-	taxKeywords.Post("/", ruleHandler.CreateTaxKeyword)
-	taxKeywords.Put("/:id", ruleHandler.UpdateTaxKeyword)
-	taxKeywords.Delete("/:id", ruleHandler.DeleteTaxKeyword)
+	taxKeywords := protected.Group("/tax-keywords", bumpRuleVersion)
+	taxKeywords.Get("/", taxKeywordHandler.GetTaxKeywords)
+	taxKeywords.Get("/:id", taxKeywordHandler.GetTaxKeyword)
+	taxKeywords.Post("/", taxKeywordHandler.CreateTaxKeyword)
+	taxKeywords.Put("/:id", taxKeywordHandler.UpdateTaxKeyword)
+	taxKeywords.Delete("/:id", taxKeywordHandler.DeleteTaxKeyword)
+
+	// Keyword synonyms expanding koreksi/obyek keywords
+	keywordSynonyms := protected.Group("/keyword-synonyms", bumpRuleVersion)
+	keywordSynonyms.Get("/", keywordSynonymHandler.GetKeywordSynonyms)
+	keywordSynonyms.Get("/:id", keywordSynonymHandler.GetKeywordSynonym)
+	keywordSynonyms.Post("/", keywordSynonymHandler.CreateKeywordSynonym)
+	keywordSynonyms.Put("/:id", keywordSynonymHandler.UpdateKeywordSynonym)
+	keywordSynonyms.Delete("/:id", keywordSynonymHandler.DeleteKeywordSynonym)
+
+	// Rule test: one keterangan through the active rules, with its normalized form
+	protected.Post("/rules/test", ruleSimulationHandler.TestRules)
+
+	// Rule analysis: overlapping, shadowed and duplicate koreksi/obyek rules
+	protected.Get("/rule-analysis", ruleAnalysisHandler.AnalyzeRules)
+
+	// Rule set version routes
+	ruleSets := protected.Group("/rule-sets")
+	ruleSets.Get("/", ruleSetHandler.GetVersions)
+	ruleSets.Post("/", ruleSetHandler.CreateVersion)
+	ruleSets.Get("/:id", ruleSetHandler.GetVersion)
+
+	// Processing run routes
+	protected.Get("/processing-runs/:id", ruleSetHandler.GetRun)
+	protected.Get("/processing-runs/:id/rule-hits", ruleStatsHandler.GetRunRuleHits)
+	protected.Get("/processing-runs/:id/step-stats", processingStepHandler.GetRunStepStats)
+
+	// Processing pipeline: which steps run, in which order and with which config
+	processingSteps := protected.Group("/processing-steps")
+	processingSteps.Get("/", processingStepHandler.GetSteps)
+	processingSteps.Put("/:name", middleware.AdminOnly(), processingStepHandler.UpdateStep)
+
+	// Rule usage: hit counts per rule and keterangan no koreksi/obyek rule matched
+	ruleStats := protected.Group("/rule-stats")
+	ruleStats.Get("/hits", ruleStatsHandler.GetRuleHitSummary)
+	ruleStats.Get("/unmatched", ruleStatsHandler.GetUnmatchedKeterangan)
+
+	// Rule cache: the rule-set version every web and worker process holds
+	protected.Get("/admin/rule-cache", middleware.AdminOnly(), ruleCacheHandler.GetStatus)
+
+	// Rule suggestions mined from unclassified rows; accepting one creates the rule
+	ruleSuggestions := protected.Group("/rule-suggestions")
+	ruleSuggestions.Get("/", ruleSuggestionHandler.GetSuggestions)
+	ruleSuggestions.Post("/accept", middleware.AdminOnly(), bumpRuleVersion, ruleSuggestionHandler.AcceptSuggestion)
+
+	// Koreksi/obyek suggestion classifier versions
+	classifier := protected.Group("/classifier/models")
+	classifier.Get("/", classifierHandler.GetModels)
+	classifier.Post("/", middleware.AdminOnly(), classifierHandler.TrainModel)
+	classifier.Get("/:id", classifierHandler.GetModel)
+	classifier.Post("/:id/activate", middleware.AdminOnly(), classifierHandler.ActivateModel)
 
 	// Upload routes
 	uploads := protected.Group("/uploads")
@@ -152,27 +262,21 @@ func SetupAPIRoutes(
 	uploads.Get("/session/:session_code/transactions", uploadHandler.GetTransactionsBySessionCode) // New optimized route - MOVED UP
 	uploads.Get("/:id/transactions", uploadHandler.GetTransactions)
 	uploads.Post("/:id/process", uploadHandler.ProcessSession)
+	uploads.Post("/:id/reprocess", ruleSetHandler.ReprocessSession)
+	uploads.Get("/:id/runs", ruleSetHandler.GetSessionRuns)
 	uploads.Post("/:id/cancel", uploadHandler.CancelSession)
 	uploads.Get("/:id/export", uploadHandler.ExportSession)
 	uploads.Get("/session/:session_code/export", uploadHandler.ExportSessionByCode)
+	uploads.Post("/session/:session_code/simulate", ruleSimulationHandler.SimulateSession)
 	uploads.Delete("/:id", uploadHandler.DeleteSession)
 	uploads.Get("/progress/:session_code", uploadHandler.GetUploadProgress)
+	uploads.Post("/session/:session_code/events/token", sessionEventsHandler.IssueStreamToken)
 
 	// Transaction routes
 	protected.Put("/transactions/:id", uploadHandler.UpdateTransaction)
+	protected.Post("/transactions/:id/reset-override", transactionOverrideHandler.ResetOverride)
 
 	// Job progress routes
 	jobs := protected.Group("/jobs")
-	jobs.Get("/:job_id/progress", func(c *fiber.Ctx) error {
-		jobID := c.Params("job_id")
-		// TODO: Implement actual progress tracking
-		return c.JSON(fiber.Map{
-			"success": true,
-			"data": fiber.Map{
-				"job_id":   jobID,
-				"progress": 50.0,
-				"status":   "processing",
-			},
-		})
-	})
+	jobs.Get("/:job_id/progress", jobProgressHandler.GetProgress)
 }
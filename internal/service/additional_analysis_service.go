@@ -5,6 +5,7 @@ import (
 	"accounting-web/internal/repository"
 	"accounting-web/internal/utils"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -42,6 +43,7 @@ func (s *AdditionalAnalysisService) Create(req models.AdditionalAnalysisRequest,
 		AccountCode:   req.AccountCode,
 		AnalysisType:  req.AnalysisType,
 		AnalysisTitle: req.AnalysisTitle,
+		KeywordCondition: optionalString(req.KeywordCondition),
 		Status:        req.Status,
 		Notes:         &req.Notes,
 		CreatedBy:     &createdBy,
@@ -132,6 +134,7 @@ func (s *AdditionalAnalysisService) Update(id int, req models.AdditionalAnalysis
 		AccountCode:   req.AccountCode,
 		AnalysisType:  req.AnalysisType,
 		AnalysisTitle: req.AnalysisTitle,
+		KeywordCondition: optionalString(req.KeywordCondition),
 		Status:        req.Status,
 		Notes:         &req.Notes,
 	}
@@ -317,4 +320,13 @@ func (s *AdditionalAnalysisService) ExportToExcel(filter models.AdditionalAnalys
 	// For now, return a placeholder
 	// TODO: Implement actual Excel export functionality
 	return []byte(fmt.Sprintf("Excel export functionality to be implemented. Found %d analyses.", len(analyses))), nil
-}
\ No newline at end of file
+}
+
+// optionalString returns nil for an empty (or blank) string
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// groupAdditionalAnalyses indexes analyses by account code, ordered by analysis type then id
+func groupAdditionalAnalyses(analyses []models.AdditionalAnalysis) map[string][]models.AdditionalAnalysis {
+	grouped := make(map[string][]models.AdditionalAnalysis)
+	for _, analysis := range analyses {
+		grouped[analysis.AccountCode] = append(grouped[analysis.AccountCode], analysis)
+	}
+
+	for _, list := range grouped {
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].AnalysisType != list[j].AnalysisType {
+				return list[i].AnalysisType < list[j].AnalysisType
+			}
+			return list[i].ID < list[j].ID
+		})
+	}
+	return grouped
+}
+
+// analyzeAdditional builds the Analisa Tambahan value from the account's additional
+// analyses. Matching analyses are combined per type as "type: title, title; type: title".
+// When none apply, the account's own analisa_tambahan is used.
+func (e *ProcessingEngine) analyzeAdditional(tx *models.TransactionData, keterangan string) string {
+	var types []string
+	titles := make(map[string][]string)
+
+	for _, analysis := range e.additionalAnalyses[tx.Account] {
+		if !matchKeywordCondition(analysis.KeywordCondition, keterangan) {
+			continue
+		}
+
+		if _, ok := titles[analysis.AnalysisType]; !ok {
+			types = append(types, analysis.AnalysisType)
+			titles[analysis.AnalysisType] = nil
+		}
+		if !containsString(titles[analysis.AnalysisType], analysis.AnalysisTitle) {
+			titles[analysis.AnalysisType] = append(titles[analysis.AnalysisType], analysis.AnalysisTitle)
+		}
+
+		if _, recorded := tx.RuleProvenance["analisa_tambahan"]; !recorded {
+			tx.RuleProvenance["analisa_tambahan"] = models.RuleProvenance{
+				RuleType: models.RuleTypeAdditionalAnalysis,
+				RuleID:   analysis.ID,
+				Keyword:  analysis.AnalysisTitle,
+			}
+		}
+	}
+
+	if len(types) > 0 {
+		parts := make([]string, 0, len(types))
+		for _, analysisType := range types {
+			parts = append(parts, fmt.Sprintf("%s: %s", analysisType, strings.Join(titles[analysisType], ", ")))
+		}
+		return strings.Join(parts, "; ")
+	}
+
+	// Fall back to the account master data
+	if account, exists := e.accounts[tx.Account]; exists {
+		return account.AnalisaTambahan
+	}
+	return ""
+}
+
+// matchKeywordCondition reports whether keterangan (normalized, see NormalizeKeyterm)
+// satisfies a keyword condition. The condition is a comma-separated list: the row must
+// contain at least one plain keyword and none of the "!"-prefixed ones. An empty
+// condition always matches.
+func matchKeywordCondition(condition *string, keterangan string) bool {
+	if condition == nil || strings.TrimSpace(*condition) == "" {
+		return true
+	}
+
+	hasInclude, included := false, false
+	for _, term := range strings.Split(*condition, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		if strings.HasPrefix(term, "!") {
+			excluded := NormalizeKeyterm(strings.TrimPrefix(term, "!"))
+			if excluded != "" && strings.Contains(keterangan, excluded) {
+				return false
+			}
+			continue
+		}
+
+		hasInclude = true
+		if term = NormalizeKeyterm(term); term != "" && strings.Contains(keterangan, term) {
+			included = true
+		}
+	}
+
+	return !hasInclude || included
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxClassifierTrainingRows = 200000
+	// Rows corrected by hand count this many times as much as rule-derived rows
+	manualCorrectionWeight = 2.0
+	// Tokens seen fewer times than this over all training rows are left out of the model
+	minClassifierTokenCount = 2
+	// Suggestions below this confidence are not stored
+	minSuggestionConfidence = 0.5
+	// Every holdoutEvery-th row is held out to measure accuracy, given enough rows
+	holdoutEvery         = 10
+	minRowsForEvaluation = 100
+)
+
+// naiveBayes is a multinomial naive Bayes model predicting one field (koreksi or obyek)
+type naiveBayes struct {
+	Classes    []naiveBayesClass `json:"classes"`
+	TotalDocs  float64           `json:"total_docs"`
+	Vocabulary int               `json:"vocabulary"`
+}
+
+type naiveBayesClass struct {
+	Value       string             `json:"value"`
+	Docs        float64            `json:"docs"`
+	TokenTotal  float64            `json:"token_total"`
+	TokenCounts map[string]float64 `json:"token_counts"`
+}
+
+// classifierDocument is a training row's features and label for one field
+type classifierDocument struct {
+	features []string
+	label    string
+	weight   float64
+}
+
+// trainNaiveBayes counts tokens per class with Laplace smoothing applied at prediction
+func trainNaiveBayes(docs []classifierDocument) *naiveBayes {
+	tokenTotals := map[string]float64{}
+	for _, doc := range docs {
+		for _, f := range doc.features {
+			tokenTotals[f] += doc.weight
+		}
+	}
+
+	model := &naiveBayes{}
+	classes := map[string]*naiveBayesClass{}
+	for _, doc := range docs {
+		class, ok := classes[doc.label]
+		if !ok {
+			class = &naiveBayesClass{Value: doc.label, TokenCounts: map[string]float64{}}
+			classes[doc.label] = class
+		}
+		class.Docs += doc.weight
+		model.TotalDocs += doc.weight
+		for _, f := range doc.features {
+			if tokenTotals[f] < minClassifierTokenCount {
+				continue
+			}
+			class.TokenCounts[f] += doc.weight
+			class.TokenTotal += doc.weight
+		}
+	}
+
+	for _, total := range tokenTotals {
+		if total >= minClassifierTokenCount {
+			model.Vocabulary++
+		}
+	}
+	for _, class := range classes {
+		model.Classes = append(model.Classes, *class)
+	}
+	sort.Slice(model.Classes, func(i, j int) bool { return model.Classes[i].Value < model.Classes[j].Value })
+	return model
+}
+
+// predict returns the most likely class and its posterior probability. It returns
+// false when the model is empty or none of the features is in its vocabulary.
+func (m *naiveBayes) predict(features []string) (string, float64, bool) {
+	if m == nil || len(m.Classes) == 0 {
+		return "", 0, false
+	}
+
+	known := features[:0:0]
+	for _, f := range features {
+		for i := range m.Classes {
+			if _, ok := m.Classes[i].TokenCounts[f]; ok {
+				known = append(known, f)
+				break
+			}
+		}
+	}
+	if len(known) == 0 {
+		return "", 0, false
+	}
+
+	scores := make([]float64, len(m.Classes))
+	best := 0
+	for i, class := range m.Classes {
+		score := math.Log(class.Docs / m.TotalDocs)
+		denominator := class.TokenTotal + float64(m.Vocabulary)
+		for _, f := range known {
+			score += math.Log((class.TokenCounts[f] + 1) / denominator)
+		}
+		scores[i] = score
+		if score > scores[best] {
+			best = i
+		}
+	}
+
+	// Posterior of the best class: softmax over the log scores
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+	return m.Classes[best].Value, 1 / sum, true
+}
+
+// classifierFeatures turns a row into tokens: the words of its normalized keterangan
+// (numbers and single characters dropped), its account code and its document type
+func classifierFeatures(keterangan, account, documentType string) []string {
+	words := strings.Fields(NormalizeKeyterm(keterangan))
+
+	features := make([]string, 0, len(words)+2)
+	for _, word := range words {
+		if len([]rune(word)) < 2 || isNumber(word) {
+			continue
+		}
+		features = append(features, word)
+	}
+	if account = strings.TrimSpace(account); account != "" {
+		features = append(features, "account:"+account)
+	}
+	if documentType = strings.TrimSpace(strings.ToLower(documentType)); documentType != "" {
+		features = append(features, "doctype:"+documentType)
+	}
+	return features
+}
+
+// TextClassifier suggests koreksi and obyek for rows no rule matched. It is trained
+// offline on processed rows and never sets koreksi or obyek itself.
+type TextClassifier struct {
+	Version int         `json:"-"`
+	Koreksi *naiveBayes `json:"koreksi"`
+	Obyek   *naiveBayes `json:"obyek"`
+}
+
+// LoadTextClassifier decodes a stored model version
+func LoadTextClassifier(model *models.ClassifierModel) (*TextClassifier, error) {
+	if model.Model == nil {
+		return nil, fmt.Errorf("classifier model %d has no trained data", model.Version)
+	}
+	var classifier TextClassifier
+	if err := json.Unmarshal([]byte(*model.Model), &classifier); err != nil {
+		return nil, fmt.Errorf("failed to decode classifier model %d: %w", model.Version, err)
+	}
+	classifier.Version = model.Version
+	return &classifier, nil
+}
+
+// Suggest fills the suggested_* fields of a processed row whose koreksi or obyek is
+// still empty, when the prediction is confident enough
+func (c *TextClassifier) Suggest(tx *models.TransactionData) {
+	c.suggest(tx, minSuggestionConfidence)
+}
+
+// suggest is Suggest with the minimum confidence set by the classifier step config
+func (c *TextClassifier) suggest(tx *models.TransactionData, minConfidence float64) {
+	tx.SuggestedKoreksi, tx.SuggestedKoreksiConfidence = nil, nil
+	tx.SuggestedObyek, tx.SuggestedObyekConfidence = nil, nil
+	tx.SuggestionModelVersion = nil
+	if c == nil || (tx.Koreksi != nil && tx.Obyek != nil) {
+		return
+	}
+
+	features := classifierFeatures(tx.Keterangan, tx.Account, tx.DocumentType)
+	suggested := false
+	if tx.Koreksi == nil {
+		if value, confidence, ok := c.Koreksi.predict(features); ok && confidence >= minConfidence {
+			confidence = roundConfidence(confidence)
+			tx.SuggestedKoreksi, tx.SuggestedKoreksiConfidence = &value, &confidence
+			suggested = true
+		}
+	}
+	if tx.Obyek == nil {
+		if value, confidence, ok := c.Obyek.predict(features); ok && confidence >= minConfidence {
+			confidence = roundConfidence(confidence)
+			tx.SuggestedObyek, tx.SuggestedObyekConfidence = &value, &confidence
+			suggested = true
+		}
+	}
+	if suggested {
+		version := c.Version
+		tx.SuggestionModelVersion = &version
+	}
+}
+
+// roundConfidence rounds to the 4 decimals stored in the database
+func roundConfidence(confidence float64) float64 {
+	return math.Round(confidence*10000) / 10000
+}
+
+// ClassifierTrainer trains new classifier versions from the processed transactions
+type ClassifierTrainer struct {
+	classifierRepo *repository.ClassifierRepository
+}
+
+func NewClassifierTrainer(classifierRepo *repository.ClassifierRepository) *ClassifierTrainer {
+	return &ClassifierTrainer{
+		classifierRepo: classifierRepo,
+	}
+}
+
+// Train trains the queued model version modelID, stores it and makes it the active one
+func (t *ClassifierTrainer) Train(modelID int) (*models.ClassifierModel, error) {
+	model, err := t.classifierRepo.GetModelByID(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load classifier model %d: %w", modelID, err)
+	}
+	model.Status = models.ClassifierStatusTraining
+	if err := t.classifierRepo.UpdateModel(model); err != nil {
+		return nil, err
+	}
+
+	if err := t.train(model); err != nil {
+		errMsg := err.Error()
+		model.Status = models.ClassifierStatusFailed
+		model.ErrorMessage = &errMsg
+		if updateErr := t.classifierRepo.UpdateModel(model); updateErr != nil {
+			return nil, updateErr
+		}
+		return model, err
+	}
+
+	trainedAt := time.Now()
+	model.Status = models.ClassifierStatusReady
+	model.TrainedAt = &trainedAt
+	if err := t.classifierRepo.UpdateModel(model); err != nil {
+		return nil, err
+	}
+	if err := t.classifierRepo.ActivateModel(model.ID); err != nil {
+		return nil, err
+	}
+	model.IsActive = true
+	model.Model = nil
+	return model, nil
+}
+
+func (t *ClassifierTrainer) train(model *models.ClassifierModel) error {
+	rows, err := t.classifierRepo.GetTrainingRows(maxClassifierTrainingRows)
+	if err != nil {
+		return fmt.Errorf("failed to load training rows: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no processed rows with koreksi or obyek to train on")
+	}
+
+	var koreksiDocs, obyekDocs []classifierDocument
+	for _, row := range rows {
+		features := classifierFeatures(row.Keterangan, row.Account, row.DocumentType)
+		if len(features) == 0 {
+			continue
+		}
+		if doc, ok := trainingDocument(features, row.Koreksi, row.KoreksiOverride); ok {
+			koreksiDocs = append(koreksiDocs, doc)
+		}
+		if doc, ok := trainingDocument(features, row.Obyek, row.ObyekOverride); ok {
+			obyekDocs = append(obyekDocs, doc)
+		}
+	}
+
+	classifier := &TextClassifier{
+		Koreksi: trainNaiveBayes(koreksiDocs),
+		Obyek:   trainNaiveBayes(obyekDocs),
+	}
+	data, err := json.Marshal(classifier)
+	if err != nil {
+		return fmt.Errorf("failed to encode classifier model: %w", err)
+	}
+	encoded := string(data)
+
+	model.TrainingRows = len(rows)
+	model.KoreksiClasses = len(classifier.Koreksi.Classes)
+	model.ObyekClasses = len(classifier.Obyek.Classes)
+	model.VocabularySize = classifier.Koreksi.Vocabulary
+	if classifier.Obyek.Vocabulary > model.VocabularySize {
+		model.VocabularySize = classifier.Obyek.Vocabulary
+	}
+	model.KoreksiAccuracy = holdoutAccuracy(koreksiDocs)
+	model.ObyekAccuracy = holdoutAccuracy(obyekDocs)
+	model.Model = &encoded
+	model.ErrorMessage = nil
+	return nil
+}
+
+func trainingDocument(features []string, value *string, manual bool) (classifierDocument, bool) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return classifierDocument{}, false
+	}
+	weight := 1.0
+	if manual {
+		weight = manualCorrectionWeight
+	}
+	return classifierDocument{features: features, label: strings.TrimSpace(*value), weight: weight}, true
+}
+
+// holdoutAccuracy trains on all but every holdoutEvery-th document and returns the
+// share of held-out documents predicted correctly, or nil with too few documents
+func holdoutAccuracy(docs []classifierDocument) *float64 {
+	if len(docs) < minRowsForEvaluation {
+		return nil
+	}
+
+	var train, test []classifierDocument
+	for i, doc := range docs {
+		if i%holdoutEvery == 0 {
+			test = append(test, doc)
+		} else {
+			train = append(train, doc)
+		}
+	}
+
+	model := trainNaiveBayes(train)
+	correct := 0
+	for _, doc := range test {
+		if value, _, ok := model.predict(doc.features); ok && value == doc.label {
+			correct++
+		}
+	}
+	accuracy := roundConfidence(float64(correct) / float64(len(test)))
+	return &accuracy
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"accounting-web/internal/models"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -42,56 +43,170 @@ func (s *ExcelService) ParseTransactionFile(filePath string) ([]models.Transacti
 	}
 
 	// Validate header
-	header := rows[0]
-	expectedHeaders := []string{
-		"Document Type", "Document Number", "Posting Date", "Account",
-		"Account Name", "Keterangan", "Debet", "Credit", "Net",
-	}
-
-	if len(header) < len(expectedHeaders) {
-		return nil, fmt.Errorf("invalid header format")
+	if err := validateTransactionHeader(rows[0]); err != nil {
+		return nil, err
 	}
 
 	// Parse data rows
 	var transactions []models.TransactionData
 	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) < 9 {
+		tx, ok := parseTransactionRow(rows[i])
+		if !ok {
 			continue // Skip incomplete rows
 		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
+
+// CountTransactionRows counts the data rows of a transaction file without parsing
+// them, to decide whether an upload is parsed in the background
+func (s *ExcelService) CountTransactionRows(filePath string) (int, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return 0, fmt.Errorf("no sheets found in Excel file")
+	}
+
+	rows, err := f.Rows(sheets[0])
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Error(); err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count - 1, nil // without the header row
+}
+
+// StreamTransactionFile parses a transaction file like ParseTransactionFile but reads
+// it row by row and hands the transactions to fn in batches of batchSize, so large
+// files are never held in memory. It returns the number of transactions parsed.
+func (s *ExcelService) StreamTransactionFile(filePath string, batchSize int, fn func([]models.TransactionData) error) (int, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return 0, fmt.Errorf("no sheets found in Excel file")
+	}
+
+	rows, err := f.Rows(sheets[0])
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	defer rows.Close()
 
-		tx := models.TransactionData{}
-
-		// Parse basic fields
-		tx.DocumentType = getCellValue(row, 0)
-		tx.DocumentNumber = getCellValue(row, 1)
-
-		// Parse posting date
-		dateStr := getCellValue(row, 2)
-		if dateStr != "" {
-			parsedDate, err := parseDate(dateStr)
-			if err == nil {
-				tx.PostingDate = &parsedDate
-			} else {
-				// If parsing fails, set to current date as fallback
-				now := time.Now()
-				tx.PostingDate = &now
+	if !rows.Next() {
+		return 0, fmt.Errorf("file must contain at least header row and one data row")
+	}
+	header, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+	if err := validateTransactionHeader(header); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	batch := make([]models.TransactionData, 0, batchSize)
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			return total, fmt.Errorf("failed to read rows: %w", err)
+		}
+		tx, ok := parseTransactionRow(row)
+		if !ok {
+			continue // Skip incomplete rows
+		}
+
+		batch = append(batch, tx)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return total, err
 			}
+			total += len(batch)
+			batch = make([]models.TransactionData, 0, batchSize)
 		}
+	}
+	if err := rows.Error(); err != nil {
+		return total, fmt.Errorf("failed to read rows: %w", err)
+	}
 
-		tx.Account = getCellValue(row, 3)
-		tx.AccountName = getCellValue(row, 4)
-		tx.Keterangan = getCellValue(row, 5)
+	if len(batch) > 0 {
+		if err := fn(batch); err != nil {
+			return total, err
+		}
+		total += len(batch)
+	}
+	return total, nil
+}
 
-		// Parse numeric fields
-		tx.Debet = parseFloat(getCellValue(row, 6))
-		tx.Credit = parseFloat(getCellValue(row, 7))
-		tx.Net = parseFloat(getCellValue(row, 8))
+// validateTransactionHeader checks the header row of a transaction file
+func validateTransactionHeader(header []string) error {
+	expectedHeaders := []string{
+		"Document Type", "Document Number", "Posting Date", "Account",
+		"Account Name", "Keterangan", "Debet", "Credit", "Net",
+	}
 
-		transactions = append(transactions, tx)
+	if len(header) < len(expectedHeaders) {
+		return fmt.Errorf("invalid header format")
+	}
+	return nil
+}
+
+// parseTransactionRow converts one data row of a transaction file; incomplete rows
+// are reported as not ok
+func parseTransactionRow(row []string) (models.TransactionData, bool) {
+	tx := models.TransactionData{}
+	if len(row) < 9 {
+		return tx, false
 	}
 
-	return transactions, nil
+	// Parse basic fields
+	tx.DocumentType = getCellValue(row, 0)
+	tx.DocumentNumber = getCellValue(row, 1)
+
+	// Parse posting date
+	dateStr := getCellValue(row, 2)
+	if dateStr != "" {
+		parsedDate, err := parseDate(dateStr)
+		if err == nil {
+			tx.PostingDate = &parsedDate
+		} else {
+			// If parsing fails, set to current date as fallback
+			now := time.Now()
+			tx.PostingDate = &now
+		}
+	}
+
+	tx.Account = getCellValue(row, 3)
+	tx.AccountName = getCellValue(row, 4)
+	tx.Keterangan = getCellValue(row, 5)
+
+	// Parse numeric fields
+	tx.Debet = parseFloat(getCellValue(row, 6))
+	tx.Credit = parseFloat(getCellValue(row, 7))
+	tx.Net = parseFloat(getCellValue(row, 8))
+
+	return tx, true
 }
 
 // ExportTransactions exports processed transactions to Excel
@@ -110,7 +225,11 @@ func (s *ExcelService) ExportTransactions(transactions []models.TransactionData,
 		"Document Type", "Document Number", "Posting Date", "Account", "Account Name",
 		"Keterangan", "Debet", "Credit", "Net", "Analisa Nature Akun", "Analisa K-O-T",
 		"Analisa Tambahan", "Koreksi", "Obyek", "UM Pajak DB", "PM DB", "Wth 21 Cr", "Wth 23 Cr",
-		"Wth 26 Cr", "Wth 4.2 Cr", "Wth 15 Cr", "PK Cr", "Processed",
+		"Wth 26 Cr", "Wth 4.2 Cr", "Wth 15 Cr", "PK Cr", "Processed", "Processing Notes", "Processing Warning",
+		"Suggested Koreksi", "Suggested Obyek",
+		// Explain columns: which rule produced each output field
+		"Koreksi Rule", "Obyek Rule", "WHT Rule", "PM DB Rule", "PK Cr Rule", "UM Pajak DB Rule",
+		"Analisa Tambahan Source",
 	}
 
 	// Write headers
@@ -176,6 +295,17 @@ func (s *ExcelService) ExportTransactions(transactions []models.TransactionData,
 				}
 				return "No"
 			}(),
+			safeString(tx.ProcessingNotes),
+			safeString(tx.ProcessingWarning),
+			safeString(tx.SuggestedKoreksi),
+			safeString(tx.SuggestedObyek),
+			tx.RuleProvenance.Explain("koreksi"),
+			tx.RuleProvenance.Explain("obyek"),
+			tx.RuleProvenance.ExplainPrefix("wth_"),
+			tx.RuleProvenance.Explain("pm_db"),
+			tx.RuleProvenance.Explain("pk_cr"),
+			tx.RuleProvenance.Explain("um_pajak_db"),
+			tx.RuleProvenance.Explain("analisa_tambahan"),
 		}
 
 		for colIdx, value := range values {
@@ -192,7 +322,7 @@ func (s *ExcelService) ExportTransactions(transactions []models.TransactionData,
 	f.SetCellStyle(sheetName, "A1", fmt.Sprintf("%s1", getColumnName(len(headers)-1)), headerStyle)
 
 	// Set column widths for better readability
-	columnWidths := []float64{15, 20, 15, 12, 25, 30, 15, 15, 15, 15, 15, 15, 15, 15, 12, 15, 20, 20, 20, 20, 20, 20, 20, 12}
+	columnWidths := []float64{15, 20, 15, 12, 25, 30, 15, 15, 15, 15, 15, 15, 15, 15, 12, 15, 20, 20, 20, 20, 20, 20, 20, 50, 40, 25, 25, 35, 25, 25}
 
 	for i, width := range columnWidths {
 		if i < len(columnWidths) {
@@ -753,6 +883,15 @@ func parseBoolValue(s string) bool {
 	return s == "Yes" || s == "yes" || s == "Y" || s == "y" || s == "1" || s == "true" || s == "TRUE"
 }
 
+// parsePriorityValue parses an optional priority cell, defaulting to 0
+func parsePriorityValue(s string) int {
+	priority, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
 func getNullStringValue(ns sql.NullString) string {
 	if ns.Valid {
 		return ns.String
@@ -760,6 +899,39 @@ func getNullStringValue(ns sql.NullString) string {
 	return ""
 }
 
+// conditionsCellValue renders rule conditions as JSON for an Excel cell
+func conditionsCellValue(conditions *models.RuleCondition) string {
+	if conditions == nil {
+		return ""
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseConditionsCell decodes a Conditions cell; validateConditionsCell must have accepted it
+func parseConditionsCell(cell string) *models.RuleCondition {
+	if strings.TrimSpace(cell) == "" {
+		return nil
+	}
+	var conditions models.RuleCondition
+	if err := json.Unmarshal([]byte(cell), &conditions); err != nil {
+		return nil
+	}
+	return &conditions
+}
+
+// validateConditionsCell checks that a Conditions cell holds a valid condition tree
+func validateConditionsCell(cell string) error {
+	var conditions models.RuleCondition
+	if err := json.Unmarshal([]byte(cell), &conditions); err != nil {
+		return fmt.Errorf("Conditions must be valid JSON: %v", err)
+	}
+	return ValidateRuleCondition(&conditions)
+}
+
 // ExportKoreksiRules exports koreksi rules to Excel file
 func (s *ExcelService) ExportKoreksiRules(rules []models.KoreksiRule, filePath string) error {
 	f := excelize.NewFile()
@@ -773,7 +945,7 @@ func (s *ExcelService) ExportKoreksiRules(rules []models.KoreksiRule, filePath s
 
 	// Set headers
 	headers := []string{
-		"Keyword", "Value", "Not Value", "Is Active",
+		"Keyword", "Value", "Not Value", "Is Active", "Priority", "Conditions",
 	}
 
 	for i, header := range headers {
@@ -800,6 +972,8 @@ func (s *ExcelService) ExportKoreksiRules(rules []models.KoreksiRule, filePath s
 			isActiveStr = "Yes"
 		}
 		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), isActiveStr)
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), rule.Priority)
+		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), conditionsCellValue(rule.Conditions))
 	}
 
 	// Set column widths
@@ -807,6 +981,8 @@ func (s *ExcelService) ExportKoreksiRules(rules []models.KoreksiRule, filePath s
 	f.SetColWidth(sheetName, "B", "B", 30)
 	f.SetColWidth(sheetName, "C", "C", 30)
 	f.SetColWidth(sheetName, "D", "D", 12)
+	f.SetColWidth(sheetName, "E", "E", 12)
+	f.SetColWidth(sheetName, "F", "F", 60)
 
 	f.SetActiveSheet(index)
 	f.DeleteSheet("Sheet1")
@@ -838,7 +1014,7 @@ func (s *ExcelService) ParseKoreksiRulesWithValidation(filePath string) (*models
 		return nil, fmt.Errorf("file must contain at least header row and one data row")
 	}
 
-	// Validate headers
+	// Validate headers (Priority is an optional trailing column for older templates)
 	expectedHeaders := []string{
 		"Keyword", "Value", "Not Value", "Is Active",
 	}
@@ -861,8 +1037,8 @@ func (s *ExcelService) ParseKoreksiRulesWithValidation(filePath string) (*models
 	for i := 1; i < len(rows); i++ {
 		row := rows[i]
 
-		// Skip completely empty rows
-		if len(row) == 0 || (len(row) > 0 && row[0] == "") {
+		// Skip completely empty rows (a rule without keyword needs conditions)
+		if len(row) == 0 || (row[0] == "" && getStringValue(row, 5) == "") {
 			continue
 		}
 
@@ -871,9 +1047,11 @@ func (s *ExcelService) ParseKoreksiRulesWithValidation(filePath string) (*models
 		value := getStringValue(row, 1)
 		notValue := getStringValue(row, 2)
 		isActiveStr := getStringValue(row, 3)
+		priorityStr := getStringValue(row, 4)
+		conditionsStr := getStringValue(row, 5)
 
 		// Validate fields
-		rowErrors := s.validateKoreksiRuleRow(i+1, keyword, value, notValue, isActiveStr)
+		rowErrors := s.validateKoreksiRuleRow(i+1, keyword, value, notValue, isActiveStr, priorityStr, conditionsStr)
 
 		if len(rowErrors) > 0 {
 			result.ValidationErrors = append(result.ValidationErrors, rowErrors...)
@@ -881,12 +1059,15 @@ func (s *ExcelService) ParseKoreksiRulesWithValidation(filePath string) (*models
 		} else {
 			// Create valid rule
 			rule := models.KoreksiRule{
-				Keyword:  keyword,
-				Value:    value,
-				NotValue: sql.NullString{String: notValue, Valid: notValue != ""},
-				IsActive: parseBoolValue(isActiveStr),
+				Keyword:    keyword,
+				Value:      value,
+				NotValue:   sql.NullString{String: notValue, Valid: notValue != ""},
+				Conditions: parseConditionsCell(conditionsStr),
+				Priority:   parsePriorityValue(priorityStr),
+				IsActive:   parseBoolValue(isActiveStr),
 			}
 			result.ValidRules = append(result.ValidRules, rule)
+			result.ValidRows = append(result.ValidRows, i+1)
 			result.ValidCount++
 		}
 	}
@@ -895,16 +1076,16 @@ func (s *ExcelService) ParseKoreksiRulesWithValidation(filePath string) (*models
 }
 
 // validateKoreksiRuleRow validates a single koreksi rule row and returns validation errors
-func (s *ExcelService) validateKoreksiRuleRow(rowNum int, keyword, value, notValue, isActiveStr string) []models.KoreksiRuleValidationError {
+func (s *ExcelService) validateKoreksiRuleRow(rowNum int, keyword, value, notValue, isActiveStr, priorityStr, conditionsStr string) []models.KoreksiRuleValidationError {
 	var errors []models.KoreksiRuleValidationError
 
-	// Validate Keyword (Required)
-	if keyword == "" {
+	// Validate Keyword (Required unless the rule has conditions)
+	if keyword == "" && conditionsStr == "" {
 		errors = append(errors, models.KoreksiRuleValidationError{
 			Row:     rowNum,
 			Field:   "Keyword",
 			Value:   keyword,
-			Message: "Keyword is required",
+			Message: "Keyword is required unless Conditions are set",
 		})
 	} else if len(keyword) > 255 {
 		errors = append(errors, models.KoreksiRuleValidationError{
@@ -952,6 +1133,29 @@ func (s *ExcelService) validateKoreksiRuleRow(rowNum int, keyword, value, notVal
 		})
 	}
 
+	// Validate Priority (Optional, must be an integer)
+	if priorityStr != "" {
+		if _, err := strconv.Atoi(strings.TrimSpace(priorityStr)); err != nil {
+			errors = append(errors, models.KoreksiRuleValidationError{
+				Row:     rowNum,
+				Field:   "Priority",
+				Value:   priorityStr,
+				Message: "Priority must be a whole number",
+			})
+		}
+	}
+
+	// Validate Conditions (Optional, JSON condition tree)
+	if conditionsStr != "" {
+		if err := validateConditionsCell(conditionsStr); err != nil {
+			errors = append(errors, models.KoreksiRuleValidationError{
+				Row:     rowNum,
+				Field:   "Conditions",
+				Value:   conditionsStr,
+				Message: err.Error(),
+			})
+		}
+	}
 	return errors
 }
 
@@ -1050,7 +1254,7 @@ func (s *ExcelService) ExportObyekRules(rules []models.ObyekRule, filePath strin
 
 	// Set headers
 	headers := []string{
-		"Keyword", "Value", "Not Value", "Is Active",
+		"Keyword", "Value", "Not Value", "Is Active", "Priority", "Conditions",
 	}
 
 	for i, header := range headers {
@@ -1077,6 +1281,8 @@ func (s *ExcelService) ExportObyekRules(rules []models.ObyekRule, filePath strin
 			isActiveStr = "Yes"
 		}
 		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), isActiveStr)
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), rule.Priority)
+		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), conditionsCellValue(rule.Conditions))
 	}
 
 	// Set column widths
@@ -1084,6 +1290,8 @@ func (s *ExcelService) ExportObyekRules(rules []models.ObyekRule, filePath strin
 	f.SetColWidth(sheetName, "B", "B", 30)
 	f.SetColWidth(sheetName, "C", "C", 30)
 	f.SetColWidth(sheetName, "D", "D", 12)
+	f.SetColWidth(sheetName, "E", "E", 12)
+	f.SetColWidth(sheetName, "F", "F", 60)
 
 	f.SetActiveSheet(index)
 	f.DeleteSheet("Sheet1")
@@ -1115,7 +1323,7 @@ func (s *ExcelService) ParseObyekRulesWithValidation(filePath string) (*models.O
 		return nil, fmt.Errorf("file must contain at least header row and one data row")
 	}
 
-	// Validate headers
+	// Validate headers (Priority is an optional trailing column for older templates)
 	expectedHeaders := []string{
 		"Keyword", "Value", "Not Value", "Is Active",
 	}
@@ -1138,8 +1346,8 @@ func (s *ExcelService) ParseObyekRulesWithValidation(filePath string) (*models.O
 	for i := 1; i < len(rows); i++ {
 		row := rows[i]
 
-		// Skip completely empty rows
-		if len(row) == 0 || (len(row) > 0 && row[0] == "") {
+		// Skip completely empty rows (a rule without keyword needs conditions)
+		if len(row) == 0 || (row[0] == "" && getStringValue(row, 5) == "") {
 			continue
 		}
 
@@ -1148,9 +1356,11 @@ func (s *ExcelService) ParseObyekRulesWithValidation(filePath string) (*models.O
 		value := getStringValue(row, 1)
 		notValue := getStringValue(row, 2)
 		isActiveStr := getStringValue(row, 3)
+		priorityStr := getStringValue(row, 4)
+		conditionsStr := getStringValue(row, 5)
 
 		// Validate fields
-		rowErrors := s.validateObyekRuleRow(i+1, keyword, value, notValue, isActiveStr)
+		rowErrors := s.validateObyekRuleRow(i+1, keyword, value, notValue, isActiveStr, priorityStr, conditionsStr)
 
 		if len(rowErrors) > 0 {
 			result.ValidationErrors = append(result.ValidationErrors, rowErrors...)
@@ -1158,12 +1368,15 @@ func (s *ExcelService) ParseObyekRulesWithValidation(filePath string) (*models.O
 		} else {
 			// Create valid rule
 			rule := models.ObyekRule{
-				Keyword:  keyword,
-				Value:    value,
-				NotValue: sql.NullString{String: notValue, Valid: notValue != ""},
-				IsActive: parseBoolValue(isActiveStr),
+				Keyword:    keyword,
+				Value:      value,
+				NotValue:   sql.NullString{String: notValue, Valid: notValue != ""},
+				Conditions: parseConditionsCell(conditionsStr),
+				Priority:   parsePriorityValue(priorityStr),
+				IsActive:   parseBoolValue(isActiveStr),
 			}
 			result.ValidRules = append(result.ValidRules, rule)
+			result.ValidRows = append(result.ValidRows, i+1)
 			result.ValidCount++
 		}
 	}
@@ -1172,16 +1385,16 @@ func (s *ExcelService) ParseObyekRulesWithValidation(filePath string) (*models.O
 }
 
 // validateObyekRuleRow validates a single obyek rule row and returns validation errors
-func (s *ExcelService) validateObyekRuleRow(rowNum int, keyword, value, notValue, isActiveStr string) []models.ObyekRuleValidationError {
+func (s *ExcelService) validateObyekRuleRow(rowNum int, keyword, value, notValue, isActiveStr, priorityStr, conditionsStr string) []models.ObyekRuleValidationError {
 	var errors []models.ObyekRuleValidationError
 
-	// Validate Keyword (Required)
-	if keyword == "" {
+	// Validate Keyword (Required unless the rule has conditions)
+	if keyword == "" && conditionsStr == "" {
 		errors = append(errors, models.ObyekRuleValidationError{
 			Row:     rowNum,
 			Field:   "Keyword",
 			Value:   keyword,
-			Message: "Keyword is required",
+			Message: "Keyword is required unless Conditions are set",
 		})
 	} else if len(keyword) > 255 {
 		errors = append(errors, models.ObyekRuleValidationError{
@@ -1229,6 +1442,29 @@ func (s *ExcelService) validateObyekRuleRow(rowNum int, keyword, value, notValue
 		})
 	}
 
+	// Validate Priority (Optional, must be an integer)
+	if priorityStr != "" {
+		if _, err := strconv.Atoi(strings.TrimSpace(priorityStr)); err != nil {
+			errors = append(errors, models.ObyekRuleValidationError{
+				Row:     rowNum,
+				Field:   "Priority",
+				Value:   priorityStr,
+				Message: "Priority must be a whole number",
+			})
+		}
+	}
+
+	// Validate Conditions (Optional, JSON condition tree)
+	if conditionsStr != "" {
+		if err := validateConditionsCell(conditionsStr); err != nil {
+			errors = append(errors, models.ObyekRuleValidationError{
+				Row:     rowNum,
+				Field:   "Conditions",
+				Value:   conditionsStr,
+				Message: err.Error(),
+			})
+		}
+	}
 	return errors
 }
 
@@ -1313,3 +1549,308 @@ func (s *ExcelService) GenerateObyekRuleImportErrorReport(result *models.ObyekRu
 
 	return f.SaveAs(outputPath)
 }
+
+// ExportUmPajakRules exports UM Pajak rules to Excel file
+func (s *ExcelService) ExportUmPajakRules(rules []models.UmPajakRule, filePath string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "UM Pajak Rules"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	// Set headers
+	headers := []string{
+		"Keyword", "Account Pattern", "Debit Condition", "Tax Type", "Is Active", "Priority",
+	}
+
+	for i, header := range headers {
+		cell := fmt.Sprintf("%s1", getColumnName(i))
+		f.SetCellValue(sheetName, cell, header)
+	}
+
+	// Set header style
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+	})
+	f.SetCellStyle(sheetName, "A1", fmt.Sprintf("%s1", getColumnName(len(headers)-1)), headerStyle)
+
+	// Write data
+	for i, rule := range rules {
+		row := i + 2
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), rule.Keyword)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), getNullStringValue(rule.AccountPattern))
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), rule.DebitCondition)
+		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), getNullStringValue(rule.TaxType))
+
+		isActiveStr := "No"
+		if rule.IsActive {
+			isActiveStr = "Yes"
+		}
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), isActiveStr)
+		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), rule.Priority)
+	}
+
+	// Set column widths
+	f.SetColWidth(sheetName, "A", "A", 30)
+	f.SetColWidth(sheetName, "B", "B", 20)
+	f.SetColWidth(sheetName, "C", "C", 18)
+	f.SetColWidth(sheetName, "D", "D", 15)
+	f.SetColWidth(sheetName, "E", "E", 12)
+	f.SetColWidth(sheetName, "F", "F", 12)
+
+	f.SetActiveSheet(index)
+	f.DeleteSheet("Sheet1")
+
+	return f.SaveAs(filePath)
+}
+
+// ParseUmPajakRulesWithValidation parses an Excel file and returns detailed validation result
+func (s *ExcelService) ParseUmPajakRulesWithValidation(filePath string) (*models.UmPajakRuleImportResult, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer f.Close()
+
+	// Get first sheet
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("no sheets found in Excel file")
+	}
+
+	sheetName := sheets[0]
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("file must contain at least header row and one data row")
+	}
+
+	// Validate headers (Priority is optional)
+	expectedHeaders := []string{
+		"Keyword", "Account Pattern", "Debit Condition", "Tax Type", "Is Active",
+	}
+
+	header := rows[0]
+	if len(header) < len(expectedHeaders) {
+		return nil, fmt.Errorf("invalid header format. Expected columns: %v", expectedHeaders)
+	}
+
+	result := &models.UmPajakRuleImportResult{
+		ValidRules:       []models.UmPajakRule{},
+		ValidationErrors: []models.UmPajakRuleValidationError{},
+		TotalRows:        len(rows) - 1,
+		ValidCount:       0,
+		ErrorCount:       0,
+		ImportTime:       time.Now(),
+	}
+
+	// Process data rows
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+
+		// Skip completely empty rows
+		if len(row) == 0 || (len(row) > 0 && row[0] == "") {
+			continue
+		}
+
+		// Extract values
+		keyword := getStringValue(row, 0)
+		accountPattern := strings.TrimSpace(getStringValue(row, 1))
+		debitCondition := getStringValue(row, 2)
+		taxType := getStringValue(row, 3)
+		isActiveStr := getStringValue(row, 4)
+		priorityStr := getStringValue(row, 5)
+
+		// Validate fields
+		rowErrors := s.validateUmPajakRuleRow(i+1, keyword, accountPattern, debitCondition, taxType, isActiveStr, priorityStr)
+
+		if len(rowErrors) > 0 {
+			result.ValidationErrors = append(result.ValidationErrors, rowErrors...)
+			result.ErrorCount++
+		} else {
+			// Create valid rule
+			condition, _ := NormalizeDebitCondition(debitCondition)
+			rule := models.UmPajakRule{
+				Keyword:        keyword,
+				AccountPattern: sql.NullString{String: accountPattern, Valid: accountPattern != ""},
+				DebitCondition: condition,
+				TaxType:        sql.NullString{String: taxType, Valid: taxType != ""},
+				Priority:       parsePriorityValue(priorityStr),
+				IsActive:       parseBoolValue(isActiveStr),
+			}
+			result.ValidRules = append(result.ValidRules, rule)
+			result.ValidCount++
+		}
+	}
+
+	return result, nil
+}
+
+// validateUmPajakRuleRow validates a single UM Pajak rule row and returns validation errors
+func (s *ExcelService) validateUmPajakRuleRow(rowNum int, keyword, accountPattern, debitCondition, taxType, isActiveStr, priorityStr string) []models.UmPajakRuleValidationError {
+	var errors []models.UmPajakRuleValidationError
+
+	// Validate Keyword (Required)
+	if keyword == "" {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Keyword",
+			Value:   keyword,
+			Message: "Keyword is required",
+		})
+	} else if len(keyword) > 255 {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Keyword",
+			Value:   keyword,
+			Message: "Keyword cannot exceed 255 characters",
+		})
+	}
+
+	// Validate Account Pattern (Optional, must be a valid glob)
+	if len(accountPattern) > 100 {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Account Pattern",
+			Value:   accountPattern,
+			Message: "Account Pattern cannot exceed 100 characters",
+		})
+	} else if err := ValidateAccountPattern(accountPattern); err != nil {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Account Pattern",
+			Value:   accountPattern,
+			Message: "Account Pattern must be a valid pattern, e.g. 1151*",
+		})
+	}
+
+	// Validate Debit Condition (Optional, defaults to positive)
+	if _, err := NormalizeDebitCondition(debitCondition); err != nil {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Debit Condition",
+			Value:   debitCondition,
+			Message: "Debit Condition must be positive, negative or nonzero",
+		})
+	}
+
+	// Validate Tax Type (Optional)
+	if len(taxType) > 50 {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Tax Type",
+			Value:   taxType,
+			Message: "Tax Type cannot exceed 50 characters",
+		})
+	}
+
+	// Validate Is Active (Optional, must be boolean-like)
+	if isActiveStr != "" && !parseBoolValue(isActiveStr) && !isBooleanLike(isActiveStr) {
+		errors = append(errors, models.UmPajakRuleValidationError{
+			Row:     rowNum,
+			Field:   "Is Active",
+			Value:   isActiveStr,
+			Message: "Is Active must be Yes/No, Y/N, 1/0, or true/false",
+		})
+	}
+
+	// Validate Priority (Optional, must be an integer)
+	if priorityStr != "" {
+		if _, err := strconv.Atoi(strings.TrimSpace(priorityStr)); err != nil {
+			errors = append(errors, models.UmPajakRuleValidationError{
+				Row:     rowNum,
+				Field:   "Priority",
+				Value:   priorityStr,
+				Message: "Priority must be a whole number",
+			})
+		}
+	}
+
+	return errors
+}
+
+// GenerateUmPajakRuleImportErrorReport creates an Excel report with import validation errors
+func (s *ExcelService) GenerateUmPajakRuleImportErrorReport(result *models.UmPajakRuleImportResult, outputPath string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Import Errors"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return err
+	}
+
+	// Set headers
+	headers := []string{
+		"Row Number", "Field", "Error Message", "Invalid Value",
+	}
+
+	// Write headers
+	for i, header := range headers {
+		cell := fmt.Sprintf("%s1", getColumnName(i))
+		f.SetCellValue(sheetName, cell, header)
+	}
+
+	// Set header style
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFE6E6"}, Pattern: 1},
+	})
+	f.SetCellStyle(sheetName, "A1", fmt.Sprintf("%s1", getColumnName(len(headers)-1)), headerStyle)
+
+	// Set error row style
+	errorStyle, _ := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFFFCC"}, Pattern: 1},
+	})
+
+	// Write error data
+	for rowIdx, validationError := range result.ValidationErrors {
+		row := rowIdx + 2
+		values := []interface{}{
+			validationError.Row,
+			validationError.Field,
+			validationError.Message,
+			validationError.Value,
+		}
+
+		for colIdx, value := range values {
+			cell := fmt.Sprintf("%s%d", getColumnName(colIdx), row)
+			f.SetCellValue(sheetName, cell, value)
+		}
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", getColumnName(len(headers)-1), row), errorStyle)
+	}
+
+	// Set column widths
+	f.SetColWidth(sheetName, "A", "A", 12)
+	f.SetColWidth(sheetName, "B", "B", 20)
+	f.SetColWidth(sheetName, "C", "C", 50)
+	f.SetColWidth(sheetName, "D", "D", 25)
+
+	// Add summary section
+	summaryStartRow := len(result.ValidationErrors) + 4
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", summaryStartRow), "Import Summary")
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", summaryStartRow+1), "Total Rows Processed:")
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", summaryStartRow+1), result.TotalRows)
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", summaryStartRow+2), "Valid Rules:")
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", summaryStartRow+2), result.ValidCount)
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", summaryStartRow+3), "Errors Found:")
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", summaryStartRow+3), result.ErrorCount)
+
+	// Style summary section
+	summaryStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+	})
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", summaryStartRow), fmt.Sprintf("A%d", summaryStartRow), summaryStyle)
+
+	f.SetActiveSheet(index)
+	f.DeleteSheet("Sheet1")
+
+	return f.SaveAs(outputPath)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// jobStatsTTL keeps the stats of a job readable for a day after its last batch
+const jobStatsTTL = 24 * time.Hour
+
+// ErrJobNotFound is returned for a job neither asynq nor the job stats know
+var ErrJobNotFound = errors.New("job not found")
+
+// SessionProgressKey is the Redis key holding the progress percentage of a session
+func SessionProgressKey(sessionID int) string {
+	return fmt.Sprintf("processing:progress:%d", sessionID)
+}
+
+// jobStatsKey is the Redis hash with the batch counters of one processing task
+func jobStatsKey(jobID string) string {
+	return "processing:job:" + jobID
+}
+
+// JobStats records the batch counters of one processing task in Redis for the job
+// progress API. A nil JobStats records nothing.
+type JobStats struct {
+	redis *redis.Client
+	key   string
+}
+
+// StartJobStats starts the stats of the task with the given asynq task ID. Without
+// Redis or a task ID it returns nil.
+func StartJobStats(ctx context.Context, redisClient *redis.Client, jobID string, sessionID int, sessionCode string, batchSize int) *JobStats {
+	if redisClient == nil || jobID == "" {
+		return nil
+	}
+
+	s := &JobStats{redis: redisClient, key: jobStatsKey(jobID)}
+	now := time.Now().UnixMilli()
+	s.write(ctx, map[string]interface{}{
+		"session_id":   sessionID,
+		"session_code": sessionCode,
+		"batch_size":   batchSize,
+		"batch":        0,
+		"processed":    0,
+		"failed":       0,
+		"status":       "active",
+		"started_at":   now,
+		"updated_at":   now,
+	})
+	return s
+}
+
+// RecordBatch stores the number of the batch just finished and the rows the task
+// processed and failed so far
+func (s *JobStats) RecordBatch(ctx context.Context, batch, processed, failed int) {
+	if s == nil {
+		return
+	}
+	s.write(ctx, map[string]interface{}{
+		"batch":      batch,
+		"processed":  processed,
+		"failed":     failed,
+		"updated_at": time.Now().UnixMilli(),
+	})
+}
+
+// Finish stores the final status of the task
+func (s *JobStats) Finish(ctx context.Context, status string) {
+	if s == nil {
+		return
+	}
+	now := time.Now().UnixMilli()
+	s.write(ctx, map[string]interface{}{
+		"status":      status,
+		"updated_at":  now,
+		"finished_at": now,
+	})
+}
+
+func (s *JobStats) write(ctx context.Context, values map[string]interface{}) {
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, s.key, values)
+	pipe.Expire(ctx, s.key, jobStatsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record job stats %s: %v", s.key, err)
+	}
+}
+
+type JobProgressService struct {
+	inspector  *asynq.Inspector
+	redis      *redis.Client
+	uploadRepo *repository.UploadRepository
+}
+
+func NewJobProgressService(inspector *asynq.Inspector, redis *redis.Client, uploadRepo *repository.UploadRepository) *JobProgressService {
+	return &JobProgressService{
+		inspector:  inspector,
+		redis:      redis,
+		uploadRepo: uploadRepo,
+	}
+}
+
+// GetProgress combines the asynq task state, the batch counters the worker records
+// and the counters of the upload session into the progress of one job
+func (s *JobProgressService) GetProgress(ctx context.Context, jobID string) (*models.JobProgress, error) {
+	progress := &models.JobProgress{JobID: jobID, State: "unknown"}
+
+	info, err := s.findTask(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		progress.Queue = info.Queue
+		progress.Type = info.Type
+		progress.State = info.State.String()
+		progress.Retried = info.Retried
+		progress.MaxRetry = info.MaxRetry
+		progress.LastError = info.LastErr
+		progress.LastFailedAt = optionalTime(info.LastFailedAt)
+		progress.CompletedAt = optionalTime(info.CompletedAt)
+
+		var payload struct {
+			SessionID   int    `json:"session_id"`
+			SessionCode string `json:"session_code"`
+		}
+		if err := json.Unmarshal(info.Payload, &payload); err == nil {
+			progress.SessionID = payload.SessionID
+			progress.SessionCode = payload.SessionCode
+		}
+	}
+
+	stats, err := s.redis.HGetAll(ctx, jobStatsKey(jobID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job stats: %w", err)
+	}
+	if info == nil && len(stats) == 0 {
+		return nil, ErrJobNotFound
+	}
+	if len(stats) > 0 {
+		applyJobStats(progress, stats, info == nil)
+	}
+
+	if progress.SessionID > 0 {
+		session, err := s.uploadRepo.GetSessionByID(progress.SessionID)
+		if err == nil {
+			progress.SessionCode = session.SessionCode
+			progress.SessionStatus = session.Status
+			progress.TotalRows = session.TotalRows
+			progress.ProcessedRows = session.ProcessedRows
+			progress.FailedRows = session.FailedRows
+		}
+
+		value, err := s.redis.Get(ctx, SessionProgressKey(progress.SessionID)).Result()
+		if err == nil {
+			progress.ProgressPercentage, _ = strconv.ParseFloat(value, 64)
+		} else if progress.TotalRows > 0 {
+			progress.ProgressPercentage = float64(progress.ProcessedRows) / float64(progress.TotalRows) * 100
+		}
+	}
+
+	estimateJobRate(progress)
+	return progress, nil
+}
+
+// findTask looks the job up in every queue; nil means asynq no longer knows it,
+// e.g. because it completed and was removed
+func (s *JobProgressService) findTask(jobID string) (*asynq.TaskInfo, error) {
+	queues, err := s.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+
+	for _, queue := range queues {
+		info, err := s.inspector.GetTaskInfo(queue, jobID)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get task info: %w", err)
+		}
+		return info, nil
+	}
+	return nil, nil
+}
+
+// applyJobStats copies the worker's batch counters; useStatus takes the state from
+// them when asynq no longer knows the task
+func applyJobStats(progress *models.JobProgress, stats map[string]string, useStatus bool) {
+	if progress.SessionID == 0 {
+		progress.SessionID, _ = strconv.Atoi(stats["session_id"])
+		progress.SessionCode = stats["session_code"]
+	}
+	progress.CurrentBatch, _ = strconv.Atoi(stats["batch"])
+	progress.BatchSize, _ = strconv.Atoi(stats["batch_size"])
+	progress.JobProcessedRows, _ = strconv.Atoi(stats["processed"])
+	progress.StartedAt = statsTime(stats["started_at"])
+	progress.UpdatedAt = statsTime(stats["updated_at"])
+	progress.FinishedAt = statsTime(stats["finished_at"])
+	if useStatus && stats["status"] != "" {
+		progress.State = stats["status"]
+	}
+}
+
+// estimateJobRate derives rows per second from the rows this job processed since it
+// started, and the time left for the rows of the session still unprocessed
+func estimateJobRate(progress *models.JobProgress) {
+	if progress.StartedAt == nil || progress.UpdatedAt == nil || progress.JobProcessedRows == 0 {
+		return
+	}
+	elapsed := progress.UpdatedAt.Sub(*progress.StartedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	progress.RowsPerSecond = float64(progress.JobProcessedRows) / elapsed
+
+	if progress.FinishedAt != nil {
+		return
+	}
+	remaining := progress.TotalRows - progress.ProcessedRows - progress.FailedRows
+	if remaining < 0 {
+		remaining = 0
+	}
+	eta := float64(remaining) / progress.RowsPerSecond
+	progress.ETASeconds = &eta
+}
+
+func statsTime(value string) *time.Time {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || millis == 0 {
+		return nil
+	}
+	t := time.UnixMilli(millis)
+	return &t
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
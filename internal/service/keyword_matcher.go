@@ -0,0 +1,274 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// keywordMatcher is an Aho-Corasick automaton over lower-cased rule keywords.
+// It finds every keyword contained in a text in a single pass, so the cost of
+// matching a transaction no longer grows with the number of rules.
+type keywordMatcher struct {
+	patterns []string
+	ids      map[string]int32
+	nodes    []acNode
+	root     [256]int32 // dense transitions of the root node
+	emptyID  int32      // id of the "" pattern, which matches every text; -1 when absent
+	hitsPool sync.Pool
+}
+
+type acNode struct {
+	edges   []acEdge // sorted by label
+	fail    int32
+	pattern int32 // pattern ending at this node, -1 if none
+	output  int32 // nearest node on the fail chain that ends a pattern, -1 if none
+}
+
+type acEdge struct {
+	label byte
+	next  int32
+}
+
+// keywordHits is the set of patterns found in one text
+type keywordHits struct {
+	seen []bool
+	ids  []int32
+}
+
+func (h *keywordHits) has(id int32) bool {
+	return id >= 0 && int(id) < len(h.seen) && h.seen[id]
+}
+
+func newKeywordMatcher() *keywordMatcher {
+	m := &keywordMatcher{
+		ids:     map[string]int32{},
+		nodes:   []acNode{{pattern: -1, output: -1}},
+		emptyID: -1,
+	}
+	m.hitsPool.New = func() interface{} {
+		return &keywordHits{seen: make([]bool, len(m.patterns))}
+	}
+	return m
+}
+
+// add registers a keyword and returns its pattern id. Keywords are compared
+// lower-cased, and the same keyword always gets the same id.
+func (m *keywordMatcher) add(keyword string) int32 {
+	keyword = strings.ToLower(keyword)
+	if id, ok := m.ids[keyword]; ok {
+		return id
+	}
+
+	id := int32(len(m.patterns))
+	m.patterns = append(m.patterns, keyword)
+	m.ids[keyword] = id
+	if keyword == "" {
+		m.emptyID = id
+		return id
+	}
+
+	state := int32(0)
+	for i := 0; i < len(keyword); i++ {
+		next := m.child(state, keyword[i])
+		if next < 0 {
+			next = int32(len(m.nodes))
+			m.nodes = append(m.nodes, acNode{pattern: -1, output: -1})
+			m.insertEdge(state, keyword[i], next)
+		}
+		state = next
+	}
+	m.nodes[state].pattern = id
+	return id
+}
+
+func (m *keywordMatcher) insertEdge(state int32, label byte, next int32) {
+	edges := m.nodes[state].edges
+	i := sort.Search(len(edges), func(i int) bool { return edges[i].label >= label })
+	edges = append(edges, acEdge{})
+	copy(edges[i+1:], edges[i:])
+	edges[i] = acEdge{label: label, next: next}
+	m.nodes[state].edges = edges
+}
+
+// child returns the trie child of state for label, or -1
+func (m *keywordMatcher) child(state int32, label byte) int32 {
+	edges := m.nodes[state].edges
+	if len(edges) <= 8 {
+		for _, edge := range edges {
+			if edge.label == label {
+				return edge.next
+			}
+		}
+		return -1
+	}
+	i := sort.Search(len(edges), func(i int) bool { return edges[i].label >= label })
+	if i < len(edges) && edges[i].label == label {
+		return edges[i].next
+	}
+	return -1
+}
+
+// build computes the failure and output links. It must be called once after
+// all keywords have been added and before scan.
+func (m *keywordMatcher) build() {
+	for i := range m.root {
+		m.root[i] = 0
+	}
+
+	queue := make([]int32, 0, len(m.nodes))
+	for _, edge := range m.nodes[0].edges {
+		m.root[edge.label] = edge.next
+		m.nodes[edge.next].fail = 0
+		queue = append(queue, edge.next)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range m.nodes[state].edges {
+			fail := m.nodes[state].fail
+			for {
+				if next := m.step(fail, edge.label); next >= 0 || fail == 0 {
+					if next < 0 {
+						next = 0
+					}
+					m.nodes[edge.next].fail = next
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+
+			failNode := m.nodes[edge.next].fail
+			if m.nodes[failNode].pattern >= 0 {
+				m.nodes[edge.next].output = failNode
+			} else {
+				m.nodes[edge.next].output = m.nodes[failNode].output
+			}
+			queue = append(queue, edge.next)
+		}
+	}
+}
+
+// step follows a goto edge, using the dense table at the root
+func (m *keywordMatcher) step(state int32, label byte) int32 {
+	if state == 0 {
+		if next := m.root[label]; next > 0 {
+			return next
+		}
+		return -1
+	}
+	return m.child(state, label)
+}
+
+// scan returns the patterns contained in text, which must already be
+// lower-cased. The result must be handed back with release.
+func (m *keywordMatcher) scan(text string) *keywordHits {
+	hits := m.hitsPool.Get().(*keywordHits)
+	if m.emptyID >= 0 {
+		hits.mark(m.emptyID)
+	}
+
+	state := int32(0)
+	for i := 0; i < len(text); i++ {
+		label := text[i]
+		for {
+			if next := m.step(state, label); next >= 0 {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = m.nodes[state].fail
+		}
+
+		out := state
+		if m.nodes[out].pattern < 0 {
+			out = m.nodes[out].output
+		}
+		for out > 0 {
+			hits.mark(m.nodes[out].pattern)
+			out = m.nodes[out].output
+		}
+	}
+	return hits
+}
+
+func (h *keywordHits) mark(id int32) {
+	if !h.seen[id] {
+		h.seen[id] = true
+		h.ids = append(h.ids, id)
+	}
+}
+
+func (m *keywordMatcher) release(hits *keywordHits) {
+	for _, id := range hits.ids {
+		hits.seen[id] = false
+	}
+	hits.ids = hits.ids[:0]
+	m.hitsPool.Put(hits)
+}
+
+// ruleKeywordIndex maps the rules of one ordered rule list onto matcher patterns
+type ruleKeywordIndex struct {
+	variants  [][]string // normalized keyword and synonym variants of each rule
+	byPattern [][]int32  // pattern id -> indices of the rules using it, ascending
+}
+
+func newRuleKeywordIndex(m *keywordMatcher, keywords []string, synonyms *synonymExpander) *ruleKeywordIndex {
+	idx := &ruleKeywordIndex{variants: make([][]string, len(keywords))}
+	for i, keyword := range keywords {
+		idx.variants[i] = keywordVariants(keyword, synonyms)
+		for _, variant := range idx.variants[i] {
+			id := m.add(variant)
+			for int(id) >= len(idx.byPattern) {
+				idx.byPattern = append(idx.byPattern, nil)
+			}
+			if n := len(idx.byPattern[id]); n == 0 || idx.byPattern[id][n-1] != int32(i) {
+				idx.byPattern[id] = append(idx.byPattern[id], int32(i))
+			}
+		}
+	}
+	return idx
+}
+
+// candidates appends to buf the indices of the rules whose keyword, or one of its
+// synonym variants, occurs in the normalized text, in rule order. Without hits it
+// falls back to checking every variant with strings.Contains.
+func (idx *ruleKeywordIndex) candidates(keterangan string, hits *keywordHits, buf []int32) []int32 {
+	buf = buf[:0]
+	if idx == nil {
+		return buf
+	}
+	if hits == nil {
+		for i, variants := range idx.variants {
+			for _, variant := range variants {
+				if strings.Contains(keterangan, variant) {
+					buf = append(buf, int32(i))
+					break
+				}
+			}
+		}
+		return buf
+	}
+
+	for _, id := range hits.ids {
+		if int(id) < len(idx.byPattern) {
+			buf = append(buf, idx.byPattern[id]...)
+		}
+	}
+	if len(buf) > 1 {
+		sort.Slice(buf, func(i, j int) bool { return buf[i] < buf[j] })
+		// A rule found through several variants is listed once
+		unique := buf[:1]
+		for _, i := range buf[1:] {
+			if i != unique[len(unique)-1] {
+				unique = append(unique, i)
+			}
+		}
+		buf = unique
+	}
+	return buf
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// linearMatch is the matching the automaton replaced: check every keyword
+// variant of each rule against the text with strings.Contains
+func linearMatch(text string, keywords []string, synonyms *synonymExpander) []int32 {
+	var matched []int32
+	for i, keyword := range keywords {
+		for _, variant := range keywordVariants(keyword, synonyms) {
+			if strings.Contains(text, variant) {
+				matched = append(matched, int32(i))
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func automatonMatch(m *keywordMatcher, idx *ruleKeywordIndex, text string) []int32 {
+	hits := m.scan(text)
+	defer m.release(hits)
+	return append([]int32(nil), idx.candidates(text, hits, nil)...)
+}
+
+func TestKeywordMatcherMatchesLinearScan(t *testing.T) {
+	// Keywords that extend, overlap and repeat each other, plus the empty
+	// keyword which matches every row
+	keywords := []string{"", "pph", "pph 21", "h 2", "21", "sewa gedung", "biaya sewa", "PPh21", "--", "a"}
+	synonyms := newSynonymExpander([]models.KeywordSynonym{
+		{Term: "pph", Canonical: "pajak penghasilan", IsActive: true},
+		{Term: "sewa", Canonical: "rental", IsActive: true},
+		{Term: "gedung", Canonical: "bangunan", IsActive: false},
+	})
+	m := newKeywordMatcher()
+	idx := newRuleKeywordIndex(m, keywords, synonyms)
+	m.build()
+
+	rows := []string{"", "PPh21 karyawan", "Pajak Penghasilan 21", "bayar RENTAL gedung", "sewa bangunan", "ref -- 0021", "xyz"}
+	for _, row := range rows {
+		text := NormalizeKeyterm(row)
+		got := automatonMatch(m, idx, text)
+		if want := linearMatch(text, keywords, synonyms); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: automaton matched %v, linear scan %v", row, got, want)
+		}
+		if fallback := idx.candidates(text, nil, nil); !reflect.DeepEqual(got, append([]int32(nil), fallback...)) {
+			t.Errorf("%q: automaton matched %v, candidates without hits %v", row, got, fallback)
+		}
+	}
+}
+
+// Keywords over a two-letter alphabet overlap nearly everywhere, so the failure
+// links do most of the work
+func TestKeywordMatcherRandomOverlaps(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	word := func(maxLen int) string {
+		b := make([]byte, 1+rng.Intn(maxLen))
+		for i := range b {
+			b[i] = "ab"[rng.Intn(2)]
+		}
+		return string(b)
+	}
+
+	for round := 0; round < 50; round++ {
+		keywords := make([]string, 1+rng.Intn(40))
+		for i := range keywords {
+			keywords[i] = word(6)
+		}
+		m := newKeywordMatcher()
+		idx := newRuleKeywordIndex(m, keywords, nil)
+		m.build()
+
+		for row := 0; row < 20; row++ {
+			text := word(30)
+			got := automatonMatch(m, idx, text)
+			if want := linearMatch(text, keywords, nil); !reflect.DeepEqual(got, want) {
+				t.Fatalf("keywords %q, text %q: automaton matched %v, linear scan %v", keywords, text, got, want)
+			}
+		}
+	}
+}
+
+func TestKeywordMatcherAddReusesPatterns(t *testing.T) {
+	m := newKeywordMatcher()
+	first := m.add("Sewa")
+	if again := m.add("sEWA"); again != first {
+		t.Errorf("add() gave %d for the same keyword, want %d", again, first)
+	}
+	if other := m.add("sewa gedung"); other == first {
+		t.Errorf("add() reused pattern %d for a different keyword", first)
+	}
+}
+
+var benchSyllables = []string{
+	"ba", "ka", "ja", "sa", "ta", "pa", "ma", "na", "ra", "la",
+	"be", "ke", "se", "te", "pe", "me", "ne", "re", "le", "ge",
+	"bi", "ki", "si", "ti", "pi", "mi", "ni", "ri", "li", "gi",
+	"bu", "ku", "su", "tu", "pu", "mu", "nu", "ru", "lu", "gu",
+}
+
+// benchmarkKeywordData generates n rule keywords of one or two words and rows of
+// mixed-case words, most of them containing a few of the keywords
+func benchmarkKeywordData(n, rows int) ([]string, []string) {
+	rng := rand.New(rand.NewSource(1))
+	vocabulary := make([]string, n*4)
+	for i := range vocabulary {
+		var sb strings.Builder
+		for s := 2 + rng.Intn(3); s > 0; s-- {
+			sb.WriteString(benchSyllables[rng.Intn(len(benchSyllables))])
+		}
+		vocabulary[i] = sb.String()
+	}
+
+	keywords := make([]string, n)
+	for i := range keywords {
+		keywords[i] = vocabulary[rng.Intn(len(vocabulary))]
+		if rng.Intn(3) == 0 {
+			keywords[i] += " " + vocabulary[rng.Intn(len(vocabulary))]
+		}
+	}
+
+	keterangan := make([]string, rows)
+	for i := range keterangan {
+		var words []string
+		for w := 4 + rng.Intn(8); w > 0; w-- {
+			words = append(words, vocabulary[rng.Intn(len(vocabulary))])
+		}
+		for k := rng.Intn(4); k > 0; k-- {
+			words = append(words, keywords[rng.Intn(len(keywords))])
+		}
+		rng.Shuffle(len(words), func(a, b int) { words[a], words[b] = words[b], words[a] })
+		keterangan[i] = strings.ToUpper(words[0]) + " " + strings.Join(words[1:], " ")
+	}
+	return keywords, keterangan
+}
+
+var benchmarkRuleCounts = []int{100, 1000, 5000}
+
+func BenchmarkKeywordMatchLinear(b *testing.B) {
+	for _, n := range benchmarkRuleCounts {
+		keywords, rows := benchmarkKeywordData(n, 1000)
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				linearMatch(strings.ToLower(rows[i%len(rows)]), keywords, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkKeywordMatchAutomaton(b *testing.B) {
+	for _, n := range benchmarkRuleCounts {
+		keywords, rows := benchmarkKeywordData(n, 1000)
+		m := newKeywordMatcher()
+		idx := newRuleKeywordIndex(m, keywords, nil)
+		m.build()
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var buf []int32
+			for i := 0; i < b.N; i++ {
+				text := strings.ToLower(rows[i%len(rows)])
+				hits := m.scan(text)
+				buf = idx.candidates(text, hits, buf)
+				m.release(hits)
+			}
+		})
+	}
+}
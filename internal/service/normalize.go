@@ -0,0 +1,160 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxKeywordVariants caps the synonym variants a single keyword expands into
+const maxKeywordVariants = 16
+
+// accentFolds maps accented lower-case letters to their base letter
+var accentFolds = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a',
+	'ç': 'c', 'č': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ī': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'š': 's',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ž': 'z',
+}
+
+// NormalizeKeyterm folds a keterangan or keyword into the form rules are matched on:
+// accents are removed, letters lower-cased, every run of punctuation and whitespace
+// becomes a single space and a space separates letters from digits. "PPh23",
+// "PPh 23", "pph-23" and "Pph. 23" all read "pph 23".
+func NormalizeKeyterm(text string) string {
+	const (
+		other = iota
+		letter
+		digit
+	)
+
+	var b strings.Builder
+	b.Grow(len(text))
+	prev := other
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		if folded, ok := accentFolds[r]; ok {
+			r = folded
+		}
+
+		class := other
+		switch {
+		case unicode.IsLetter(r):
+			class = letter
+		case unicode.IsDigit(r):
+			class = digit
+		}
+
+		if class == other {
+			prev = other
+			continue
+		}
+		if b.Len() > 0 && class != prev {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+		prev = class
+	}
+	return b.String()
+}
+
+// synonymExpander expands keywords with the managed synonyms. Terms linked through
+// synonyms form a group whose members are interchangeable in keywords.
+type synonymExpander struct {
+	groups  map[string][]string // normalized phrase -> every phrase of its group
+	phrases []string            // keys of groups, longest first
+}
+
+func newSynonymExpander(synonyms []models.KeywordSynonym) *synonymExpander {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(p string) string {
+		if parent[p] != p {
+			parent[p] = find(parent[p])
+		}
+		return parent[p]
+	}
+	for _, s := range synonyms {
+		term, canonical := NormalizeKeyterm(s.Term), NormalizeKeyterm(s.Canonical)
+		if !s.IsActive || term == "" || canonical == "" || term == canonical {
+			continue
+		}
+		for _, p := range []string{term, canonical} {
+			if _, ok := parent[p]; !ok {
+				parent[p] = p
+			}
+		}
+		parent[find(term)] = find(canonical)
+	}
+	if len(parent) == 0 {
+		return nil
+	}
+
+	members := map[string][]string{}
+	for p := range parent {
+		root := find(p)
+		members[root] = append(members[root], p)
+	}
+	x := &synonymExpander{groups: map[string][]string{}}
+	for p := range parent {
+		group := members[find(p)]
+		sort.Strings(group)
+		x.groups[p] = group
+		x.phrases = append(x.phrases, p)
+	}
+	sort.Slice(x.phrases, func(i, j int) bool {
+		if len(x.phrases[i]) != len(x.phrases[j]) {
+			return len(x.phrases[i]) > len(x.phrases[j])
+		}
+		return x.phrases[i] < x.phrases[j]
+	})
+	return x
+}
+
+// expand returns the normalized keyword followed by its synonym variants: each whole-
+// word occurrence of a group member replaced by the other members of the group
+func (x *synonymExpander) expand(keyword string) []string {
+	variants := []string{keyword}
+	if x == nil || keyword == "" {
+		return variants
+	}
+
+	seen := map[string]bool{keyword: true}
+	for i := 0; i < len(variants) && len(variants) < maxKeywordVariants; i++ {
+		padded := " " + variants[i] + " "
+		for _, phrase := range x.phrases {
+			if !strings.Contains(padded, " "+phrase+" ") {
+				continue
+			}
+			for _, other := range x.groups[phrase] {
+				if other == phrase {
+					continue
+				}
+				variant := strings.TrimSpace(strings.ReplaceAll(padded, " "+phrase+" ", " "+other+" "))
+				if !seen[variant] && len(variants) < maxKeywordVariants {
+					seen[variant] = true
+					variants = append(variants, variant)
+				}
+			}
+		}
+	}
+	return variants
+}
+
+// keywordVariants normalizes a rule keyword and expands it with the synonyms. A
+// keyword without letters or digits is kept lower-cased so it does not turn into the
+// empty keyword, which matches every row.
+func keywordVariants(keyword string, synonyms *synonymExpander) []string {
+	normalized := NormalizeKeyterm(keyword)
+	if normalized == "" && strings.TrimSpace(keyword) != "" {
+		return []string{strings.ToLower(strings.TrimSpace(keyword))}
+	}
+	return synonyms.expand(normalized)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeKeyterm(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"PPh23", "pph 23"},
+		{"pph-23", "pph 23"},
+		{"Pph. 23", "pph 23"},
+		{"  Biaya   SEWA\tgedung ", "biaya sewa gedung"},
+		{"Café résumé", "cafe resume"},
+		{"PPN/VAT 11%", "ppn vat 11"},
+		{"INV2024A", "inv 2024 a"},
+		{"--", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeKeyterm(tt.text); got != tt.want {
+			t.Errorf("NormalizeKeyterm(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordVariants(t *testing.T) {
+	synonyms := newSynonymExpander([]models.KeywordSynonym{
+		{Term: "PPh", Canonical: "Pajak Penghasilan", IsActive: true},
+		{Term: "sewa", Canonical: "rental", IsActive: true},
+		{Term: "rent", Canonical: "rental", IsActive: true},
+		{Term: "gedung", Canonical: "bangunan", IsActive: false},
+	})
+
+	tests := []struct {
+		keyword string
+		want    []string
+	}{
+		{"PPh 21", []string{"pph 21", "pajak penghasilan 21"}},
+		{"pajak penghasilan", []string{"pajak penghasilan", "pph"}},
+		// sewa and rent share a canonical, so each brings in the other
+		{"Sewa gedung", []string{"sewa gedung", "rent gedung", "rental gedung"}},
+		{"persewaan", []string{"persewaan"}},
+		{"bangunan", []string{"bangunan"}},
+		{" -- ", []string{"--"}},
+	}
+
+	for _, tt := range tests {
+		if got := keywordVariants(tt.keyword, synonyms); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("keywordVariants(%q) = %q, want %q", tt.keyword, got, tt.want)
+		}
+	}
+	if got := keywordVariants("PPh-21", nil); !reflect.DeepEqual(got, []string{"pph 21"}) {
+		t.Errorf("keywordVariants without synonyms = %q", got)
+	}
+}
@@ -3,93 +3,431 @@ package service
 import (
 	"accounting-web/internal/models"
 	"accounting-web/internal/repository"
+	"database/sql"
 	"fmt"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// processingChunkSize is the number of rows a batch worker claims at a time
+	processingChunkSize = 64
+	// minRowsPerWorker keeps small batches from starting goroutines that have no work
+	minRowsPerWorker = 256
 )
 
 type ProcessingEngine struct {
-	accountRepo *repository.AccountRepository
-	rulesRepo   *repository.RulesRepository
-	uploadRepo  *repository.UploadRepository
+	accountRepo  *repository.AccountRepository
+	rulesRepo    *repository.RulesRepository
+	analysisRepo *repository.AdditionalAnalysisRepository
+	uploadRepo   *repository.UploadRepository
 
 	// Cached rules
 	accounts       map[string]models.Account
 	koreksiRules   []models.KoreksiRule
 	obyekRules     []models.ObyekRule
 	whtRules       []models.WithholdingTaxRule
+	whtResolution  whtResolution
 	taxKeywords    []models.TaxKeyword
-	inputTaxKeywords  []string
-	outputTaxKeywords []string
+	umPajakRules   []models.UmPajakRule
+	// additionalAnalyses holds the active analyses per account code, ordered by type then id
+	additionalAnalyses map[string][]models.AdditionalAnalysis
+	inputTaxKeywords  []models.TaxKeyword
+	outputTaxKeywords []models.TaxKeyword
+	// synonyms expands rule keywords with the managed keyword synonyms
+	synonyms *synonymExpander
+
+	// Keyword automaton and rule conditions compiled from the cached rules, see compileRules
+	matcher         *keywordMatcher
+	koreksiIndex    *ruleKeywordIndex
+	obyekIndex      *ruleKeywordIndex
+	whtIndex        *ruleKeywordIndex
+	inputTaxIndex   *ruleKeywordIndex
+	outputTaxIndex  *ruleKeywordIndex
+	umPajakIndex    *ruleKeywordIndex
+	koreksiNotTerms [][]notValueTerm
+	obyekNotTerms   [][]notValueTerm
+	// conditions per rule, parallel to the rule lists; nil means keyword only
+	koreksiConditions []ruleConditionFunc
+	obyekConditions   []ruleConditionFunc
+	whtConditions     []ruleConditionFunc
+	// validity windows on the posting date, parallel to the rule lists
+	whtWindows       []validityWindow
+	inputTaxWindows  []validityWindow
+	outputTaxWindows []validityWindow
+	// undatedAsOf is the date used for rows without a posting date (the load date)
+	undatedAsOf string
+	// linearMatching bypasses the automaton and checks every rule with strings.Contains
+	linearMatching bool
+
+	// dryRun makes ProcessBatch skip persisting results (used by rule simulation)
+	dryRun bool
+	// workers bounds the goroutines processing the rows of a batch, see SetWorkers
+	workers int
+
+	// ruleCache, when set, supplies the rules of LoadRules. rulesVersion is the version
+	// they were loaded at; ProcessBatch reloads them once the cache has seen a newer
+	// one, unless a fixed rule set was loaded with LoadRuleSet.
+	ruleCache    *RuleCache
+	rulesVersion int64
+	followsCache bool
+
+	// classifier suggests koreksi/obyek for rows no rule matched; nil disables suggestions
+	classifier *TextClassifier
+
+	// steps is the processing pipeline (see SetSteps) with the order of each step;
+	// stepCounters, parallel to steps, count their work during ProcessBatch
+	steps        []ProcessingStep
+	stepOrders   []int
+	stepCounters []stepCounter
+	// disabledClears empty the output fields of the disabled steps on every row
+	disabledClears []func(tx *models.TransactionData)
 }
 
 func NewProcessingEngine(
 	accountRepo *repository.AccountRepository,
 	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
 	uploadRepo *repository.UploadRepository,
 ) *ProcessingEngine {
-	return &ProcessingEngine{
-		accountRepo: accountRepo,
-		rulesRepo:   rulesRepo,
-		uploadRepo:  uploadRepo,
+	e := &ProcessingEngine{
+		accountRepo:  accountRepo,
+		rulesRepo:    rulesRepo,
+		analysisRepo: analysisRepo,
+		uploadRepo:   uploadRepo,
 	}
+	e.useDefaultSteps()
+	return e
 }
 
-// LoadRules loads all active rules into memory for processing
+// LoadRules loads all active rules into memory for processing, from the rule cache
+// when one is set
 func (e *ProcessingEngine) LoadRules() error {
-	// Load accounts
-	accounts, err := e.accountRepo.GetAllActive()
+	if e.ruleCache != nil {
+		snapshot, version, err := e.ruleCache.Snapshot()
+		if err != nil {
+			return err
+		}
+		e.LoadRuleSet(snapshot)
+		e.rulesVersion = version
+		e.followsCache = true
+		return nil
+	}
+
+	snapshot, err := loadActiveRuleSet(e.accountRepo, e.rulesRepo, e.analysisRepo)
 	if err != nil {
-		return fmt.Errorf("failed to load accounts: %w", err)
+		return err
 	}
-	e.accounts = make(map[string]models.Account)
-	for _, acc := range accounts {
-		e.accounts[acc.AccountCode] = acc
+	e.LoadRuleSet(snapshot)
+	return nil
+}
+
+// SetRuleCache makes LoadRules take the rules from cache and ProcessBatch reload them
+// when they change
+func (e *ProcessingEngine) SetRuleCache(cache *RuleCache) {
+	e.ruleCache = cache
+}
+
+// rulesOutdated reports whether rules loaded from the cache have changed since
+func (e *ProcessingEngine) rulesOutdated() bool {
+	return e.followsCache && e.ruleCache.Latest() != e.rulesVersion
+}
+
+// loadActiveRuleSet reads every active account and rule from the database
+func loadActiveRuleSet(
+	accountRepo *repository.AccountRepository,
+	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
+) (*models.RuleSetSnapshot, error) {
+	var err error
+	snapshot := &models.RuleSetSnapshot{}
+
+	// Load accounts
+	snapshot.Accounts, err = accountRepo.GetAllActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accounts: %w", err)
 	}
 
 	// Load koreksi rules
-	e.koreksiRules, err = e.rulesRepo.GetActiveKoreksiRules()
+	snapshot.KoreksiRules, err = rulesRepo.GetActiveKoreksiRules()
 	if err != nil {
-		return fmt.Errorf("failed to load koreksi rules: %w", err)
+		return nil, fmt.Errorf("failed to load koreksi rules: %w", err)
 	}
 
 	// Load obyek rules
-	e.obyekRules, err = e.rulesRepo.GetActiveObyekRules()
+	snapshot.ObyekRules, err = rulesRepo.GetActiveObyekRules()
 	if err != nil {
-		return fmt.Errorf("failed to load obyek rules: %w", err)
+		return nil, fmt.Errorf("failed to load obyek rules: %w", err)
 	}
 
 	// Load withholding tax rules
-	e.whtRules, err = e.rulesRepo.GetActiveWithholdingTaxRules()
+	snapshot.WithholdingTaxRules, err = rulesRepo.GetActiveWithholdingTaxRules()
 	if err != nil {
-		return fmt.Errorf("failed to load WHT rules: %w", err)
+		return nil, fmt.Errorf("failed to load WHT rules: %w", err)
 	}
 
 	// Load tax keywords
-	taxKeywords, err := e.rulesRepo.GetActiveTaxKeywords()
+	snapshot.TaxKeywords, err = rulesRepo.GetActiveTaxKeywords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tax keywords: %w", err)
+	}
+
+	// Load WHT resolution policies
+	snapshot.WHTResolutionPolicies, err = rulesRepo.GetWHTResolutionPolicies()
 	if err != nil {
-		return fmt.Errorf("failed to load tax keywords: %w", err)
+		return nil, fmt.Errorf("failed to load WHT resolution policies: %w", err)
 	}
-	e.taxKeywords = taxKeywords
+
+	// Load keyword synonyms
+	snapshot.KeywordSynonyms, err = rulesRepo.GetActiveKeywordSynonyms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyword synonyms: %w", err)
+	}
+
+	// Load UM Pajak rules
+	snapshot.UmPajakRules, err = rulesRepo.GetActiveUmPajakRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load UM Pajak rules: %w", err)
+	}
+
+	// Load additional analyses
+	snapshot.AdditionalAnalyses, err = analysisRepo.GetAllActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load additional analyses: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// LoadRuleSet replaces the cached rules with the given snapshot, e.g. a stored rule-set version
+func (e *ProcessingEngine) LoadRuleSet(snapshot *models.RuleSetSnapshot) {
+	e.followsCache = false
+	e.accounts = make(map[string]models.Account)
+	for _, acc := range snapshot.Accounts {
+		e.accounts[acc.AccountCode] = acc
+	}
+
+	e.koreksiRules = append([]models.KoreksiRule{}, snapshot.KoreksiRules...)
+	sortKoreksiRules(e.koreksiRules)
+
+	e.obyekRules = append([]models.ObyekRule{}, snapshot.ObyekRules...)
+	sortObyekRules(e.obyekRules)
+
+	e.whtRules = append([]models.WithholdingTaxRule{}, snapshot.WithholdingTaxRules...)
+	sortWithholdingTaxRules(e.whtRules)
+	e.whtResolution = newWHTResolution(snapshot.WHTResolutionPolicies)
+	e.taxKeywords = append([]models.TaxKeyword{}, snapshot.TaxKeywords...)
+
+	e.umPajakRules = append([]models.UmPajakRule{}, snapshot.UmPajakRules...)
+	sortUmPajakRules(e.umPajakRules)
+
+	e.additionalAnalyses = groupAdditionalAnalyses(snapshot.AdditionalAnalyses)
+	e.synonyms = newSynonymExpander(snapshot.KeywordSynonyms)
 
 	// Separate input and output tax keywords
-	e.inputTaxKeywords = []string{}
-	e.outputTaxKeywords = []string{}
-	for _, kw := range taxKeywords {
+	e.inputTaxKeywords = []models.TaxKeyword{}
+	e.outputTaxKeywords = []models.TaxKeyword{}
+	for _, kw := range e.taxKeywords {
 		if kw.TaxCategory == "input_tax" {
-			e.inputTaxKeywords = append(e.inputTaxKeywords, kw.Keyword)
+			e.inputTaxKeywords = append(e.inputTaxKeywords, kw)
 		} else if kw.TaxCategory == "output_tax" {
-			e.outputTaxKeywords = append(e.outputTaxKeywords, kw.Keyword)
+			e.outputTaxKeywords = append(e.outputTaxKeywords, kw)
 		}
 	}
 
-	return nil
+	e.compileRules()
+}
+
+// notValueTerm is a not_value term of a koreksi/obyek rule with its normalized synonym
+// variants and their matcher pattern ids
+type notValueTerm struct {
+	term     string
+	variants []string
+	ids      []int32
 }
 
-// ProcessTransaction processes a single transaction according to PRD rules
+// compileRules builds the keyword automaton and the rule conditions for the
+// cached rule lists. It must be called again whenever those lists change.
+func (e *ProcessingEngine) compileRules() {
+	m := newKeywordMatcher()
+
+	keywords := make([]string, len(e.koreksiRules))
+	e.koreksiNotTerms = make([][]notValueTerm, len(e.koreksiRules))
+	e.koreksiConditions = make([]ruleConditionFunc, len(e.koreksiRules))
+	for i, rule := range e.koreksiRules {
+		keywords[i] = rule.Keyword
+		e.koreksiNotTerms[i] = compileNotValue(m, rule.NotValue, e.synonyms)
+		e.koreksiConditions[i] = compileStoredCondition(rule.Conditions)
+	}
+	e.koreksiIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+
+	keywords = make([]string, len(e.obyekRules))
+	e.obyekNotTerms = make([][]notValueTerm, len(e.obyekRules))
+	e.obyekConditions = make([]ruleConditionFunc, len(e.obyekRules))
+	for i, rule := range e.obyekRules {
+		keywords[i] = rule.Keyword
+		e.obyekNotTerms[i] = compileNotValue(m, rule.NotValue, e.synonyms)
+		e.obyekConditions[i] = compileStoredCondition(rule.Conditions)
+	}
+	e.obyekIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+
+	keywords = make([]string, len(e.whtRules))
+	e.whtConditions = make([]ruleConditionFunc, len(e.whtRules))
+	e.whtWindows = make([]validityWindow, len(e.whtRules))
+	for i, rule := range e.whtRules {
+		keywords[i] = rule.Keyword
+		e.whtConditions[i] = compileStoredCondition(rule.Conditions)
+		e.whtWindows[i] = newValidityWindow(rule.ValidFrom, rule.ValidTo)
+	}
+	e.whtIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+
+	keywords = make([]string, len(e.inputTaxKeywords))
+	e.inputTaxWindows = make([]validityWindow, len(e.inputTaxKeywords))
+	for i, kw := range e.inputTaxKeywords {
+		keywords[i] = kw.Keyword
+		e.inputTaxWindows[i] = newValidityWindow(kw.ValidFrom, kw.ValidTo)
+	}
+	e.inputTaxIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+
+	keywords = make([]string, len(e.outputTaxKeywords))
+	e.outputTaxWindows = make([]validityWindow, len(e.outputTaxKeywords))
+	for i, kw := range e.outputTaxKeywords {
+		keywords[i] = kw.Keyword
+		e.outputTaxWindows[i] = newValidityWindow(kw.ValidFrom, kw.ValidTo)
+	}
+	e.outputTaxIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+	e.undatedAsOf = time.Now().Format(validityDateLayout)
+
+	keywords = make([]string, len(e.umPajakRules))
+	for i, rule := range e.umPajakRules {
+		keywords[i] = rule.Keyword
+	}
+	e.umPajakIndex = newRuleKeywordIndex(m, keywords, e.synonyms)
+
+	m.build()
+	e.matcher = m
+}
+
+// compileStoredCondition compiles the conditions of a cached rule. Conditions are
+// validated when rules are saved, so one that no longer compiles disables its rule
+// rather than letting it match on the keyword alone.
+func compileStoredCondition(condition *models.RuleCondition) ruleConditionFunc {
+	match, err := compileRuleCondition(condition)
+	if err != nil {
+		return func(*models.TransactionData) bool { return false }
+	}
+	return match
+}
+
+func compileNotValue(m *keywordMatcher, notValue sql.NullString, synonyms *synonymExpander) []notValueTerm {
+	var terms []notValueTerm
+	for _, term := range splitNotValues(notValue) {
+		t := notValueTerm{term: term, variants: keywordVariants(term, synonyms)}
+		for _, variant := range t.variants {
+			t.ids = append(t.ids, m.add(variant))
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// SetLinearMatching switches between the keyword automaton (the default) and
+// checking every rule in turn. cmd/rulebench uses it to compare both.
+func (e *ProcessingEngine) SetLinearMatching(enabled bool) {
+	e.linearMatching = enabled
+}
+
+// SetWorkers sets how many goroutines process the rows of a batch; 0 or less uses
+// one per CPU (GOMAXPROCS)
+func (e *ProcessingEngine) SetWorkers(workers int) {
+	e.workers = workers
+}
+
+// SetClassifier sets the classifier that suggests koreksi/obyek for rows the rules
+// leave empty; nil turns suggestions off
+func (e *ProcessingEngine) SetClassifier(classifier *TextClassifier) {
+	e.classifier = classifier
+}
+
+// scanKeywords runs the automaton over keterangan. It returns nil when linear
+// matching is enabled, which makes the rule indexes fall back to strings.Contains.
+func (e *ProcessingEngine) scanKeywords(keterangan string) *keywordHits {
+	if e.linearMatching || e.matcher == nil {
+		return nil
+	}
+	return e.matcher.scan(keterangan)
+}
+
+// ProcessTransaction processes a single transaction through the step pipeline
 func (e *ProcessingEngine) ProcessTransaction(tx *models.TransactionData) error {
-	keterangan := strings.ToLower(tx.Keterangan)
+	e.processTransaction(tx, nil)
+	return nil
+}
+
+// processTransaction runs the steps on tx, counting their work in counters when given
+func (e *ProcessingEngine) processTransaction(tx *models.TransactionData, counters []stepCounter) {
+	// Keywords are matched on the normalized keterangan, see NormalizeKeyterm
+	state := &StepState{
+		Keterangan:  NormalizeKeyterm(tx.Keterangan),
+		PostingDate: e.postingDateKey(tx),
+	}
+	tx.RuleProvenance = models.RuleProvenanceMap{}
+
+	state.hits = e.scanKeywords(state.Keterangan)
+	if state.hits != nil {
+		defer e.matcher.release(state.hits)
+	}
+
+	for _, clearOutputs := range e.disabledClears {
+		clearOutputs(tx)
+	}
+
+	for i, step := range e.steps {
+		var err error
+		if counters == nil {
+			err = step.Process(tx, state)
+		} else {
+			before := captureStepOutputs(tx)
+			start := time.Now()
+			err = step.Process(tx, state)
+			counters[i].duration += time.Since(start)
+			after := captureStepOutputs(tx)
+			counters[i].transactions++
+			counters[i].fieldsChanged += before.changedFields(&after)
+			if err != nil {
+				counters[i].errors++
+			}
+		}
+		if err != nil {
+			state.Warnings = append(state.Warnings, fmt.Sprintf("step %s failed: %v", step.Name(), err))
+		}
+	}
+
+	// Keep the matched/excluded decisions so reviewers can see why a rule did or didn't fire
+	if len(state.Notes) > 0 {
+		notesValue := strings.Join(state.Notes, "; ")
+		tx.ProcessingNotes = &notesValue
+	} else {
+		tx.ProcessingNotes = nil
+	}
+	if len(state.Warnings) > 0 {
+		warningValue := strings.Join(state.Warnings, "; ")
+		tx.ProcessingWarning = &warningValue
+	} else {
+		tx.ProcessingWarning = nil
+	}
+
+	// Mark as processed
+	tx.IsProcessed = true
+}
 
-	// STEP 1: Analisa Nature Akun
+// analyzeNatureAkun fills Analisa Nature Akun from the account master: its nature,
+// else its name, else the account code
+func (e *ProcessingEngine) analyzeNatureAkun(tx *models.TransactionData, state *StepState) {
 	if account, exists := e.accounts[tx.Account]; exists {
 		if account.Nature != "" {
 			tx.AnalisaNatureAkun = &account.Nature
@@ -101,157 +439,406 @@ func (e *ProcessingEngine) ProcessTransaction(tx *models.TransactionData) error
 			tx.AnalisaNatureAkun = &tx.Account
 		}
 	}
+}
 
-	// STEP 2: Koreksi - Match keterangan with koreksi_rules
-	koreksiValue := e.matchKoreksiRule(keterangan)
-	if koreksiValue != "" {
+// applyKoreksiRule matches keterangan with koreksi_rules, unless koreksi was set by hand
+func (e *ProcessingEngine) applyKoreksiRule(tx *models.TransactionData, state *StepState) {
+	if tx.KoreksiOverride {
+		state.Notes = append(state.Notes, "koreksi kept as manual override")
+		return
+	}
+	koreksiRule, koreksiNotes := e.matchKoreksiRule(tx, state.Keterangan, state.hits)
+	state.Notes = append(state.Notes, koreksiNotes...)
+	if koreksiRule != nil && koreksiRule.Value != "" {
+		koreksiValue := koreksiRule.Value
 		tx.Koreksi = &koreksiValue
+		tx.RuleProvenance["koreksi"] = models.RuleProvenance{RuleType: models.RuleTypeKoreksi, RuleID: koreksiRule.ID, Keyword: koreksiRule.Keyword}
 	}
+}
 
-	// STEP 3: Obyek - Match keterangan with obyek_rules
-	obyekValue := e.matchObyekRule(keterangan)
-	if obyekValue != "" {
+// applyObyekRule matches keterangan with obyek_rules, unless obyek was set by hand
+func (e *ProcessingEngine) applyObyekRule(tx *models.TransactionData, state *StepState) {
+	if tx.ObyekOverride {
+		state.Notes = append(state.Notes, "obyek kept as manual override")
+		return
+	}
+	obyekRule, obyekNotes := e.matchObyekRule(tx, state.Keterangan, state.hits)
+	state.Notes = append(state.Notes, obyekNotes...)
+	if obyekRule != nil && obyekRule.Value != "" {
+		obyekValue := obyekRule.Value
 		tx.Obyek = &obyekValue
+		tx.RuleProvenance["obyek"] = models.RuleProvenance{RuleType: models.RuleTypeObyek, RuleID: obyekRule.ID, Keyword: obyekRule.Keyword}
 	}
+}
 
-	// STEP 4: Analisa Koreksi - Obyek
+// combineKoreksiObyek fills Analisa Koreksi - Obyek from koreksi and obyek
+func combineKoreksiObyek(tx *models.TransactionData, separator string) {
 	if (tx.Koreksi != nil && *tx.Koreksi != "") && (tx.Obyek != nil && *tx.Obyek != "") {
-		combinedValue := fmt.Sprintf("%s - %s", *tx.Koreksi, *tx.Obyek)
+		combinedValue := *tx.Koreksi + separator + *tx.Obyek
 		tx.AnalisaKoreksiObyek = &combinedValue
 	} else if tx.Koreksi != nil && *tx.Koreksi != "" {
 		tx.AnalisaKoreksiObyek = tx.Koreksi
 	} else if tx.Obyek != nil && *tx.Obyek != "" {
 		tx.AnalisaKoreksiObyek = tx.Obyek
 	}
+}
 
-	// STEP 5: Withholding Tax (21, 23, 26, 4.2, 15)
-	e.calculateWithholdingTax(tx, keterangan)
+func (e *ProcessingEngine) applyWithholdingTax(tx *models.TransactionData, state *StepState) {
+	notes, warnings := e.calculateWithholdingTax(tx, state.Keterangan, state.hits, state.PostingDate)
+	state.Notes = append(state.Notes, notes...)
+	state.Warnings = append(state.Warnings, warnings...)
+}
 
-	// STEP 6: PM DB (Input Tax)
-	e.calculateInputTax(tx, keterangan)
+func (e *ProcessingEngine) applyInputTax(tx *models.TransactionData, state *StepState) {
+	e.calculateInputTax(tx, state.Keterangan, state.hits, state.PostingDate)
+}
 
-	// STEP 7: PK CR (Output Tax)
-	e.calculateOutputTax(tx, keterangan)
+func (e *ProcessingEngine) applyOutputTax(tx *models.TransactionData, state *StepState) {
+	e.calculateOutputTax(tx, state.Keterangan, state.hits, state.PostingDate)
+}
 
-	// STEP 8: UM Pajak DB (TBD - not implemented yet)
-	tx.UmPajakDB = models.NullableNumericFloat64{Value: 0.0, Valid: false}
+func (e *ProcessingEngine) applyPrepaidTax(tx *models.TransactionData, state *StepState) {
+	e.calculatePrepaidTax(tx, state.Keterangan, state.hits)
+}
 
-	// STEP 9: Analisa Tambahan (TBD - not implemented yet)
-	analisaTambahanValue := ""
+func (e *ProcessingEngine) applyAdditionalAnalysis(tx *models.TransactionData, state *StepState) {
+	analisaTambahanValue := e.analyzeAdditional(tx, state.Keterangan)
 	tx.AnalisaTambahan = &analisaTambahanValue
+}
 
-	// Mark as processed
-	tx.IsProcessed = true
+// matchKoreksiRule finds the first matching koreksi rule based on priority.
+// A rule whose conditions are not met, or whose not_value terms appear in the
+// keterangan, is skipped; the returned notes describe which rule matched and
+// which were excluded.
+func (e *ProcessingEngine) matchKoreksiRule(tx *models.TransactionData, keterangan string, hits *keywordHits) (*models.KoreksiRule, []string) {
+	var notes []string
+	for _, i := range e.koreksiIndex.candidates(keterangan, hits, nil) {
+		rule := &e.koreksiRules[i]
+		if match := e.koreksiConditions[i]; match != nil && !match(tx) {
+			if rule.Keyword != "" {
+				notes = append(notes, fmt.Sprintf("koreksi rule #%d %q skipped: conditions not met", rule.ID, rule.Keyword))
+			}
+			continue
+		}
+		if excludedBy := matchNotValueTerms(keterangan, hits, e.koreksiNotTerms[i]); excludedBy != "" {
+			notes = append(notes, fmt.Sprintf("koreksi rule #%d %q excluded by not_value %q", rule.ID, rule.Keyword, excludedBy))
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("koreksi rule #%d %q matched", rule.ID, rule.Keyword))
+		return rule, notes
+	}
+	return nil, notes
+}
 
-	return nil
+// matchObyekRule finds the first matching obyek rule based on priority.
+// Exclusion semantics are the same as matchKoreksiRule.
+func (e *ProcessingEngine) matchObyekRule(tx *models.TransactionData, keterangan string, hits *keywordHits) (*models.ObyekRule, []string) {
+	var notes []string
+	for _, i := range e.obyekIndex.candidates(keterangan, hits, nil) {
+		rule := &e.obyekRules[i]
+		if match := e.obyekConditions[i]; match != nil && !match(tx) {
+			if rule.Keyword != "" {
+				notes = append(notes, fmt.Sprintf("obyek rule #%d %q skipped: conditions not met", rule.ID, rule.Keyword))
+			}
+			continue
+		}
+		if excludedBy := matchNotValueTerms(keterangan, hits, e.obyekNotTerms[i]); excludedBy != "" {
+			notes = append(notes, fmt.Sprintf("obyek rule #%d %q excluded by not_value %q", rule.ID, rule.Keyword, excludedBy))
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("obyek rule #%d %q matched", rule.ID, rule.Keyword))
+		return rule, notes
+	}
+	return nil, notes
+}
+
+// sortKoreksiRules orders koreksi rules by priority (highest first), oldest rule first on ties
+func sortKoreksiRules(rules []models.KoreksiRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
 }
 
-// matchKoreksiRule finds the first matching koreksi rule based on priority
-func (e *ProcessingEngine) matchKoreksiRule(keterangan string) string {
-	for _, rule := range e.koreksiRules {
-		keyword := strings.ToLower(rule.Keyword)
-		if strings.Contains(keterangan, keyword) {
-			return rule.Value
+// sortWithholdingTaxRules orders WHT rules by priority (highest first), oldest rule first on ties
+func sortWithholdingTaxRules(rules []models.WithholdingTaxRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
 		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// sortObyekRules orders obyek rules by priority (highest first), oldest rule first on ties
+func sortObyekRules(rules []models.ObyekRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// splitNotValues splits a comma-separated not_value into lowercase, trimmed terms
+func splitNotValues(notValue sql.NullString) []string {
+	if !notValue.Valid {
+		return nil
 	}
-	return ""
+
+	var terms []string
+	for _, term := range strings.Split(notValue.String, ",") {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
 }
 
-// matchObyekRule finds the first matching obyek rule based on priority
-func (e *ProcessingEngine) matchObyekRule(keterangan string) string {
-	for _, rule := range e.obyekRules {
-		keyword := strings.ToLower(rule.Keyword)
-		if strings.Contains(keterangan, keyword) {
-			return rule.Value
+// matchNotValueTerms returns the first not_value term found in keterangan, or ""
+// if none. The automaton hits answer the lookup when available.
+func matchNotValueTerms(keterangan string, hits *keywordHits, terms []notValueTerm) string {
+	for _, t := range terms {
+		for i, variant := range t.variants {
+			if hits != nil {
+				if hits.has(t.ids[i]) {
+					return t.term
+				}
+			} else if strings.Contains(keterangan, variant) {
+				return t.term
+			}
 		}
 	}
 	return ""
 }
 
-// calculateWithholdingTax calculates all withholding taxes if credit > 0
-func (e *ProcessingEngine) calculateWithholdingTax(tx *models.TransactionData, keterangan string) {
-	if tx.Credit <= 0 {
-		return
+// postingDateKey returns the date used to pick effective-dated rules for a row
+func (e *ProcessingEngine) postingDateKey(tx *models.TransactionData) string {
+	if tx.PostingDate == nil {
+		return e.undatedAsOf
 	}
+	return tx.PostingDate.Format(validityDateLayout)
+}
 
-	// Check each WHT rule
-	for _, rule := range e.whtRules {
-		keyword := strings.ToLower(rule.Keyword)
-		if strings.Contains(keterangan, keyword) {
-			amount := tx.Credit * rule.TaxRate
-
-			switch rule.TaxType {
-			case "wth_21":
-				tx.Wth21Cr = models.NullableNumericFloat64{Value: amount, Valid: true}
-			case "wth_23":
-				tx.Wth23Cr = models.NullableNumericFloat64{Value: amount, Valid: true}
-			case "wth_26":
-				tx.Wth26Cr = models.NullableNumericFloat64{Value: amount, Valid: true}
-			case "wth_4_2":
-				tx.Wth42Cr = models.NullableNumericFloat64{Value: amount, Valid: true}
-			case "wth_15":
-				tx.Wth15Cr = models.NullableNumericFloat64{Value: amount, Valid: true}
-			}
+// calculateWithholdingTax calculates all withholding taxes using the rules effective
+// on the posting date. Each rule applies its rate to the base its calculation mode
+// selects (credit, debit, DPP or gross-up) and skips rows where that base is empty.
+// When several rules match, the resolution policies decide which apply; the notes
+// and warnings explain the rules left out.
+func (e *ProcessingEngine) calculateWithholdingTax(tx *models.TransactionData, keterangan string, hits *keywordHits, postingDate string) (notes, warnings []string) {
+	if tx.Credit <= 0 && tx.Debet <= 0 {
+		return nil, nil
+	}
+
+	var matches []int32
+	for _, i := range e.whtIndex.candidates(keterangan, hits, nil) {
+		rule := &e.whtRules[i]
+		if !e.whtWindows[i].contains(postingDate) {
+			continue
+		}
+		if whtTaxBase(rule, tx) <= 0 {
+			continue
+		}
+		if match := e.whtConditions[i]; match != nil && !match(tx) {
+			continue
+		}
+		matches = append(matches, i)
+	}
+
+	apply, notes, warnings := e.whtResolution.resolve(e.whtRules, matches)
+	for _, i := range apply {
+		rule := e.whtRules[i]
+		amount := calculateWHTAmount(&rule, tx)
+		value := models.NullableNumericFloat64{Value: amount, Valid: true}
+
+		field := ""
+		switch rule.TaxType {
+		case "wth_21":
+			tx.Wth21Cr = value
+			field = "wth_21_cr"
+		case "wth_23":
+			tx.Wth23Cr = value
+			field = "wth_23_cr"
+		case "wth_26":
+			tx.Wth26Cr = value
+			field = "wth_26_cr"
+		case "wth_4_2":
+			tx.Wth42Cr = value
+			field = "wth_4_2_cr"
+		case "wth_15":
+			tx.Wth15Cr = value
+			field = "wth_15_cr"
+		}
+		if field != "" {
+			tx.RuleProvenance[field] = models.RuleProvenance{RuleType: models.RuleTypeWHT, RuleID: rule.ID, Keyword: rule.Keyword, Mode: whtModeLabel(&rule)}
 		}
 	}
+	return notes, warnings
 }
 
 // calculateInputTax calculates PM DB (Input Tax)
-func (e *ProcessingEngine) calculateInputTax(tx *models.TransactionData, keterangan string) {
+func (e *ProcessingEngine) calculateInputTax(tx *models.TransactionData, keterangan string, hits *keywordHits, postingDate string) {
 	if tx.Debet <= 0 {
 		tx.PmDB = models.NullableNumericFloat64{Value: 0.0, Valid: false}
 		return
 	}
 
-	// Check if keterangan contains input tax keyword
-	for _, keyword := range e.inputTaxKeywords {
-		keywordLower := strings.ToLower(keyword)
-		if strings.Contains(keterangan, keywordLower) {
-			tx.PmDB = models.NullableNumericFloat64{Value: tx.Debet, Valid: true}
-			return
+	// Check if keterangan contains an input tax keyword effective on the posting date
+	for _, i := range e.inputTaxIndex.candidates(keterangan, hits, nil) {
+		if !e.inputTaxWindows[i].contains(postingDate) {
+			continue
 		}
+		keyword := e.inputTaxKeywords[i]
+		tx.PmDB = models.NullableNumericFloat64{Value: tx.Debet, Valid: true}
+		tx.RuleProvenance["pm_db"] = models.RuleProvenance{RuleType: models.RuleTypeTaxKeyword, RuleID: keyword.ID, Keyword: keyword.Keyword}
+		return
 	}
 
 	tx.PmDB = models.NullableNumericFloat64{Value: 0.0, Valid: false}
 }
 
 // calculateOutputTax calculates PK CR (Output Tax)
-func (e *ProcessingEngine) calculateOutputTax(tx *models.TransactionData, keterangan string) {
+func (e *ProcessingEngine) calculateOutputTax(tx *models.TransactionData, keterangan string, hits *keywordHits, postingDate string) {
 	if tx.Credit <= 0 {
 		tx.PkCr = models.NullableNumericFloat64{Value: 0.0, Valid: false}
 		return
 	}
 
-	// Check if keterangan contains output tax keyword
-	for _, keyword := range e.outputTaxKeywords {
-		keywordLower := strings.ToLower(keyword)
-		if strings.Contains(keterangan, keywordLower) {
-			tx.PkCr = models.NullableNumericFloat64{Value: tx.Credit, Valid: true}
-			return
+	// Check if keterangan contains an output tax keyword effective on the posting date
+	for _, i := range e.outputTaxIndex.candidates(keterangan, hits, nil) {
+		if !e.outputTaxWindows[i].contains(postingDate) {
+			continue
 		}
+		keyword := e.outputTaxKeywords[i]
+		tx.PkCr = models.NullableNumericFloat64{Value: tx.Credit, Valid: true}
+		tx.RuleProvenance["pk_cr"] = models.RuleProvenance{RuleType: models.RuleTypeTaxKeyword, RuleID: keyword.ID, Keyword: keyword.Keyword}
+		return
 	}
 
 	tx.PkCr = models.NullableNumericFloat64{Value: 0.0, Valid: false}
 }
 
-// ProcessBatch processes a batch of transactions
+// calculatePrepaidTax fills UM Pajak DB with the debit amount when a UM Pajak rule matches
+func (e *ProcessingEngine) calculatePrepaidTax(tx *models.TransactionData, keterangan string, hits *keywordHits) {
+	for _, i := range e.umPajakIndex.candidates(keterangan, hits, nil) {
+		rule := &e.umPajakRules[i]
+		if umPajakRuleApplies(rule, tx) {
+			tx.UmPajakDB = models.NullableNumericFloat64{Value: tx.Debet, Valid: true}
+			tx.RuleProvenance["um_pajak_db"] = models.RuleProvenance{RuleType: models.RuleTypeUmPajak, RuleID: rule.ID, Keyword: rule.Keyword}
+			return
+		}
+	}
+
+	tx.UmPajakDB = models.NullableNumericFloat64{Value: 0.0, Valid: false}
+}
+
+// ProcessBatch processes a batch of transactions and stores the results
 func (e *ProcessingEngine) ProcessBatch(transactions []models.TransactionData) error {
-	// Load rules if not already loaded
-	if e.koreksiRules == nil || e.obyekRules == nil {
+	// Load rules if not already loaded, or reload them when they changed
+	if e.koreksiRules == nil || e.obyekRules == nil || e.rulesOutdated() {
 		if err := e.LoadRules(); err != nil {
 			return err
 		}
 	}
 
-	// Process each transaction
-	for i := range transactions {
-		if err := e.ProcessTransaction(&transactions[i]); err != nil {
-			errMsg := err.Error()
-			transactions[i].ProcessingError = &errMsg
-			continue
-		}
+	e.ProcessTransactions(transactions)
+
+	if e.dryRun {
+		return nil
 	}
 
 	// Bulk update transactions
 	return e.uploadRepo.BulkUpdateTransactions(transactions)
 }
+
+// ProcessTransactions processes the rows in place with a bounded pool of goroutines
+// (see SetWorkers) and counts the work of every step for StepStats. The cached rules
+// are only read while processing, so the rows are independent of each other.
+func (e *ProcessingEngine) ProcessTransactions(transactions []models.TransactionData) {
+	workers := e.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if max := (len(transactions) + minRowsPerWorker - 1) / minRowsPerWorker; workers > max {
+		workers = max
+	}
+
+	e.stepCounters = make([]stepCounter, len(e.steps))
+	if workers <= 1 {
+		for i := range transactions {
+			e.processTransaction(&transactions[i], e.stepCounters)
+		}
+		return
+	}
+
+	// Workers claim chunks of rows through a shared cursor and count into their own
+	// counters, which are summed once all rows are done
+	var next int64
+	counters := make([][]stepCounter, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		counters[w] = make([]stepCounter, len(e.steps))
+		wg.Add(1)
+		go func(counters []stepCounter) {
+			defer wg.Done()
+			for {
+				end := int(atomic.AddInt64(&next, processingChunkSize))
+				start := end - processingChunkSize
+				if start >= len(transactions) {
+					return
+				}
+				if end > len(transactions) {
+					end = len(transactions)
+				}
+				for i := start; i < end; i++ {
+					e.processTransaction(&transactions[i], counters)
+				}
+			}
+		}(counters[w])
+	}
+	wg.Wait()
+
+	for _, workerCounters := range counters {
+		for i, c := range workerCounters {
+			e.stepCounters[i].transactions += c.transactions
+			e.stepCounters[i].fieldsChanged += c.fieldsChanged
+			e.stepCounters[i].errors += c.errors
+			e.stepCounters[i].duration += c.duration
+		}
+	}
+}
+
+// resetOutputFields clears every rule-derived output so a transaction can be
+// evaluated from scratch. Manually overridden koreksi/obyek values are kept.
+func resetOutputFields(tx *models.TransactionData) {
+	tx.AnalisaNatureAkun = nil
+	tx.AnalisaKoreksiObyek = nil
+	if !tx.KoreksiOverride {
+		tx.Koreksi = nil
+	}
+	if !tx.ObyekOverride {
+		tx.Obyek = nil
+	}
+	tx.UmPajakDB = models.NullableNumericFloat64{}
+	tx.PmDB = models.NullableNumericFloat64{}
+	tx.Wth21Cr = models.NullableNumericFloat64{}
+	tx.Wth23Cr = models.NullableNumericFloat64{}
+	tx.Wth26Cr = models.NullableNumericFloat64{}
+	tx.Wth42Cr = models.NullableNumericFloat64{}
+	tx.Wth15Cr = models.NullableNumericFloat64{}
+	tx.PkCr = models.NullableNumericFloat64{}
+	tx.AnalisaTambahan = nil
+	tx.ProcessingError = nil
+	tx.ProcessingNotes = nil
+	tx.ProcessingWarning = nil
+	tx.RuleProvenance = nil
+	tx.SuggestedKoreksi = nil
+	tx.SuggestedKoreksiConfidence = nil
+	tx.SuggestedObyek = nil
+	tx.SuggestedObyekConfidence = nil
+	tx.SuggestionModelVersion = nil
+	tx.IsProcessed = false
+}
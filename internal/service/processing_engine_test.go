@@ -0,0 +1,97 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+var benchmarkWHTTypes = []string{"wth_21", "wth_23", "wth_26", "wth_4_2", "wth_15"}
+
+// benchmarkBatch builds a rule set of n rules per rule type over the benchmark
+// keywords, and rows whose keterangan contain a few of them
+func benchmarkBatch(n, rows int) (*models.RuleSetSnapshot, []models.TransactionData) {
+	keywords, keterangan := benchmarkKeywordData(n, rows)
+
+	snapshot := &models.RuleSetSnapshot{}
+	for i, keyword := range keywords {
+		id := i + 1
+		snapshot.KoreksiRules = append(snapshot.KoreksiRules, models.KoreksiRule{
+			ID: id, Keyword: keyword, Value: fmt.Sprintf("K%d", id), Priority: i % 10, IsActive: true,
+		})
+		snapshot.ObyekRules = append(snapshot.ObyekRules, models.ObyekRule{
+			ID: id, Keyword: keywords[(i+1)%len(keywords)], Value: fmt.Sprintf("O%d", id), Priority: i % 7, IsActive: true,
+		})
+		snapshot.WithholdingTaxRules = append(snapshot.WithholdingTaxRules, models.WithholdingTaxRule{
+			ID: id, Keyword: keywords[(i+2)%len(keywords)], TaxType: benchmarkWHTTypes[i%len(benchmarkWHTTypes)], TaxRate: 0.02, IsActive: true,
+		})
+		category := "input_tax"
+		if i%2 == 0 {
+			category = "output_tax"
+		}
+		snapshot.TaxKeywords = append(snapshot.TaxKeywords, models.TaxKeyword{
+			ID: id, Keyword: keywords[(i+3)%len(keywords)], TaxCategory: category, IsActive: true,
+		})
+		snapshot.UmPajakRules = append(snapshot.UmPajakRules, models.UmPajakRule{
+			ID: id, Keyword: keywords[(i+4)%len(keywords)], DebitCondition: models.DebitConditionPositive, Priority: i % 5, IsActive: true,
+		})
+	}
+
+	transactions := make([]models.TransactionData, len(keterangan))
+	for i := range transactions {
+		amount := float64(1000 + i*37)
+		transactions[i] = models.TransactionData{
+			ID:         int64(i + 1),
+			Account:    fmt.Sprintf("%d", 100000+i*7),
+			Keterangan: keterangan[i],
+		}
+		if i%2 == 0 {
+			transactions[i].Debet = amount
+		} else {
+			transactions[i].Credit = amount
+		}
+	}
+	return snapshot, transactions
+}
+
+func TestProcessTransactionsWorkersMatchSequential(t *testing.T) {
+	snapshot, transactions := benchmarkBatch(200, 2000)
+	engine := NewProcessingEngine(nil, nil, nil, nil)
+	engine.LoadRuleSet(snapshot)
+
+	oneByOne := append([]models.TransactionData(nil), transactions...)
+	for i := range oneByOne {
+		engine.ProcessTransaction(&oneByOne[i])
+	}
+
+	for _, workers := range []int{1, 4} {
+		rows := append([]models.TransactionData(nil), transactions...)
+		engine.SetWorkers(workers)
+		engine.ProcessTransactions(rows)
+		if !reflect.DeepEqual(rows, oneByOne) {
+			t.Errorf("rows processed with %d workers differ from ProcessTransaction row by row", workers)
+		}
+	}
+}
+
+// BenchmarkProcessTransactions measures the throughput of one batch on a single
+// goroutine and on the worker pool
+func BenchmarkProcessTransactions(b *testing.B) {
+	snapshot, transactions := benchmarkBatch(1000, 5000)
+	engine := NewProcessingEngine(nil, nil, nil, nil)
+	engine.LoadRuleSet(snapshot)
+	batch := make([]models.TransactionData, len(transactions))
+
+	for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			engine.SetWorkers(workers)
+			for i := 0; i < b.N; i++ {
+				copy(batch, transactions)
+				engine.ProcessTransactions(batch)
+			}
+			b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
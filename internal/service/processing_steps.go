@@ -0,0 +1,439 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProcessingStep is one stage of the processing pipeline. Steps run in order on every
+// row and share the StepState of that row.
+type ProcessingStep interface {
+	Name() string
+	// Process fills the row's output fields. An error is recorded as a warning on the
+	// row; the remaining steps still run.
+	Process(tx *models.TransactionData, state *StepState) error
+}
+
+// StepState is what the steps of one row share
+type StepState struct {
+	// Keterangan is the normalized keterangan keywords are matched on, see NormalizeKeyterm
+	Keterangan string
+	// PostingDate (YYYY-MM-DD) picks the effective-dated rules
+	PostingDate string
+	// Notes and Warnings end up in processing_notes and processing_warning
+	Notes    []string
+	Warnings []string
+
+	hits *keywordHits
+}
+
+// ProcessingStepFactory builds a step for an engine from its stored JSON config
+type ProcessingStepFactory func(e *ProcessingEngine, config models.StepConfig) (ProcessingStep, error)
+
+type registeredStep struct {
+	description  string
+	defaultOrder int
+	factory      ProcessingStepFactory
+	// clear empties the output fields the step fills; it runs on every row while
+	// the step is disabled
+	clear func(tx *models.TransactionData)
+}
+
+// processingStepRegistry holds the built-in steps and those added with
+// RegisterProcessingStep
+var processingStepRegistry = map[string]registeredStep{
+	"analisa_nature_akun": {"Analisa Nature Akun from the account master", 10,
+		simpleStep("analisa_nature_akun", (*ProcessingEngine).analyzeNatureAkun), clearNatureAkun},
+	"koreksi": {"Koreksi from koreksi_rules", 20,
+		simpleStep("koreksi", (*ProcessingEngine).applyKoreksiRule), clearKoreksi},
+	"obyek": {"Obyek from obyek_rules", 30,
+		simpleStep("obyek", (*ProcessingEngine).applyObyekRule), clearObyek},
+	"analisa_koreksi_obyek": {"Analisa Koreksi - Obyek", 40, newKoreksiObyekStep, clearKoreksiObyek},
+	"withholding_tax": {"Withholding tax (21, 23, 26, 4.2, 15)", 50,
+		simpleStep("withholding_tax", (*ProcessingEngine).applyWithholdingTax), clearWithholdingTax},
+	"pm_db": {"PM DB (input tax)", 60,
+		simpleStep("pm_db", (*ProcessingEngine).applyInputTax), clearInputTax},
+	"pk_cr": {"PK CR (output tax)", 70,
+		simpleStep("pk_cr", (*ProcessingEngine).applyOutputTax), clearOutputTax},
+	"um_pajak_db": {"UM Pajak DB (prepaid tax)", 80,
+		simpleStep("um_pajak_db", (*ProcessingEngine).applyPrepaidTax), clearPrepaidTax},
+	"analisa_tambahan": {"Analisa Tambahan", 90,
+		simpleStep("analisa_tambahan", (*ProcessingEngine).applyAdditionalAnalysis), clearAdditionalAnalysis},
+	"classifier_suggestions": {"Classifier suggestions for koreksi/obyek", 100, newClassifierStep, clearSuggestions},
+}
+
+// RegisterProcessingStep adds a company-specific step. Call it from an init function;
+// the step runs at defaultOrder until the processing_steps table says otherwise.
+// The engine does not know which fields such a step fills, so disabling it leaves
+// them as they are.
+func RegisterProcessingStep(name, description string, defaultOrder int, factory ProcessingStepFactory) {
+	if _, exists := processingStepRegistry[name]; exists {
+		panic(fmt.Sprintf("processing step %q registered twice", name))
+	}
+	processingStepRegistry[name] = registeredStep{description: description, defaultOrder: defaultOrder, factory: factory}
+}
+
+// ProcessingStepSettings merges the stored step settings with the registered steps:
+// registered steps without a stored row are listed enabled at their default order.
+// Stored rows of steps that are no longer registered, such as a company-specific step
+// removed from the build, are left out. The result is in pipeline order.
+func ProcessingStepSettings(stored []models.ProcessingStep) []models.ProcessingStep {
+	settings := make([]models.ProcessingStep, 0, len(processingStepRegistry))
+	seen := map[string]bool{}
+	for _, step := range stored {
+		registered, ok := processingStepRegistry[step.Name]
+		if !ok {
+			log.Printf("Ignoring settings of unregistered processing step %q", step.Name)
+			continue
+		}
+		if step.Description == "" {
+			step.Description = registered.description
+		}
+		seen[step.Name] = true
+		settings = append(settings, step)
+	}
+	for name, registered := range processingStepRegistry {
+		if !seen[name] {
+			settings = append(settings, models.ProcessingStep{
+				Name:        name,
+				Description: registered.description,
+				Enabled:     true,
+				StepOrder:   registered.defaultOrder,
+			})
+		}
+	}
+	sort.SliceStable(settings, func(i, j int) bool {
+		if settings[i].StepOrder != settings[j].StepOrder {
+			return settings[i].StepOrder < settings[j].StepOrder
+		}
+		return settings[i].Name < settings[j].Name
+	})
+	return settings
+}
+
+// ValidateProcessingStep checks that name is a registered step and config is valid for it
+func ValidateProcessingStep(name string, config models.StepConfig) error {
+	registered, ok := processingStepRegistry[name]
+	if !ok {
+		return fmt.Errorf("unknown processing step %q", name)
+	}
+	_, err := registered.factory(&ProcessingEngine{}, config)
+	return err
+}
+
+// SetSteps builds the pipeline from the step settings; see ProcessingStepSettings.
+// Every row processed afterwards has the output fields of the disabled built-in steps
+// cleared, so values from a run where a step was still enabled do not linger.
+// Manually overridden koreksi/obyek values are kept.
+func (e *ProcessingEngine) SetSteps(stored []models.ProcessingStep) error {
+	var steps []ProcessingStep
+	var orders []int
+	var clears []func(tx *models.TransactionData)
+	for _, setting := range ProcessingStepSettings(stored) {
+		registered := processingStepRegistry[setting.Name]
+		if !setting.Enabled {
+			if registered.clear != nil {
+				clears = append(clears, registered.clear)
+			}
+			continue
+		}
+		step, err := registered.factory(e, setting.Config)
+		if err != nil {
+			return fmt.Errorf("processing step %q: %w", setting.Name, err)
+		}
+		steps = append(steps, step)
+		orders = append(orders, setting.StepOrder)
+	}
+	e.steps = steps
+	e.stepOrders = orders
+	e.disabledClears = clears
+	e.stepCounters = nil
+	return nil
+}
+
+// LoadSteps builds the pipeline from the processing_steps table
+func (e *ProcessingEngine) LoadSteps(stepRepo *repository.ProcessingStepRepository) error {
+	stored, err := stepRepo.GetSteps()
+	if err != nil {
+		return fmt.Errorf("failed to load processing steps: %w", err)
+	}
+	return e.SetSteps(stored)
+}
+
+// StepStats returns what each step did during the last ProcessBatch, in pipeline order.
+// With several workers the durations add up the time spent in each worker.
+func (e *ProcessingEngine) StepStats() []models.ProcessingStepStat {
+	stats := make([]models.ProcessingStepStat, len(e.stepCounters))
+	for i, c := range e.stepCounters {
+		stats[i] = models.ProcessingStepStat{
+			StepName:      e.steps[i].Name(),
+			StepOrder:     e.stepOrders[i],
+			Transactions:  c.transactions,
+			FieldsChanged: c.fieldsChanged,
+			Errors:        c.errors,
+			DurationMs:    float64(c.duration.Microseconds()) / 1000,
+		}
+	}
+	return stats
+}
+
+// AddStepStats adds the stats of a batch to the running totals of a run
+func AddStepStats(totals, batch []models.ProcessingStepStat) []models.ProcessingStepStat {
+	for _, stat := range batch {
+		found := false
+		for i := range totals {
+			if totals[i].StepName == stat.StepName {
+				totals[i].Transactions += stat.Transactions
+				totals[i].FieldsChanged += stat.FieldsChanged
+				totals[i].Errors += stat.Errors
+				totals[i].DurationMs += stat.DurationMs
+				found = true
+				break
+			}
+		}
+		if !found {
+			totals = append(totals, stat)
+		}
+	}
+	return totals
+}
+
+// FormatStepStats renders step stats on one line for the worker log
+func FormatStepStats(stats []models.ProcessingStepStat) string {
+	parts := make([]string, len(stats))
+	for i, stat := range stats {
+		parts[i] = fmt.Sprintf("%s %.1fms/%d fields", stat.StepName, stat.DurationMs, stat.FieldsChanged)
+		if stat.Errors > 0 {
+			parts[i] += fmt.Sprintf("/%d errors", stat.Errors)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stepCounter accumulates a step's work over a batch
+type stepCounter struct {
+	transactions  int
+	fieldsChanged int
+	errors        int
+	duration      time.Duration
+}
+
+// stepOutputs is the comparable state of a row's output fields, used to count the
+// fields a step changes
+type stepOutputs struct {
+	text    [7]string
+	amounts [8]models.NullableNumericFloat64
+}
+
+func captureStepOutputs(tx *models.TransactionData) stepOutputs {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	return stepOutputs{
+		text: [7]string{
+			str(tx.AnalisaNatureAkun), str(tx.Koreksi), str(tx.Obyek), str(tx.AnalisaKoreksiObyek),
+			str(tx.AnalisaTambahan), str(tx.SuggestedKoreksi), str(tx.SuggestedObyek),
+		},
+		amounts: [8]models.NullableNumericFloat64{
+			tx.UmPajakDB, tx.PmDB, tx.Wth21Cr, tx.Wth23Cr, tx.Wth26Cr, tx.Wth42Cr, tx.Wth15Cr, tx.PkCr,
+		},
+	}
+}
+
+// changedFields counts the fields that differ between two captures
+func (o *stepOutputs) changedFields(after *stepOutputs) int {
+	changed := 0
+	for i := range o.text {
+		if o.text[i] != after.text[i] {
+			changed++
+		}
+	}
+	for i := range o.amounts {
+		if o.amounts[i] != after.amounts[i] {
+			changed++
+		}
+	}
+	return changed
+}
+
+// useDefaultSteps sets the pipeline of every registered step at its default order
+func (e *ProcessingEngine) useDefaultSteps() {
+	if err := e.SetSteps(nil); err != nil {
+		// Built-in steps take no required config, so only a broken registration fails
+		panic(err)
+	}
+}
+
+// decodeStepConfig decodes a step's JSON config into dest, rejecting unknown fields.
+// dest nil means the step takes no config.
+func decodeStepConfig(config models.StepConfig, dest interface{}) error {
+	if config.IsEmpty() {
+		return nil
+	}
+	if dest == nil {
+		if string(bytes.TrimSpace(config)) == "{}" {
+			return nil
+		}
+		return fmt.Errorf("step takes no config")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// funcStep adapts an engine method to ProcessingStep
+type funcStep struct {
+	name    string
+	process func(tx *models.TransactionData, state *StepState) error
+}
+
+func (s *funcStep) Name() string { return s.name }
+
+func (s *funcStep) Process(tx *models.TransactionData, state *StepState) error {
+	return s.process(tx, state)
+}
+
+// simpleStep is the factory of a step without config running an engine method
+func simpleStep(name string, method func(*ProcessingEngine, *models.TransactionData, *StepState)) ProcessingStepFactory {
+	return func(e *ProcessingEngine, config models.StepConfig) (ProcessingStep, error) {
+		if err := decodeStepConfig(config, nil); err != nil {
+			return nil, err
+		}
+		return &funcStep{name: name, process: func(tx *models.TransactionData, state *StepState) error {
+			method(e, tx, state)
+			return nil
+		}}, nil
+	}
+}
+
+func clearNatureAkun(tx *models.TransactionData) {
+	tx.AnalisaNatureAkun = nil
+}
+
+func clearKoreksi(tx *models.TransactionData) {
+	if !tx.KoreksiOverride {
+		tx.Koreksi = nil
+	}
+}
+
+func clearObyek(tx *models.TransactionData) {
+	if !tx.ObyekOverride {
+		tx.Obyek = nil
+	}
+}
+
+func clearKoreksiObyek(tx *models.TransactionData) {
+	tx.AnalisaKoreksiObyek = nil
+}
+
+func clearWithholdingTax(tx *models.TransactionData) {
+	tx.Wth21Cr = models.NullableNumericFloat64{}
+	tx.Wth23Cr = models.NullableNumericFloat64{}
+	tx.Wth26Cr = models.NullableNumericFloat64{}
+	tx.Wth42Cr = models.NullableNumericFloat64{}
+	tx.Wth15Cr = models.NullableNumericFloat64{}
+}
+
+func clearInputTax(tx *models.TransactionData) {
+	tx.PmDB = models.NullableNumericFloat64{}
+}
+
+func clearOutputTax(tx *models.TransactionData) {
+	tx.PkCr = models.NullableNumericFloat64{}
+}
+
+func clearPrepaidTax(tx *models.TransactionData) {
+	tx.UmPajakDB = models.NullableNumericFloat64{}
+}
+
+func clearAdditionalAnalysis(tx *models.TransactionData) {
+	tx.AnalisaTambahan = nil
+}
+
+func clearSuggestions(tx *models.TransactionData) {
+	tx.SuggestedKoreksi = nil
+	tx.SuggestedKoreksiConfidence = nil
+	tx.SuggestedObyek = nil
+	tx.SuggestedObyekConfidence = nil
+	tx.SuggestionModelVersion = nil
+}
+
+// newKoreksiObyekStep combines koreksi and obyek; config {"separator": " - "}
+func newKoreksiObyekStep(e *ProcessingEngine, config models.StepConfig) (ProcessingStep, error) {
+	separator, err := koreksiObyekSeparatorConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &funcStep{name: "analisa_koreksi_obyek", process: func(tx *models.TransactionData, state *StepState) error {
+		combineKoreksiObyek(tx, separator)
+		return nil
+	}}, nil
+}
+
+// koreksiObyekSeparatorConfig decodes the separator of the analisa_koreksi_obyek step
+func koreksiObyekSeparatorConfig(config models.StepConfig) (string, error) {
+	cfg := struct {
+		Separator *string `json:"separator"`
+	}{}
+	if err := decodeStepConfig(config, &cfg); err != nil {
+		return "", err
+	}
+	if cfg.Separator != nil {
+		return *cfg.Separator, nil
+	}
+	return " - ", nil
+}
+
+// koreksiObyekSeparator returns the separator the analisa_koreksi_obyek step uses with
+// the stored step settings, and false when the step is disabled
+func koreksiObyekSeparator(stored []models.ProcessingStep) (string, bool, error) {
+	for _, setting := range ProcessingStepSettings(stored) {
+		if setting.Name != "analisa_koreksi_obyek" {
+			continue
+		}
+		if !setting.Enabled {
+			return "", false, nil
+		}
+		separator, err := koreksiObyekSeparatorConfig(setting.Config)
+		if err != nil {
+			return "", false, fmt.Errorf("processing step %q: %w", setting.Name, err)
+		}
+		return separator, true, nil
+	}
+	return "", false, fmt.Errorf("processing step %q is not registered", "analisa_koreksi_obyek")
+}
+
+// newClassifierStep suggests koreksi/obyek with the engine's classifier; config
+// {"min_confidence": 0.5}
+func newClassifierStep(e *ProcessingEngine, config models.StepConfig) (ProcessingStep, error) {
+	cfg := struct {
+		MinConfidence *float64 `json:"min_confidence"`
+	}{}
+	if err := decodeStepConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	minConfidence := minSuggestionConfidence
+	if cfg.MinConfidence != nil {
+		if *cfg.MinConfidence <= 0 || *cfg.MinConfidence > 1 {
+			return nil, fmt.Errorf("min_confidence must be above 0 and at most 1")
+		}
+		minConfidence = *cfg.MinConfidence
+	}
+	return &funcStep{name: "classifier_suggestions", process: func(tx *models.TransactionData, state *StepState) error {
+		e.classifier.suggest(tx, minConfidence)
+		return nil
+	}}, nil
+}
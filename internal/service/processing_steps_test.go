@@ -0,0 +1,131 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"testing"
+)
+
+// processedRow is a row carrying the outputs of every step from an earlier run
+func processedRow() models.TransactionData {
+	str := func(s string) *string { return &s }
+	amount := models.NullableNumericFloat64{Value: 20000, Valid: true}
+	return models.TransactionData{
+		Account:             "610001",
+		Keterangan:          "biaya sewa",
+		AnalisaNatureAkun:   str("Biaya Sewa"),
+		Koreksi:             str("Koreksi Positif"),
+		Obyek:               str("Sewa"),
+		AnalisaKoreksiObyek: str("Koreksi Positif - Sewa"),
+		Wth23Cr:             amount,
+		PmDB:                amount,
+		PkCr:                amount,
+		UmPajakDB:           amount,
+		AnalisaTambahan:     str("Sewa gedung"),
+		SuggestedKoreksi:    str("Koreksi Positif"),
+	}
+}
+
+func disabledSteps(names ...string) []models.ProcessingStep {
+	stored := make([]models.ProcessingStep, len(names))
+	for i, name := range names {
+		stored[i] = models.ProcessingStep{Name: name, Enabled: false, StepOrder: processingStepRegistry[name].defaultOrder}
+	}
+	return stored
+}
+
+func TestSetStepsClearsDisabledStepOutputs(t *testing.T) {
+	engine := NewProcessingEngine(nil, nil, nil, nil)
+	engine.LoadRuleSet(&models.RuleSetSnapshot{})
+
+	var names []string
+	for name := range processingStepRegistry {
+		names = append(names, name)
+	}
+	if err := engine.SetSteps(disabledSteps(names...)); err != nil {
+		t.Fatal(err)
+	}
+	tx := processedRow()
+	engine.ProcessTransaction(&tx)
+	if after := captureStepOutputs(&tx); after != (stepOutputs{}) {
+		t.Errorf("outputs after processing with every step disabled = %+v, want none", after)
+	}
+
+	// Manual overrides survive their step being disabled
+	if err := engine.SetSteps(disabledSteps("koreksi", "obyek")); err != nil {
+		t.Fatal(err)
+	}
+	tx = processedRow()
+	tx.KoreksiOverride = true
+	tx.ObyekOverride = true
+	engine.ProcessTransaction(&tx)
+	if tx.Koreksi == nil || *tx.Koreksi != "Koreksi Positif" || tx.Obyek == nil || *tx.Obyek != "Sewa" {
+		t.Errorf("koreksi %v, obyek %v: want the overridden values", tx.Koreksi, tx.Obyek)
+	}
+
+	// Enabled steps still produce their outputs
+	if err := engine.SetSteps(disabledSteps("pm_db")); err != nil {
+		t.Fatal(err)
+	}
+	tx = processedRow()
+	engine.ProcessTransaction(&tx)
+	if tx.PmDB.Valid {
+		t.Errorf("pm_db = %v, want cleared", tx.PmDB)
+	}
+	if tx.AnalisaKoreksiObyek == nil || *tx.AnalisaKoreksiObyek != "Koreksi Positif - Sewa" {
+		t.Errorf("analisa_koreksi_obyek = %v, want it combined by the enabled step", tx.AnalisaKoreksiObyek)
+	}
+}
+
+func TestSetStepsIgnoresUnregisteredSteps(t *testing.T) {
+	engine := NewProcessingEngine(nil, nil, nil, nil)
+	engine.LoadRuleSet(&models.RuleSetSnapshot{})
+
+	// Rows left behind by steps removed from the build, enabled or not
+	stored := []models.ProcessingStep{
+		{Name: "company_removed_step", Enabled: true, StepOrder: 15, Config: models.StepConfig(`{"unknown": true}`)},
+		{Name: "company_disabled_step", Enabled: false, StepOrder: 25},
+		{Name: "pm_db", Enabled: false, StepOrder: 60},
+	}
+	if err := engine.SetSteps(stored); err != nil {
+		t.Fatalf("SetSteps() error = %v", err)
+	}
+	if len(engine.steps) != len(processingStepRegistry)-1 {
+		t.Errorf("pipeline has %d steps, want the %d enabled registered steps", len(engine.steps), len(processingStepRegistry)-1)
+	}
+
+	for _, setting := range ProcessingStepSettings(stored) {
+		if _, ok := processingStepRegistry[setting.Name]; !ok {
+			t.Errorf("ProcessingStepSettings() lists unregistered step %q", setting.Name)
+		}
+	}
+}
+
+func TestKoreksiObyekSeparator(t *testing.T) {
+	step := func(enabled bool, config string) []models.ProcessingStep {
+		return []models.ProcessingStep{{Name: "analisa_koreksi_obyek", Enabled: enabled, StepOrder: 40, Config: models.StepConfig(config)}}
+	}
+
+	tests := []struct {
+		stored        []models.ProcessingStep
+		wantSeparator string
+		wantEnabled   bool
+	}{
+		{nil, " - ", true},
+		{step(true, `{"separator": " / "}`), " / ", true},
+		{step(true, `{}`), " - ", true},
+		{step(false, ""), "", false},
+	}
+	for _, tt := range tests {
+		separator, enabled, err := koreksiObyekSeparator(tt.stored)
+		if err != nil {
+			t.Fatalf("koreksiObyekSeparator() error = %v", err)
+		}
+		if separator != tt.wantSeparator || enabled != tt.wantEnabled {
+			t.Errorf("koreksiObyekSeparator() = %q, %v, want %q, %v", separator, enabled, tt.wantSeparator, tt.wantEnabled)
+		}
+	}
+
+	if _, _, err := koreksiObyekSeparator(step(true, `{"sep": "/"}`)); err == nil {
+		t.Error("an unknown config key should be rejected")
+	}
+}
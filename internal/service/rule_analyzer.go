@@ -0,0 +1,377 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleAnalyzer finds koreksi and obyek rules that overlap: rules shadowed by an
+// earlier rule whose keyword is a substring of theirs, duplicate keywords, keywords
+// excluded by a not_value list and inactive copies of other rules. Keywords and
+// not_value terms are expanded with the active keyword synonyms, as the engine does.
+type RuleAnalyzer struct {
+	rulesRepo *repository.RulesRepository
+}
+
+func NewRuleAnalyzer(rulesRepo *repository.RulesRepository) *RuleAnalyzer {
+	return &RuleAnalyzer{
+		rulesRepo: rulesRepo,
+	}
+}
+
+// analyzedRule is a koreksi or obyek rule as the analyzer sees it. Saved rules have
+// an id; rows of an import file have a row number and sort after every saved rule
+// of the same priority, as they will once inserted.
+type analyzedRule struct {
+	id         int
+	row        int
+	keyword    string   // normalized, as the engine matches it
+	variants   []string // keyword followed by its synonym variants
+	original   string
+	value      string
+	notValues  []string // every variant of the not_value terms
+	conditions *models.RuleCondition
+	priority   int
+	active     bool
+	seq        int
+}
+
+func (r *analyzedRule) label() string {
+	if r.id == 0 {
+		return fmt.Sprintf("row %d", r.row)
+	}
+	return fmt.Sprintf("rule #%d", r.id)
+}
+
+func newAnalyzedRule(id, row int, keyword, value string, notValue sql.NullString, conditions *models.RuleCondition, priority int, active bool, synonyms *synonymExpander) analyzedRule {
+	variants := keywordVariants(keyword, synonyms)
+	return analyzedRule{
+		id:         id,
+		row:        row,
+		keyword:    variants[0],
+		variants:   variants,
+		original:   keyword,
+		value:      strings.TrimSpace(value),
+		notValues:  normalizedNotValues(notValue, synonyms),
+		conditions: conditions,
+		priority:   priority,
+		active:     active,
+		seq:        id,
+	}
+}
+
+func analyzedKoreksiRules(rules []models.KoreksiRule, rows []int, synonyms *synonymExpander) []analyzedRule {
+	analyzed := make([]analyzedRule, len(rules))
+	for i, r := range rules {
+		row := 0
+		if rows != nil {
+			row = rows[i]
+		}
+		analyzed[i] = newAnalyzedRule(r.ID, row, r.Keyword, r.Value, r.NotValue, r.Conditions, r.Priority, r.IsActive, synonyms)
+	}
+	return analyzed
+}
+
+func analyzedObyekRules(rules []models.ObyekRule, rows []int, synonyms *synonymExpander) []analyzedRule {
+	analyzed := make([]analyzedRule, len(rules))
+	for i, r := range rules {
+		row := 0
+		if rows != nil {
+			row = rows[i]
+		}
+		analyzed[i] = newAnalyzedRule(r.ID, row, r.Keyword, r.Value, r.NotValue, r.Conditions, r.Priority, r.IsActive, synonyms)
+	}
+	return analyzed
+}
+
+// Analyze reports the findings over the saved rules of one type ("koreksi" or
+// "obyek"), or of both when ruleType is empty
+func (a *RuleAnalyzer) Analyze(ruleType string) (*models.RuleAnalysisReport, error) {
+	report := &models.RuleAnalysisReport{
+		Findings:   []models.RuleFinding{},
+		Summary:    map[string]int{},
+		AnalyzedAt: time.Now(),
+	}
+	synonyms, err := a.loadSynonyms()
+	if err != nil {
+		return nil, err
+	}
+
+	if ruleType == "" || ruleType == models.RuleTypeKoreksi {
+		rules, err := a.rulesRepo.GetAllKoreksiRules()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load koreksi rules: %w", err)
+		}
+		report.Findings = append(report.Findings, analyzeRuleList(models.RuleTypeKoreksi, analyzedKoreksiRules(rules, nil, synonyms))...)
+		report.RulesAnalyzed += len(rules)
+	}
+
+	if ruleType == "" || ruleType == models.RuleTypeObyek {
+		rules, err := a.rulesRepo.GetAllObyekRules()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load obyek rules: %w", err)
+		}
+		report.Findings = append(report.Findings, analyzeRuleList(models.RuleTypeObyek, analyzedObyekRules(rules, nil, synonyms))...)
+		report.RulesAnalyzed += len(rules)
+	}
+
+	for _, f := range report.Findings {
+		report.Summary[f.Kind]++
+	}
+	return report, nil
+}
+
+// AnalyzeKoreksiImport analyzes koreksi rules parsed from an import file (rows holds
+// each rule's row number) together with the saved rules, and returns the findings
+// that involve an imported row
+func (a *RuleAnalyzer) AnalyzeKoreksiImport(imported []models.KoreksiRule, rows []int) ([]models.RuleFinding, error) {
+	saved, err := a.rulesRepo.GetAllKoreksiRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load koreksi rules: %w", err)
+	}
+	synonyms, err := a.loadSynonyms()
+	if err != nil {
+		return nil, err
+	}
+	return analyzeImport(models.RuleTypeKoreksi, analyzedKoreksiRules(saved, nil, synonyms), analyzedKoreksiRules(imported, rows, synonyms)), nil
+}
+
+// AnalyzeObyekImport is AnalyzeKoreksiImport for obyek rules
+func (a *RuleAnalyzer) AnalyzeObyekImport(imported []models.ObyekRule, rows []int) ([]models.RuleFinding, error) {
+	saved, err := a.rulesRepo.GetAllObyekRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load obyek rules: %w", err)
+	}
+	synonyms, err := a.loadSynonyms()
+	if err != nil {
+		return nil, err
+	}
+	return analyzeImport(models.RuleTypeObyek, analyzedObyekRules(saved, nil, synonyms), analyzedObyekRules(imported, rows, synonyms)), nil
+}
+
+func (a *RuleAnalyzer) loadSynonyms() (*synonymExpander, error) {
+	synonyms, err := a.rulesRepo.GetActiveKeywordSynonyms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyword synonyms: %w", err)
+	}
+	return newSynonymExpander(synonyms), nil
+}
+
+func analyzeImport(ruleType string, saved, imported []analyzedRule) []models.RuleFinding {
+	maxSeq := 0
+	for _, r := range saved {
+		if r.seq > maxSeq {
+			maxSeq = r.seq
+		}
+	}
+	for i := range imported {
+		imported[i].seq = maxSeq + i + 1
+	}
+
+	var findings []models.RuleFinding
+	for _, f := range analyzeRuleList(ruleType, append(saved, imported...)) {
+		if f.Row != 0 || f.RelatedRow != 0 {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// analyzeRuleList analyzes the rules of one type. Rules are matched in priority order
+// (oldest first on ties) and the first match wins, so a rule is shadowed when each of
+// its keyword variants contains a variant of an earlier rule: every row it matches,
+// the earlier rule matches first. The shadowing is certain unless the earlier rule has
+// conditions or not_value terms that can exclude the row.
+func analyzeRuleList(ruleType string, rules []analyzedRule) []models.RuleFinding {
+	var active, inactive []*analyzedRule
+	for i := range rules {
+		if rules[i].active {
+			active = append(active, &rules[i])
+		} else {
+			inactive = append(inactive, &rules[i])
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].priority != active[j].priority {
+			return active[i].priority > active[j].priority
+		}
+		return active[i].seq < active[j].seq
+	})
+	sort.SliceStable(inactive, func(i, j int) bool { return inactive[i].seq < inactive[j].seq })
+
+	var findings []models.RuleFinding
+	finding := func(kind, severity string, rule, related *analyzedRule, message string) {
+		f := models.RuleFinding{
+			Kind:     kind,
+			Severity: severity,
+			RuleType: ruleType,
+			RuleID:   rule.id,
+			Row:      rule.row,
+			Keyword:  rule.original,
+			Message:  message,
+		}
+		if related != nil {
+			f.RelatedRuleID = related.id
+			f.RelatedRow = related.row
+			f.RelatedKeyword = related.original
+		}
+		findings = append(findings, f)
+	}
+
+	activeByKeyword := map[string][]*analyzedRule{}
+	for j, rule := range active {
+		if rule.keyword == "" {
+			continue
+		}
+		activeByKeyword[rule.keyword] = append(activeByKeyword[rule.keyword], rule)
+
+		if term := selfExcludingTerm(rule); term != "" {
+			finding(models.RuleFindingKeywordInNotValue, models.RuleFindingError, rule, nil,
+				fmt.Sprintf("never fires: its not_value %q is part of its own keyword", term))
+		}
+
+		if f := findShadowing(active[:j], rule); f != nil {
+			finding(f.kind, f.severity, rule, f.by, f.message)
+		}
+	}
+
+	// Rules whose keyword another rule excludes through its not_value list
+	for _, rule := range active {
+		for _, term := range rule.notValues {
+			for _, excluded := range activeByKeyword[term] {
+				if excluded != rule {
+					finding(models.RuleFindingKeywordInNotValue, models.RuleFindingInfo, excluded, rule,
+						fmt.Sprintf("%s skips rows containing %q through its not_value", rule.label(), term))
+				}
+			}
+		}
+	}
+
+	inactiveByKeyword := map[string]*analyzedRule{}
+	for _, rule := range inactive {
+		if rule.keyword == "" {
+			continue
+		}
+		if matches := activeByKeyword[rule.keyword]; len(matches) > 0 {
+			finding(models.RuleFindingInactiveDuplicate, models.RuleFindingInfo, rule, matches[0],
+				fmt.Sprintf("inactive copy of active %s", matches[0].label()))
+		} else if first, ok := inactiveByKeyword[rule.keyword]; ok {
+			finding(models.RuleFindingInactiveDuplicate, models.RuleFindingInfo, rule, first,
+				fmt.Sprintf("inactive copy of inactive %s", first.label()))
+		} else {
+			inactiveByKeyword[rule.keyword] = rule
+		}
+	}
+
+	return findings
+}
+
+type shadowing struct {
+	kind     string
+	severity string
+	by       *analyzedRule
+	message  string
+}
+
+// findShadowing looks for the earlier rule that takes rule's matches: an exact
+// duplicate first, then the first earlier rule that always shadows it, then the first
+// that may. It returns nil when no earlier rule covers any of rule's keyword variants.
+func findShadowing(earlier []*analyzedRule, rule *analyzedRule) *shadowing {
+	var possible *analyzedRule
+	var possibleUncovered []string
+	for _, prev := range earlier {
+		if prev.keyword == "" {
+			continue
+		}
+		uncovered := uncoveredVariants(prev, rule)
+		if len(uncovered) == len(rule.variants) {
+			continue
+		}
+
+		if prev.keyword == rule.keyword && SameRuleConditions(prev.conditions, rule.conditions) {
+			if strings.EqualFold(prev.value, rule.value) {
+				return &shadowing{models.RuleFindingDuplicate, models.RuleFindingWarning, prev,
+					fmt.Sprintf("duplicate of %s (same keyword and value)", prev.label())}
+			}
+			return &shadowing{models.RuleFindingConflict, models.RuleFindingError, prev,
+				fmt.Sprintf("same keyword as %s with a different value (%q vs %q); %s wins", prev.label(), rule.value, prev.value, prev.label())}
+		}
+
+		if len(uncovered) == 0 && prev.conditions == nil && len(prev.notValues) == 0 {
+			return &shadowing{models.RuleFindingShadowed, models.RuleFindingError, prev,
+				fmt.Sprintf("never fires: %s (%q, priority %d) matches first on every row containing %q", prev.label(), prev.original, prev.priority, rule.original)}
+		}
+		if possible == nil {
+			possible = prev
+			possibleUncovered = uncovered
+		}
+	}
+
+	if possible == nil {
+		return nil
+	}
+	if len(possibleUncovered) > 0 {
+		return &shadowing{models.RuleFindingShadowed, models.RuleFindingWarning, possible,
+			fmt.Sprintf("fires only on rows matched through %q, unless the conditions or not_value of %s (%q, priority %d) exclude the row", possibleUncovered, possible.label(), possible.original, possible.priority)}
+	}
+	return &shadowing{models.RuleFindingShadowed, models.RuleFindingWarning, possible,
+		fmt.Sprintf("fires only when the conditions or not_value of %s (%q, priority %d) exclude the row", possible.label(), possible.original, possible.priority)}
+}
+
+// uncoveredVariants returns the keyword variants of rule that prev does not take
+// first: those containing none of prev's variants, or one of prev's not_value terms
+func uncoveredVariants(prev, rule *analyzedRule) []string {
+	var uncovered []string
+	for _, variant := range rule.variants {
+		if !containsAny(variant, prev.variants) || containsAny(variant, prev.notValues) {
+			uncovered = append(uncovered, variant)
+		}
+	}
+	return uncovered
+}
+
+// selfExcludingTerm returns a not_value term of rule that is part of every one of its
+// keyword variants, so that every row the rule matches excludes it; "" if none is
+func selfExcludingTerm(rule *analyzedRule) string {
+	var term string
+	for _, variant := range rule.variants {
+		found := false
+		for _, t := range rule.notValues {
+			if strings.Contains(variant, t) {
+				if term == "" {
+					term = t
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ""
+		}
+	}
+	return term
+}
+
+func containsAny(text string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedNotValues returns the not_value terms with their synonym variants, in the
+// form the engine matches them
+func normalizedNotValues(notValue sql.NullString, synonyms *synonymExpander) []string {
+	var terms []string
+	for _, term := range splitNotValues(notValue) {
+		terms = append(terms, keywordVariants(term, synonyms)...)
+	}
+	return terms
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"database/sql"
+	"testing"
+)
+
+func TestFindShadowingWithSynonyms(t *testing.T) {
+	synonyms := newSynonymExpander([]models.KeywordSynonym{
+		{Term: "sw gdg", Canonical: "sewa gedung", IsActive: true},
+	})
+	sewa := newAnalyzedRule(1, 0, "sewa", "Koreksi Positif", sql.NullString{}, nil, 0, true, synonyms)
+	sewaGedung := newAnalyzedRule(2, 0, "sewa gedung", "Koreksi Negatif", sql.NullString{}, nil, 0, true, synonyms)
+	biayaSewa := newAnalyzedRule(3, 0, "biaya sewa", "Koreksi Negatif", sql.NullString{}, nil, 0, true, synonyms)
+
+	// "sewa gedung" still fires on rows containing its synonym "sw gdg"
+	f := findShadowing([]*analyzedRule{&sewa}, &sewaGedung)
+	if f == nil || f.kind != models.RuleFindingShadowed || f.severity != models.RuleFindingWarning {
+		t.Errorf("sewa gedung after sewa: got %+v, want a shadowed warning", f)
+	}
+
+	// Without synonyms of its own, "biaya sewa" never fires
+	f = findShadowing([]*analyzedRule{&sewa}, &biayaSewa)
+	if f == nil || f.kind != models.RuleFindingShadowed || f.severity != models.RuleFindingError {
+		t.Errorf("biaya sewa after sewa: got %+v, want a shadowed error", f)
+	}
+
+	// An earlier rule on the synonym covers both variants
+	swGdg := newAnalyzedRule(4, 0, "sw gdg", "Koreksi Positif", sql.NullString{}, nil, 0, true, synonyms)
+	f = findShadowing([]*analyzedRule{&swGdg}, &sewaGedung)
+	if f == nil || f.severity != models.RuleFindingError {
+		t.Errorf("sewa gedung after sw gdg: got %+v, want a shadowed error", f)
+	}
+}
+
+func TestSelfExcludingTermWithSynonyms(t *testing.T) {
+	synonyms := newSynonymExpander([]models.KeywordSynonym{
+		{Term: "sw gdg", Canonical: "sewa gedung", IsActive: true},
+	})
+
+	rule := newAnalyzedRule(1, 0, "sewa gedung", "Sewa", sql.NullString{String: "gedung", Valid: true}, nil, 0, true, synonyms)
+	if term := selfExcludingTerm(&rule); term != "" {
+		t.Errorf("selfExcludingTerm() = %q, want none: the rule still fires through \"sw gdg\"", term)
+	}
+
+	rule = newAnalyzedRule(2, 0, "sewa gedung", "Sewa", sql.NullString{String: "sewa gedung", Valid: true}, nil, 0, true, synonyms)
+	if term := selfExcludingTerm(&rule); term == "" {
+		t.Error("selfExcludingTerm() found nothing, want the rule's own keyword")
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ruleVersionKey counts rule and account changes; every change increments it
+	ruleVersionKey = "rules:version"
+	// ruleInvalidateChannel carries a RuleInvalidation for every change
+	ruleInvalidateChannel = "rules:invalidate"
+	// ruleCacheHoldersKey is a hash of the RuleCacheHolder of every process by instance
+	ruleCacheHoldersKey = "rules:holders"
+
+	ruleCacheHeartbeat = 30 * time.Second
+	// Holders without a heartbeat for ruleCacheStaleAfter are reported stale and are
+	// removed after ruleCacheForgetAfter
+	ruleCacheStaleAfter  = 3 * ruleCacheHeartbeat
+	ruleCacheForgetAfter = 24 * time.Hour
+)
+
+// RuleVersionNotifier bumps the rule-set version in Redis and publishes an
+// invalidation so every RuleCache reloads on its next use
+type RuleVersionNotifier struct {
+	redis *redis.Client
+	// local is the cache of this process, which sees the new version right away
+	// instead of when the message comes back over pub/sub
+	local *RuleCache
+}
+
+func NewRuleVersionNotifier(redis *redis.Client, local *RuleCache) *RuleVersionNotifier {
+	return &RuleVersionNotifier{redis: redis, local: local}
+}
+
+// Bump records a rule or account change. Without Redis it does nothing; caches then
+// load the rules on every use. Errors are logged, the change itself already happened.
+func (n *RuleVersionNotifier) Bump(reason string, changedBy *int) {
+	if n == nil || n.redis == nil {
+		return
+	}
+
+	ctx := context.Background()
+	version, err := n.redis.Incr(ctx, ruleVersionKey).Result()
+	if err != nil {
+		log.Printf("Failed to bump rule version (%s): %v", reason, err)
+		return
+	}
+	if n.local != nil {
+		n.local.observe(version)
+	}
+
+	message, err := json.Marshal(models.RuleInvalidation{Version: version, Reason: reason, ChangedBy: changedBy, At: time.Now()})
+	if err != nil {
+		log.Printf("Failed to encode rule invalidation: %v", err)
+		return
+	}
+	if err := n.redis.Publish(ctx, ruleInvalidateChannel, message).Err(); err != nil {
+		log.Printf("Failed to publish rule invalidation %d: %v", version, err)
+	}
+}
+
+// RuleCache keeps the active rules of one process in memory and reloads them when
+// the Redis rule-set version moves past the version they were loaded at. The latest
+// version arrives over pub/sub and is re-read with every heartbeat in case a message
+// was missed. Without Redis nothing is cached.
+type RuleCache struct {
+	role     string
+	instance string
+	redis    *redis.Client
+
+	accountRepo  *repository.AccountRepository
+	rulesRepo    *repository.RulesRepository
+	analysisRepo *repository.AdditionalAnalysisRepository
+
+	latest int64 // atomic
+
+	mu       sync.Mutex
+	snapshot *models.RuleSetSnapshot
+	version  int64
+	loadedAt *time.Time
+}
+
+// NewRuleCache creates the cache of a process; role tells web and worker apart in
+// the admin overview
+func NewRuleCache(
+	role string,
+	redis *redis.Client,
+	accountRepo *repository.AccountRepository,
+	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
+) *RuleCache {
+	hostname, _ := os.Hostname()
+	return &RuleCache{
+		role:         role,
+		instance:     fmt.Sprintf("%s:%s:%d", role, hostname, os.Getpid()),
+		redis:        redis,
+		accountRepo:  accountRepo,
+		rulesRepo:    rulesRepo,
+		analysisRepo: analysisRepo,
+	}
+}
+
+// Start reads the current version, subscribes to invalidations and starts the
+// heartbeat. Both stop when ctx is done.
+func (c *RuleCache) Start(ctx context.Context) {
+	if c.redis == nil {
+		return
+	}
+
+	c.refreshLatest(ctx)
+	c.reportHolder(ctx)
+
+	pubsub := c.redis.Subscribe(ctx, ruleInvalidateChannel)
+	go func() {
+		for message := range pubsub.Channel() {
+			var invalidation models.RuleInvalidation
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				log.Printf("Ignoring malformed rule invalidation: %v", err)
+				continue
+			}
+			c.observe(invalidation.Version)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(ruleCacheHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				pubsub.Close()
+				return
+			case <-ticker.C:
+				c.refreshLatest(ctx)
+				c.reportHolder(ctx)
+			}
+		}
+	}()
+}
+
+// Latest returns the newest rule-set version this process has seen
+func (c *RuleCache) Latest() int64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.latest)
+}
+
+// observe records a version seen over pub/sub or read from Redis
+func (c *RuleCache) observe(version int64) {
+	for {
+		current := atomic.LoadInt64(&c.latest)
+		if version <= current || atomic.CompareAndSwapInt64(&c.latest, current, version) {
+			return
+		}
+	}
+}
+
+func (c *RuleCache) refreshLatest(ctx context.Context) {
+	value, err := c.redis.Get(ctx, ruleVersionKey).Result()
+	if err == redis.Nil {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read rule version: %v", err)
+		return
+	}
+	if version, err := strconv.ParseInt(value, 10, 64); err == nil {
+		c.observe(version)
+	}
+}
+
+// Snapshot returns the active rules and the version they were loaded at, reloading
+// them from the database when a newer version was seen. The snapshot is shared by
+// every caller and must not be modified.
+func (c *RuleCache) Snapshot() (*models.RuleSetSnapshot, int64, error) {
+	if c.redis == nil {
+		snapshot, err := loadActiveRuleSet(c.accountRepo, c.rulesRepo, c.analysisRepo)
+		return snapshot, 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	latest := c.Latest()
+	if c.snapshot != nil && c.version == latest {
+		return c.snapshot, c.version, nil
+	}
+
+	// A change during the load bumps the version past latest, so the next call
+	// loads again
+	snapshot, err := loadActiveRuleSet(c.accountRepo, c.rulesRepo, c.analysisRepo)
+	if err != nil {
+		return nil, 0, err
+	}
+	loadedAt := time.Now()
+	c.snapshot, c.version, c.loadedAt = snapshot, latest, &loadedAt
+	log.Printf("Rule cache (%s) loaded rule-set version %d", c.role, latest)
+
+	go c.reportHolder(context.Background())
+	return c.snapshot, c.version, nil
+}
+
+// reportHolder stores the version this process holds for the admin overview
+func (c *RuleCache) reportHolder(ctx context.Context) {
+	c.mu.Lock()
+	holder := models.RuleCacheHolder{
+		Instance:      c.instance,
+		Role:          c.role,
+		HeldVersion:   c.version,
+		LatestVersion: c.Latest(),
+		LoadedAt:      c.loadedAt,
+		HeartbeatAt:   time.Now(),
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return
+	}
+	if err := c.redis.HSet(ctx, ruleCacheHoldersKey, c.instance, data).Err(); err != nil {
+		log.Printf("Failed to report rule cache version: %v", err)
+	}
+}
+
+// GetRuleCacheStatus returns the current rule-set version and the version every web
+// and worker process reported holding. Holders silent for a day are removed.
+func GetRuleCacheStatus(ctx context.Context, redisClient *redis.Client) (*models.RuleCacheStatus, error) {
+	status := &models.RuleCacheStatus{Holders: []models.RuleCacheHolder{}}
+
+	value, err := redisClient.Get(ctx, ruleVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read rule version: %w", err)
+	}
+	if value != "" {
+		status.Version, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	entries, err := redisClient.HGetAll(ctx, ruleCacheHoldersKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule cache holders: %w", err)
+	}
+	now := time.Now()
+	for instance, data := range entries {
+		var holder models.RuleCacheHolder
+		if err := json.Unmarshal([]byte(data), &holder); err != nil {
+			continue
+		}
+		silence := now.Sub(holder.HeartbeatAt)
+		if silence > ruleCacheForgetAfter {
+			redisClient.HDel(ctx, ruleCacheHoldersKey, instance)
+			continue
+		}
+		holder.Stale = silence > ruleCacheStaleAfter
+		holder.Current = holder.HeldVersion == status.Version
+		status.Holders = append(status.Holders, holder)
+	}
+	sort.Slice(status.Holders, func(i, j int) bool {
+		if status.Holders[i].Role != status.Holders[j].Role {
+			return status.Holders[i].Role < status.Holders[j].Role
+		}
+		return status.Holders[i].Instance < status.Holders[j].Instance
+	})
+	return status, nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxConditionDepth   = 10
+	maxConditionPattern = 500
+)
+
+// ruleConditionFunc reports whether a transaction satisfies a compiled rule condition
+type ruleConditionFunc func(tx *models.TransactionData) bool
+
+// ValidateRuleMatch checks what a koreksi, obyek or WHT rule matches on: a keyword,
+// structured conditions, or both
+func ValidateRuleMatch(keyword string, conditions *models.RuleCondition) error {
+	if strings.TrimSpace(keyword) == "" && conditions == nil {
+		return fmt.Errorf("keyword is required unless conditions are set")
+	}
+	if strings.TrimSpace(keyword) != "" && NormalizeKeyterm(keyword) == "" {
+		// Keywords are matched in normalized form, which keeps only letters and digits
+		return fmt.Errorf("keyword must contain letters or digits")
+	}
+	return ValidateRuleCondition(conditions)
+}
+
+// ValidateRuleCondition checks that a condition tree is well formed: known fields and
+// operators, numeric operands where needed and regular expressions that compile
+func ValidateRuleCondition(condition *models.RuleCondition) error {
+	_, err := compileRuleCondition(condition)
+	return err
+}
+
+// compileRuleCondition turns a condition tree into a matcher. A nil condition
+// compiles to nil, which callers treat as always satisfied.
+func compileRuleCondition(condition *models.RuleCondition) (ruleConditionFunc, error) {
+	if condition == nil {
+		return nil, nil
+	}
+	return compileConditionNode(condition, 1)
+}
+
+func compileConditionNode(c *models.RuleCondition, depth int) (ruleConditionFunc, error) {
+	if depth > maxConditionDepth {
+		return nil, fmt.Errorf("conditions are nested more than %d levels deep", maxConditionDepth)
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(c.All) > 0, len(c.Any) > 0, c.Not != nil, c.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("each condition must have exactly one of all, any, not or field")
+	}
+
+	switch {
+	case len(c.All) > 0:
+		children, err := compileConditionList(c.All, depth)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *models.TransactionData) bool {
+			for _, child := range children {
+				if !child(tx) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case len(c.Any) > 0:
+		children, err := compileConditionList(c.Any, depth)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *models.TransactionData) bool {
+			for _, child := range children {
+				if child(tx) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case c.Not != nil:
+		child, err := compileConditionNode(c.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *models.TransactionData) bool { return !child(tx) }, nil
+	}
+
+	return compileConditionLeaf(c)
+}
+
+func compileConditionList(conditions []models.RuleCondition, depth int) ([]ruleConditionFunc, error) {
+	children := make([]ruleConditionFunc, 0, len(conditions))
+	for i := range conditions {
+		child, err := compileConditionNode(&conditions[i], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+func compileConditionLeaf(c *models.RuleCondition) (ruleConditionFunc, error) {
+	op := strings.ToLower(strings.TrimSpace(c.Op))
+
+	switch c.Field {
+	case models.ConditionFieldAccount, models.ConditionFieldAccountName,
+		models.ConditionFieldDocumentType, models.ConditionFieldKeterangan:
+		return compileStringCondition(c.Field, op, c)
+	case models.ConditionFieldDebet, models.ConditionFieldCredit,
+		models.ConditionFieldNet, models.ConditionFieldAmount:
+		return compileNumberCondition(c.Field, op, c)
+	case models.ConditionFieldSide:
+		side := strings.ToLower(string(c.Operand))
+		if side != "debit" && side != "credit" {
+			return nil, fmt.Errorf("side must be debit or credit")
+		}
+		isSide := func(tx *models.TransactionData) bool {
+			if side == "debit" {
+				return tx.Debet != 0
+			}
+			return tx.Credit != 0
+		}
+		switch op {
+		case "eq":
+			return isSide, nil
+		case "ne":
+			return func(tx *models.TransactionData) bool { return !isSide(tx) }, nil
+		}
+		return nil, fmt.Errorf("side supports eq and ne, not %q", c.Op)
+	}
+
+	return nil, fmt.Errorf("unknown condition field %q", c.Field)
+}
+
+func stringConditionField(field string) func(tx *models.TransactionData) string {
+	switch field {
+	case models.ConditionFieldAccount:
+		return func(tx *models.TransactionData) string { return tx.Account }
+	case models.ConditionFieldAccountName:
+		return func(tx *models.TransactionData) string { return tx.AccountName }
+	case models.ConditionFieldDocumentType:
+		return func(tx *models.TransactionData) string { return tx.DocumentType }
+	}
+	return func(tx *models.TransactionData) string { return tx.Keterangan }
+}
+
+// compileStringCondition compiles a text comparison. Comparisons ignore case;
+// between compares numerically when the value and both bounds are numbers (account
+// ranges) and lexically otherwise.
+func compileStringCondition(field, op string, c *models.RuleCondition) (ruleConditionFunc, error) {
+	get := stringConditionField(field)
+	value := strings.ToLower(strings.TrimSpace(string(c.Operand)))
+
+	switch op {
+	case "eq", "ne":
+		if value == "" {
+			return nil, fmt.Errorf("%s %s needs a value", field, op)
+		}
+		negate := op == "ne"
+		return func(tx *models.TransactionData) bool {
+			return strings.EqualFold(strings.TrimSpace(get(tx)), value) != negate
+		}, nil
+	case "in", "not_in":
+		if len(c.Operands) == 0 {
+			return nil, fmt.Errorf("%s %s needs a list of values", field, op)
+		}
+		set := make(map[string]bool, len(c.Operands))
+		for _, v := range c.Operands {
+			set[strings.ToLower(strings.TrimSpace(string(v)))] = true
+		}
+		negate := op == "not_in"
+		return func(tx *models.TransactionData) bool {
+			return set[strings.ToLower(strings.TrimSpace(get(tx)))] != negate
+		}, nil
+	case "prefix":
+		if value == "" {
+			return nil, fmt.Errorf("%s prefix needs a value", field)
+		}
+		return func(tx *models.TransactionData) bool {
+			return strings.HasPrefix(strings.ToLower(strings.TrimSpace(get(tx))), value)
+		}, nil
+	case "contains":
+		if value == "" {
+			return nil, fmt.Errorf("%s contains needs a value", field)
+		}
+		return func(tx *models.TransactionData) bool {
+			return strings.Contains(strings.ToLower(get(tx)), value)
+		}, nil
+	case "regex":
+		pattern := string(c.Operand)
+		if pattern == "" {
+			return nil, fmt.Errorf("%s regex needs a pattern", field)
+		}
+		if len(pattern) > maxConditionPattern {
+			return nil, fmt.Errorf("%s regex cannot exceed %d characters", field, maxConditionPattern)
+		}
+		// Go's regexp is RE2: no backreferences or lookarounds, and matching runs in linear time
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s regex %q: %w", field, pattern, err)
+		}
+		return func(tx *models.TransactionData) bool { return re.MatchString(get(tx)) }, nil
+	case "between":
+		from := strings.ToLower(strings.TrimSpace(string(c.From)))
+		to := strings.ToLower(strings.TrimSpace(string(c.To)))
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("%s between needs from and to", field)
+		}
+		fromNum, fromErr := strconv.ParseFloat(from, 64)
+		toNum, toErr := strconv.ParseFloat(to, 64)
+		numeric := fromErr == nil && toErr == nil
+		return func(tx *models.TransactionData) bool {
+			v := strings.ToLower(strings.TrimSpace(get(tx)))
+			if numeric {
+				if n, err := strconv.ParseFloat(v, 64); err == nil {
+					return n >= fromNum && n <= toNum
+				}
+			}
+			return v >= from && v <= to
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%s supports eq, ne, in, not_in, prefix, contains, regex and between, not %q", field, c.Op)
+}
+
+func numberConditionField(field string) func(tx *models.TransactionData) float64 {
+	switch field {
+	case models.ConditionFieldDebet:
+		return func(tx *models.TransactionData) float64 { return tx.Debet }
+	case models.ConditionFieldCredit:
+		return func(tx *models.TransactionData) float64 { return tx.Credit }
+	case models.ConditionFieldNet:
+		return func(tx *models.TransactionData) float64 { return tx.Net }
+	}
+	return func(tx *models.TransactionData) float64 {
+		if tx.Debet != 0 {
+			return tx.Debet
+		}
+		return tx.Credit
+	}
+}
+
+// compileNumberCondition compiles an amount comparison
+func compileNumberCondition(field, op string, c *models.RuleCondition) (ruleConditionFunc, error) {
+	get := numberConditionField(field)
+
+	if op == "between" {
+		from, err := c.From.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%s between needs numeric from", field)
+		}
+		to, err := c.To.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%s between needs numeric to", field)
+		}
+		return func(tx *models.TransactionData) bool {
+			v := get(tx)
+			return v >= from && v <= to
+		}, nil
+	}
+
+	value, err := c.Operand.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s needs a numeric value", field, op)
+	}
+
+	var compare func(v float64) bool
+	switch op {
+	case "eq":
+		compare = func(v float64) bool { return v == value }
+	case "ne":
+		compare = func(v float64) bool { return v != value }
+	case "gt":
+		compare = func(v float64) bool { return v > value }
+	case "gte":
+		compare = func(v float64) bool { return v >= value }
+	case "lt":
+		compare = func(v float64) bool { return v < value }
+	case "lte":
+		compare = func(v float64) bool { return v <= value }
+	default:
+		return nil, fmt.Errorf("%s supports eq, ne, gt, gte, lt, lte and between, not %q", field, c.Op)
+	}
+	return func(tx *models.TransactionData) bool { return compare(get(tx)) }, nil
+}
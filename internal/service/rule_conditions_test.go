@@ -0,0 +1,125 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompileRuleConditionMatches(t *testing.T) {
+	expense := &models.TransactionData{
+		Account:      "6110",
+		AccountName:  "Biaya Entertainment",
+		DocumentType: "JV",
+		Keterangan:   "Jamuan makan klien PT ABC",
+		Debet:        1500000,
+		Net:          1500000,
+	}
+	revenue := &models.TransactionData{
+		Account:      "4100",
+		AccountName:  "Pendapatan Jasa",
+		DocumentType: "SI",
+		Keterangan:   "Invoice 2024-001",
+		Credit:       250000,
+		Net:          -250000,
+	}
+
+	tests := []struct {
+		condition string
+		tx        *models.TransactionData
+		want      bool
+	}{
+		{`{"field": "document_type", "op": "eq", "value": " jv "}`, expense, true},
+		{`{"field": "document_type", "op": "ne", "value": "JV"}`, expense, false},
+		{`{"field": "account", "op": "prefix", "value": "61"}`, revenue, false},
+		{`{"field": "keterangan", "op": "contains", "value": "JAMUAN"}`, expense, true},
+		{`{"field": "document_type", "op": "in", "values": ["jv", "RV"]}`, expense, true},
+		{`{"field": "document_type", "op": "not_in", "values": ["JV", "RV"]}`, revenue, true},
+		{`{"field": "keterangan", "op": "regex", "value": "^invoice \\d{4}-"}`, revenue, true},
+		{`{"field": "account", "op": "between", "from": 6000, "to": "6999"}`, expense, true},
+		{`{"field": "account", "op": "between", "from": "6000", "to": "6999"}`, revenue, false},
+		{`{"field": "account_name", "op": "between", "from": "a", "to": "c"}`, expense, true},
+		{`{"field": "amount", "op": "gte", "value": 1000000}`, expense, true},
+		{`{"field": "amount", "op": "lt", "value": "1000000"}`, revenue, true},
+		{`{"field": "net", "op": "between", "from": -300000, "to": 0}`, revenue, true},
+		{`{"field": "side", "op": "ne", "value": "Debit"}`, revenue, true},
+		{`{"all": [{"field": "account", "op": "prefix", "value": "61"}, {"field": "amount", "op": "gt", "value": 2000000}]}`, expense, false},
+		{`{"any": [{"field": "account", "op": "prefix", "value": "61"}, {"field": "document_type", "op": "eq", "value": "SI"}]}`, revenue, true},
+		{`{"not": {"field": "document_type", "op": "in", "values": ["JV", "RV"]}}`, expense, false},
+	}
+
+	for _, tt := range tests {
+		var condition models.RuleCondition
+		if err := json.Unmarshal([]byte(tt.condition), &condition); err != nil {
+			t.Fatalf("%s: %v", tt.condition, err)
+		}
+		match, err := compileRuleCondition(&condition)
+		if err != nil {
+			t.Errorf("%s: compileRuleCondition() error = %v", tt.condition, err)
+			continue
+		}
+		if got := match(tt.tx); got != tt.want {
+			t.Errorf("%s: match() = %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+
+	if match, err := compileRuleCondition(nil); err != nil || match != nil {
+		t.Errorf("compileRuleCondition(nil) should give no matcher and no error")
+	}
+}
+
+func TestCompileRuleConditionErrors(t *testing.T) {
+	deep := `{"field": "account", "op": "prefix", "value": "6"}`
+	for i := 0; i < maxConditionDepth; i++ {
+		deep = `{"not": ` + deep + `}`
+	}
+
+	tests := []struct {
+		condition string
+		wantErr   string
+	}{
+		{`{}`, "exactly one of"},
+		{`{"field": "amount_due", "op": "eq", "value": 1}`, "unknown condition field"},
+		{`{"field": "account", "op": "gt", "value": "6"}`, "account supports"},
+		{`{"field": "account", "op": "eq"}`, "needs a value"},
+		{`{"field": "document_type", "op": "in"}`, "needs a list of values"},
+		{`{"field": "keterangan", "op": "regex", "value": "(unclosed"}`, "invalid keterangan regex"},
+		{`{"field": "keterangan", "op": "regex", "value": "` + strings.Repeat("a", maxConditionPattern+1) + `"}`, "cannot exceed"},
+		{`{"field": "account", "op": "between", "from": "6000"}`, "needs from and to"},
+		{`{"field": "debet", "op": "gt", "value": "many"}`, "needs a numeric value"},
+		{`{"field": "side", "op": "eq", "value": "left"}`, "debit or credit"},
+		{deep, "nested more than"},
+	}
+
+	for _, tt := range tests {
+		var condition models.RuleCondition
+		if err := json.Unmarshal([]byte(tt.condition), &condition); err != nil {
+			t.Fatalf("%s: %v", tt.condition, err)
+		}
+		_, err := compileRuleCondition(&condition)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%.60s: compileRuleCondition() error = %v, want %q", tt.condition, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateRuleMatch(t *testing.T) {
+	condition := &models.RuleCondition{Field: models.ConditionFieldAccount, Op: "prefix", Operand: "61"}
+
+	if err := ValidateRuleMatch("entertainment", nil); err != nil {
+		t.Errorf("keyword only: %v", err)
+	}
+	if err := ValidateRuleMatch("", condition); err != nil {
+		t.Errorf("conditions only: %v", err)
+	}
+	if err := ValidateRuleMatch("  ", nil); err == nil {
+		t.Error("a rule without keyword or conditions should be rejected")
+	}
+	if err := ValidateRuleMatch("--", condition); err == nil {
+		t.Error("a keyword without letters or digits should be rejected")
+	}
+	if err := ValidateRuleMatch("entertainment", &models.RuleCondition{Field: "nope"}); err == nil {
+		t.Error("invalid conditions should be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"sort"
+)
+
+// RuleHitCounter counts, per rule, the rows a processing run filled from it. Hits
+// come from the rule provenance, so a rule counts once per row even when it fills
+// several fields.
+type RuleHitCounter struct {
+	counts map[ruleHitKey]int
+}
+
+type ruleHitKey struct {
+	ruleType string
+	ruleID   int
+}
+
+func NewRuleHitCounter() *RuleHitCounter {
+	return &RuleHitCounter{counts: map[ruleHitKey]int{}}
+}
+
+// Add counts the rules behind a processed batch; rows that failed are skipped
+func (c *RuleHitCounter) Add(transactions []models.TransactionData) {
+	seen := map[ruleHitKey]bool{}
+	for i := range transactions {
+		tx := &transactions[i]
+		if tx.ProcessingError != nil || len(tx.RuleProvenance) == 0 {
+			continue
+		}
+
+		for k := range seen {
+			delete(seen, k)
+		}
+		for _, p := range tx.RuleProvenance {
+			key := ruleHitKey{ruleType: p.RuleType, ruleID: p.RuleID}
+			if p.RuleID == 0 || seen[key] {
+				continue
+			}
+			seen[key] = true
+			c.counts[key]++
+		}
+	}
+}
+
+// Stats returns the counts as rows for a run, most used rules first
+func (c *RuleHitCounter) Stats(runID int, sessionCode string) []models.RuleHitStat {
+	stats := make([]models.RuleHitStat, 0, len(c.counts))
+	for key, hits := range c.counts {
+		stats = append(stats, models.RuleHitStat{
+			RunID:       runID,
+			SessionCode: sessionCode,
+			RuleType:    key.ruleType,
+			RuleID:      key.ruleID,
+			Hits:        hits,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Hits != stats[j].Hits {
+			return stats[i].Hits > stats[j].Hits
+		}
+		if stats[i].RuleType != stats[j].RuleType {
+			return stats[i].RuleType < stats[j].RuleType
+		}
+		return stats[i].RuleID < stats[j].RuleID
+	})
+	return stats
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// RuleSetService freezes the active rules into immutable, versioned snapshots
+type RuleSetService struct {
+	accountRepo  *repository.AccountRepository
+	rulesRepo    *repository.RulesRepository
+	analysisRepo *repository.AdditionalAnalysisRepository
+	ruleSetRepo  *repository.RuleSetRepository
+	ruleCache    *RuleCache
+}
+
+func NewRuleSetService(
+	accountRepo *repository.AccountRepository,
+	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
+	ruleSetRepo *repository.RuleSetRepository,
+	ruleCache *RuleCache,
+) *RuleSetService {
+	return &RuleSetService{
+		accountRepo:  accountRepo,
+		rulesRepo:    rulesRepo,
+		analysisRepo: analysisRepo,
+		ruleSetRepo:  ruleSetRepo,
+		ruleCache:    ruleCache,
+	}
+}
+
+// CaptureActive snapshots the currently active rules. If an identical snapshot was
+// captured before, the existing version is reused.
+func (s *RuleSetService) CaptureActive(createdBy *int, note string) (*models.RuleSetVersion, *models.RuleSetSnapshot, error) {
+	var active *models.RuleSetSnapshot
+	var err error
+	if s.ruleCache != nil {
+		active, _, err = s.ruleCache.Snapshot()
+	} else {
+		active, err = loadActiveRuleSet(s.accountRepo, s.rulesRepo, s.analysisRepo)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the serialized form stable so identical rule sets hash identically. The
+	// cached snapshot is shared, so the accounts are sorted in a copy.
+	snapshot := *active
+	snapshot.Accounts = append([]models.Account{}, active.Accounts...)
+	sort.Slice(snapshot.Accounts, func(i, j int) bool {
+		return snapshot.Accounts[i].AccountCode < snapshot.Accounts[j].AccountCode
+	})
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to serialize rule set: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	version := &models.RuleSetVersion{
+		Checksum:                hex.EncodeToString(sum[:]),
+		Snapshot:                string(data),
+		AccountCount:            len(snapshot.Accounts),
+		KoreksiRuleCount:        len(snapshot.KoreksiRules),
+		ObyekRuleCount:          len(snapshot.ObyekRules),
+		WHTRuleCount:            len(snapshot.WithholdingTaxRules),
+		TaxKeywordCount:         len(snapshot.TaxKeywords),
+		UmPajakRuleCount:        len(snapshot.UmPajakRules),
+		AdditionalAnalysisCount: len(snapshot.AdditionalAnalyses),
+		CreatedBy:               createdBy,
+	}
+	if note != "" {
+		version.Note = &note
+	}
+
+	if err := s.ruleSetRepo.CreateVersion(version); err != nil {
+		return nil, nil, fmt.Errorf("failed to store rule set version: %w", err)
+	}
+
+	return version, &snapshot, nil
+}
+
+// LoadVersion returns a stored version together with its decoded snapshot
+func (s *RuleSetService) LoadVersion(id int) (*models.RuleSetVersion, *models.RuleSetSnapshot, error) {
+	version, err := s.ruleSetRepo.GetVersionByID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rule set version %d not found: %w", id, err)
+	}
+
+	var snapshot models.RuleSetSnapshot
+	if err := json.Unmarshal([]byte(version.Snapshot), &snapshot); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode rule set version %d: %w", id, err)
+	}
+
+	return version, &snapshot, nil
+}
@@ -0,0 +1,442 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSimulationMaxRows     = 500
+	maxSimulationMaxRows         = 5000
+	defaultSimulationMaxScanRows = 20000
+	maxSimulationMaxScanRows     = 100000
+	simulationBatchSize          = 1000
+)
+
+// simulatedOutputFields are the output columns compared by the simulation, in display order
+var simulatedOutputFields = []string{
+	"analisa_nature_akun", "koreksi", "obyek", "analisa_koreksi_obyek",
+	"um_pajak_db", "pm_db", "wth_21_cr", "wth_23_cr", "wth_26_cr", "wth_4_2_cr", "wth_15_cr", "pk_cr",
+	"analisa_tambahan",
+}
+
+// RuleSimulationService runs the processing engine over a session with a proposed
+// rule set without persisting anything
+type RuleSimulationService struct {
+	accountRepo  *repository.AccountRepository
+	rulesRepo    *repository.RulesRepository
+	analysisRepo *repository.AdditionalAnalysisRepository
+	uploadRepo   *repository.UploadRepository
+	stepRepo     *repository.ProcessingStepRepository
+	ruleCache    *RuleCache
+}
+
+func NewRuleSimulationService(
+	accountRepo *repository.AccountRepository,
+	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
+	uploadRepo *repository.UploadRepository,
+	stepRepo *repository.ProcessingStepRepository,
+	ruleCache *RuleCache,
+) *RuleSimulationService {
+	return &RuleSimulationService{
+		accountRepo:  accountRepo,
+		rulesRepo:    rulesRepo,
+		analysisRepo: analysisRepo,
+		uploadRepo:   uploadRepo,
+		stepRepo:     stepRepo,
+		ruleCache:    ruleCache,
+	}
+}
+
+// Simulate processes the transactions of the session, up to the scan limit, against
+// the active rules plus the proposed changes and returns a per-row diff with per-rule
+// counts
+func (s *RuleSimulationService) Simulate(sessionCode string, req models.RuleSimulationRequest) (*models.RuleSimulationResult, error) {
+	// The proposals are applied on top of one fixed snapshot. Loading it with
+	// LoadRuleSet keeps the engine from following the rule cache, which would replace
+	// the proposed rules with the live ones when a rule changes during the simulation.
+	var active *models.RuleSetSnapshot
+	var err error
+	if s.ruleCache != nil {
+		active, _, err = s.ruleCache.Snapshot()
+	} else {
+		active, err = loadActiveRuleSet(s.accountRepo, s.rulesRepo, s.analysisRepo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	engine := NewProcessingEngine(s.accountRepo, s.rulesRepo, s.analysisRepo, s.uploadRepo)
+	engine.LoadRuleSet(active)
+	if err := engine.LoadSteps(s.stepRepo); err != nil {
+		return nil, err
+	}
+	engine.dryRun = true
+
+	proposedRules := map[string]bool{}
+	if err := s.applyKoreksiChanges(engine, req.KoreksiRules, proposedRules); err != nil {
+		return nil, err
+	}
+	if err := s.applyObyekChanges(engine, req.ObyekRules, proposedRules); err != nil {
+		return nil, err
+	}
+	engine.compileRules()
+
+	maxRows := req.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultSimulationMaxRows
+	}
+	if maxRows > maxSimulationMaxRows {
+		maxRows = maxSimulationMaxRows
+	}
+	maxScanRows := req.MaxScanRows
+	if maxScanRows <= 0 {
+		maxScanRows = defaultSimulationMaxScanRows
+	}
+	if maxScanRows > maxSimulationMaxScanRows {
+		maxScanRows = maxSimulationMaxScanRows
+	}
+
+	result := &models.RuleSimulationResult{
+		SessionCode:       sessionCode,
+		FieldChangeCounts: map[string]int{},
+		RuleCounts:        []models.SimulationRuleCount{},
+		Rows:              []models.SimulationRowDiff{},
+	}
+	ruleCounts := map[string]*models.SimulationRuleCount{}
+	countFor := func(p models.RuleProvenance) *models.SimulationRuleCount {
+		key := fmt.Sprintf("%s:%d", p.RuleType, p.RuleID)
+		if _, ok := ruleCounts[key]; !ok {
+			ruleCounts[key] = &models.SimulationRuleCount{
+				RuleType: p.RuleType,
+				RuleID:   p.RuleID,
+				Keyword:  p.Keyword,
+				Proposed: proposedRules[key],
+			}
+		}
+		return ruleCounts[key]
+	}
+
+	var afterID int64
+	for {
+		limit := simulationBatchSize
+		if remaining := maxScanRows - result.TotalRows; remaining < limit {
+			limit = remaining
+		}
+		if limit == 0 {
+			// Scan limit reached; look for one more row to tell whether the session has more
+			more, err := s.uploadRepo.GetTransactionsBySessionCodeAfterID(sessionCode, afterID, 1)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load transactions: %w", err)
+			}
+			result.ScanTruncated = len(more) > 0
+			break
+		}
+
+		original, err := s.uploadRepo.GetTransactionsBySessionCodeAfterID(sessionCode, afterID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load transactions: %w", err)
+		}
+		if len(original) == 0 {
+			break
+		}
+		afterID = original[len(original)-1].ID
+
+		simulated := make([]models.TransactionData, len(original))
+		for i := range original {
+			simulated[i] = original[i]
+			resetOutputFields(&simulated[i])
+		}
+
+		if err := engine.ProcessBatch(simulated); err != nil {
+			return nil, err
+		}
+
+		for i := range simulated {
+			result.TotalRows++
+			oldValues := outputFieldValues(&original[i])
+			newValues := outputFieldValues(&simulated[i])
+
+			var changes []models.SimulationFieldChange
+			for _, field := range simulatedOutputFields {
+				if oldValues[field] != newValues[field] {
+					changes = append(changes, models.SimulationFieldChange{Field: field, Old: oldValues[field], New: newValues[field]})
+					result.FieldChangeCounts[field]++
+				}
+			}
+			changed := make(map[string]bool, len(changes))
+			for _, change := range changes {
+				changed[change.Field] = true
+			}
+
+			for field, p := range simulated[i].RuleProvenance {
+				count := countFor(p)
+				count.MatchedRows++
+				if changed[field] {
+					count.ChangedRows++
+				}
+			}
+			for field, p := range original[i].RuleProvenance {
+				if changed[field] {
+					countFor(p).LostRows++
+				}
+			}
+
+			if len(changes) == 0 {
+				continue
+			}
+			result.ChangedRows++
+			if len(result.Rows) >= maxRows {
+				result.RowsTruncated = true
+				continue
+			}
+			result.Rows = append(result.Rows, models.SimulationRowDiff{
+				TransactionID:  original[i].ID,
+				DocumentNumber: original[i].DocumentNumber,
+				Account:        original[i].Account,
+				Keterangan:     original[i].Keterangan,
+				Changes:        changes,
+			})
+		}
+	}
+
+	for _, count := range ruleCounts {
+		result.RuleCounts = append(result.RuleCounts, *count)
+	}
+	sort.Slice(result.RuleCounts, func(i, j int) bool {
+		a, b := result.RuleCounts[i], result.RuleCounts[j]
+		if a.RuleType != b.RuleType {
+			return a.RuleType < b.RuleType
+		}
+		return a.RuleID < b.RuleID
+	})
+
+	return result, nil
+}
+
+// applyKoreksiChanges applies proposed deletions, updates and additions to the engine's koreksi rules
+func (s *RuleSimulationService) applyKoreksiChanges(engine *ProcessingEngine, changes models.RuleSimulationChanges, proposed map[string]bool) error {
+	removed := map[int]bool{}
+	for _, id := range changes.Delete {
+		removed[id] = true
+	}
+
+	var updated []models.KoreksiRule
+	for _, change := range changes.Update {
+		rule, err := s.rulesRepo.GetKoreksiRuleByID(change.ID)
+		if err != nil {
+			return fmt.Errorf("koreksi rule %d not found", change.ID)
+		}
+		// As in a rule update, conditions left out of the change are kept
+		conditions := change.Conditions.Or(rule.Conditions)
+		if err := validateSimulatedRule(change, conditions); err != nil {
+			return fmt.Errorf("koreksi rule %d: %w", change.ID, err)
+		}
+		applySimulatedRule(&rule.Keyword, &rule.Value, &rule.NotValue, &rule.Priority, &rule.IsActive, change)
+		rule.Conditions = conditions
+		removed[rule.ID] = true
+		if rule.IsActive {
+			updated = append(updated, *rule)
+		}
+	}
+
+	rules := make([]models.KoreksiRule, 0, len(engine.koreksiRules)+len(updated)+len(changes.Add))
+	for _, rule := range engine.koreksiRules {
+		if !removed[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	rules = append(rules, updated...)
+
+	for i, change := range changes.Add {
+		if err := validateSimulatedRule(change, change.Conditions.Condition); err != nil {
+			return fmt.Errorf("new koreksi rule %d: %w", i+1, err)
+		}
+		rule := models.KoreksiRule{ID: -(i + 1), IsActive: true, Conditions: change.Conditions.Condition}
+		applySimulatedRule(&rule.Keyword, &rule.Value, &rule.NotValue, &rule.Priority, &rule.IsActive, change)
+		if rule.IsActive {
+			rules = append(rules, rule)
+			proposed[fmt.Sprintf("%s:%d", models.RuleTypeKoreksi, rule.ID)] = true
+		}
+	}
+
+	sortKoreksiRules(rules)
+	engine.koreksiRules = rules
+	return nil
+}
+
+// applyObyekChanges applies proposed deletions, updates and additions to the engine's obyek rules
+func (s *RuleSimulationService) applyObyekChanges(engine *ProcessingEngine, changes models.RuleSimulationChanges, proposed map[string]bool) error {
+	removed := map[int]bool{}
+	for _, id := range changes.Delete {
+		removed[id] = true
+	}
+
+	var updated []models.ObyekRule
+	for _, change := range changes.Update {
+		rule, err := s.rulesRepo.GetObyekRuleByID(change.ID)
+		if err != nil {
+			return fmt.Errorf("obyek rule %d not found", change.ID)
+		}
+		// As in a rule update, conditions left out of the change are kept
+		conditions := change.Conditions.Or(rule.Conditions)
+		if err := validateSimulatedRule(change, conditions); err != nil {
+			return fmt.Errorf("obyek rule %d: %w", change.ID, err)
+		}
+		applySimulatedRule(&rule.Keyword, &rule.Value, &rule.NotValue, &rule.Priority, &rule.IsActive, change)
+		rule.Conditions = conditions
+		removed[rule.ID] = true
+		if rule.IsActive {
+			updated = append(updated, *rule)
+		}
+	}
+
+	rules := make([]models.ObyekRule, 0, len(engine.obyekRules)+len(updated)+len(changes.Add))
+	for _, rule := range engine.obyekRules {
+		if !removed[rule.ID] {
+			rules = append(rules, rule)
+		}
+	}
+	rules = append(rules, updated...)
+
+	for i, change := range changes.Add {
+		if err := validateSimulatedRule(change, change.Conditions.Condition); err != nil {
+			return fmt.Errorf("new obyek rule %d: %w", i+1, err)
+		}
+		rule := models.ObyekRule{ID: -(i + 1), IsActive: true, Conditions: change.Conditions.Condition}
+		applySimulatedRule(&rule.Keyword, &rule.Value, &rule.NotValue, &rule.Priority, &rule.IsActive, change)
+		if rule.IsActive {
+			rules = append(rules, rule)
+			proposed[fmt.Sprintf("%s:%d", models.RuleTypeObyek, rule.ID)] = true
+		}
+	}
+
+	sortObyekRules(rules)
+	engine.obyekRules = rules
+	return nil
+}
+
+// validateSimulatedRule applies the same checks as the rule CRUD handlers to a change
+// and the conditions the rule ends up with
+func validateSimulatedRule(change models.SimulatedRule, conditions *models.RuleCondition) error {
+	if change.Value == "" {
+		return fmt.Errorf("value is required")
+	}
+	if err := ValidateRuleMatch(change.Keyword, conditions); err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+	return nil
+}
+
+func applySimulatedRule(keyword, value *string, notValue *sql.NullString, priority *int, isActive *bool, change models.SimulatedRule) {
+	*keyword = change.Keyword
+	*value = change.Value
+	*notValue = sql.NullString{String: change.NotValue, Valid: change.NotValue != ""}
+	if change.Priority != nil {
+		*priority = *change.Priority
+	}
+	if change.IsActive != nil {
+		*isActive = *change.IsActive
+	}
+}
+
+// TestRow runs one made-up row through the active rules without saving anything and
+// shows the normalized keterangan the keywords were matched on
+func (s *RuleSimulationService) TestRow(req models.RuleTestRequest) (*models.RuleTestResult, error) {
+	engine := NewProcessingEngine(s.accountRepo, s.rulesRepo, s.analysisRepo, s.uploadRepo)
+	engine.SetRuleCache(s.ruleCache)
+	if err := engine.LoadRules(); err != nil {
+		return nil, err
+	}
+	if err := engine.LoadSteps(s.stepRepo); err != nil {
+		return nil, err
+	}
+
+	tx := &models.TransactionData{
+		Keterangan:   req.Keterangan,
+		Account:      req.Account,
+		DocumentType: req.DocumentType,
+		Debet:        req.Debet,
+		Credit:       req.Credit,
+		Net:          req.Debet - req.Credit,
+	}
+	if req.PostingDate != "" {
+		postingDate, err := parseValidityDate("posting_date", req.PostingDate)
+		if err != nil {
+			return nil, err
+		}
+		tx.PostingDate = postingDate
+	}
+	if err := engine.ProcessTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	result := &models.RuleTestResult{
+		Keterangan:           req.Keterangan,
+		NormalizedKeterangan: NormalizeKeyterm(req.Keterangan),
+		Outputs:              outputFieldValues(tx),
+		RuleProvenance:       tx.RuleProvenance,
+	}
+	if tx.ProcessingNotes != nil {
+		result.ProcessingNotes = *tx.ProcessingNotes
+	}
+	if tx.ProcessingWarning != nil {
+		result.ProcessingWarning = *tx.ProcessingWarning
+	}
+
+	for _, keyword := range req.Keywords {
+		variants := keywordVariants(keyword, engine.synonyms)
+		test := models.RuleTestKeyword{
+			Keyword:    keyword,
+			Normalized: variants[0],
+			Variants:   variants[1:],
+		}
+		for _, variant := range variants {
+			if strings.Contains(result.NormalizedKeterangan, variant) {
+				test.Matched = true
+				test.MatchedVariant = variant
+				break
+			}
+		}
+		result.Keywords = append(result.Keywords, test)
+	}
+
+	return result, nil
+}
+
+// outputFieldValues renders the comparable output fields of a transaction as strings
+func outputFieldValues(tx *models.TransactionData) map[string]string {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	num := func(n models.NullableNumericFloat64) string {
+		if !n.Valid {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", n.Value)
+	}
+
+	return map[string]string{
+		"analisa_nature_akun":   str(tx.AnalisaNatureAkun),
+		"koreksi":               str(tx.Koreksi),
+		"obyek":                 str(tx.Obyek),
+		"analisa_koreksi_obyek": str(tx.AnalisaKoreksiObyek),
+		"um_pajak_db":           num(tx.UmPajakDB),
+		"pm_db":                 num(tx.PmDB),
+		"wth_21_cr":             num(tx.Wth21Cr),
+		"wth_23_cr":             num(tx.Wth23Cr),
+		"wth_26_cr":             num(tx.Wth26Cr),
+		"wth_4_2_cr":            num(tx.Wth42Cr),
+		"wth_15_cr":             num(tx.Wth15Cr),
+		"pk_cr":                 num(tx.PkCr),
+		"analisa_tambahan":      str(tx.AnalisaTambahan),
+	}
+}
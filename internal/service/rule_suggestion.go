@@ -0,0 +1,270 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSuggestionNGram      = 3
+	maxUnclassifiedRows     = 50000
+	maxLabeledRows          = 20000
+	maxSuggestionExamples   = 3
+	minSuggestionKeywordLen = 3
+)
+
+// suggestionStopWords are skipped as keywords and never start or end one
+var suggestionStopWords = map[string]bool{
+	"dan": true, "di": true, "ke": true, "dari": true, "untuk": true, "atas": true,
+	"yang": true, "dengan": true, "pada": true, "per": true, "no": true, "and": true,
+	"the": true, "of": true, "for": true, "to": true, "in": true, "on": true,
+}
+
+// RuleSuggestionService proposes koreksi and obyek keywords from the n-grams that occur
+// most often in the keterangan of unclassified rows. The value of a suggestion comes
+// from the rows users classified by hand that contain the same n-gram.
+type RuleSuggestionService struct {
+	ruleStatsRepo *repository.RuleStatsRepository
+	rulesRepo     *repository.RulesRepository
+}
+
+func NewRuleSuggestionService(ruleStatsRepo *repository.RuleStatsRepository, rulesRepo *repository.RulesRepository) *RuleSuggestionService {
+	return &RuleSuggestionService{
+		ruleStatsRepo: ruleStatsRepo,
+		rulesRepo:     rulesRepo,
+	}
+}
+
+// gramStats counts the rows an n-gram occurs in
+type gramStats struct {
+	keyword  string
+	tokens   int
+	support  int
+	totalNet float64
+	examples []string
+	labeled  int
+	values   map[string]int
+}
+
+// Suggest mines keyword suggestions for one rule type ("koreksi" or "obyek")
+func (s *RuleSuggestionService) Suggest(filter models.RuleSuggestionFilter) ([]models.RuleSuggestion, error) {
+	existing, err := s.existingKeywords(filter.RuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	unclassified, err := s.ruleStatsRepo.GetUnclassifiedRows(filter.SessionCode, filter.From, filter.To, maxUnclassifiedRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unclassified rows: %w", err)
+	}
+
+	stats := map[string]*gramStats{}
+	for _, row := range unclassified {
+		for _, gram := range keteranganNGrams(row.Keterangan) {
+			st, ok := stats[gram.keyword]
+			if !ok {
+				st = &gramStats{keyword: gram.keyword, tokens: gram.tokens, values: map[string]int{}}
+				stats[gram.keyword] = st
+			}
+			st.support++
+			st.totalNet += row.Net
+			if len(st.examples) < maxSuggestionExamples {
+				st.examples = append(st.examples, row.Keterangan)
+			}
+		}
+	}
+
+	// Drop rare and already existing keywords before counting the labeled rows
+	for keyword, st := range stats {
+		if st.support < filter.MinSupport || existing[keyword] {
+			delete(stats, keyword)
+		}
+	}
+	if len(stats) == 0 {
+		return []models.RuleSuggestion{}, nil
+	}
+
+	labeled, err := s.ruleStatsRepo.GetManuallyClassifiedRows(filter.RuleType, maxLabeledRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manually classified rows: %w", err)
+	}
+	for _, row := range labeled {
+		value := row.Koreksi
+		if filter.RuleType == models.RuleTypeObyek {
+			value = row.Obyek
+		}
+		if value == nil || strings.TrimSpace(*value) == "" {
+			continue
+		}
+		for _, gram := range keteranganNGrams(row.Keterangan) {
+			if st, ok := stats[gram.keyword]; ok {
+				st.labeled++
+				st.values[strings.TrimSpace(*value)]++
+			}
+		}
+	}
+
+	suggestions := make([]models.RuleSuggestion, 0, len(stats))
+	for _, st := range pruneRedundantGrams(stats) {
+		suggestion := models.RuleSuggestion{
+			RuleType:    filter.RuleType,
+			Keyword:     st.keyword,
+			Support:     st.support,
+			TotalNet:    st.totalNet,
+			LabeledRows: st.labeled,
+			Examples:    st.examples,
+		}
+		suggestion.Value, suggestion.ValueRows = topValue(st.values)
+		if st.labeled > 0 {
+			suggestion.Confidence = float64(suggestion.ValueRows) / float64(st.labeled)
+		}
+		if suggestion.Confidence < filter.MinConfidence {
+			continue
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Support != b.Support {
+			return a.Support > b.Support
+		}
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return a.Keyword < b.Keyword
+	})
+	if filter.Limit > 0 && len(suggestions) > filter.Limit {
+		suggestions = suggestions[:filter.Limit]
+	}
+	return suggestions, nil
+}
+
+// existingKeywords returns the lower-cased keywords of the saved rules of a type
+func (s *RuleSuggestionService) existingKeywords(ruleType string) (map[string]bool, error) {
+	keywords := map[string]bool{}
+	switch ruleType {
+	case models.RuleTypeKoreksi:
+		rules, err := s.rulesRepo.GetAllKoreksiRules()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load koreksi rules: %w", err)
+		}
+		for _, r := range rules {
+			keywords[NormalizeKeyterm(r.Keyword)] = true
+		}
+	case models.RuleTypeObyek:
+		rules, err := s.rulesRepo.GetAllObyekRules()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load obyek rules: %w", err)
+		}
+		for _, r := range rules {
+			keywords[NormalizeKeyterm(r.Keyword)] = true
+		}
+	default:
+		return nil, fmt.Errorf("rule type must be koreksi or obyek")
+	}
+	return keywords, nil
+}
+
+type keteranganGram struct {
+	keyword string
+	tokens  int
+}
+
+// keteranganNGrams returns the distinct 1..3-word n-grams of a normalized keterangan
+// (see NormalizeKeyterm), the form rules are matched on. Long numbers (invoice and
+// reference numbers) and single letters break a phrase; short numbers such as the 23
+// of "pph 23" are kept, but an n-gram needs at least one word.
+func keteranganNGrams(keterangan string) []keteranganGram {
+	var grams []keteranganGram
+	seen := map[string]bool{}
+	var phrase []string
+	flush := func() {
+		for n := 1; n <= maxSuggestionNGram; n++ {
+			for i := 0; i+n <= len(phrase); i++ {
+				if suggestionStopWords[phrase[i]] || suggestionStopWords[phrase[i+n-1]] {
+					continue
+				}
+				keyword := strings.Join(phrase[i:i+n], " ")
+				if len(keyword) < minSuggestionKeywordLen || seen[keyword] || isNumber(strings.ReplaceAll(keyword, " ", "")) {
+					continue
+				}
+				seen[keyword] = true
+				grams = append(grams, keteranganGram{keyword: keyword, tokens: n})
+			}
+		}
+		phrase = phrase[:0]
+	}
+
+	for _, word := range strings.Fields(NormalizeKeyterm(keterangan)) {
+		if isNumber(word) {
+			if len(word) > 2 {
+				flush()
+				continue
+			}
+		} else if len([]rune(word)) < 2 {
+			flush()
+			continue
+		}
+		phrase = append(phrase, word)
+	}
+	flush()
+	return grams
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneRedundantGrams drops an n-gram when a longer n-gram starting or ending with it
+// occurs in as many unclassified and labeled rows: both then cover the same rows, and
+// the longer one is the more specific keyword
+func pruneRedundantGrams(stats map[string]*gramStats) []*gramStats {
+	grams := make([]*gramStats, 0, len(stats))
+	for _, st := range stats {
+		grams = append(grams, st)
+	}
+	sort.Slice(grams, func(i, j int) bool { return grams[i].tokens > grams[j].tokens })
+
+	redundant := map[string]bool{}
+	for _, st := range grams {
+		if st.tokens == 1 {
+			continue
+		}
+		words := strings.Fields(st.keyword)
+		for _, sub := range []string{strings.Join(words[1:], " "), strings.Join(words[:len(words)-1], " ")} {
+			if shorter, ok := stats[sub]; ok && shorter.support == st.support && shorter.labeled == st.labeled {
+				redundant[sub] = true
+			}
+		}
+	}
+
+	kept := grams[:0]
+	for _, st := range grams {
+		if !redundant[st.keyword] {
+			kept = append(kept, st)
+		}
+	}
+	return kept
+}
+
+// topValue returns the most frequent value and its count, the alphabetically first
+// one on ties
+func topValue(values map[string]int) (string, int) {
+	best, count := "", 0
+	for value, n := range values {
+		if n > count || (n == count && value < best) {
+			best, count = value, n
+		}
+	}
+	return best, count
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const validityDateLayout = "2006-01-02"
+
+// ParseValidityWindow parses the optional valid_from/valid_to dates (YYYY-MM-DD) of a
+// rule. An empty date leaves that side of the window open.
+func ParseValidityWindow(from, to string) (*time.Time, *time.Time, error) {
+	validFrom, err := parseValidityDate("valid_from", from)
+	if err != nil {
+		return nil, nil, err
+	}
+	validTo, err := parseValidityDate("valid_to", to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if validFrom != nil && validTo != nil && validTo.Before(*validFrom) {
+		return nil, nil, fmt.Errorf("valid_to cannot be before valid_from")
+	}
+	return validFrom, validTo, nil
+}
+
+func parseValidityDate(name, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(validityDateLayout, value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+	}
+	return &date, nil
+}
+
+// FormatValidityWindow renders a validity window for messages, e.g. "2024-01-01 to open"
+func FormatValidityWindow(from, to *time.Time) string {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return "open"
+		}
+		return t.Format(validityDateLayout)
+	}
+	return format(from) + " to " + format(to)
+}
+
+// SameRuleConditions reports whether two rules match on the same conditions, which
+// makes rules with the same keyword and tax type versions of one another
+func SameRuleConditions(a, b *models.RuleCondition) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// validityWindow is a rule's validity window as comparable YYYY-MM-DD strings;
+// an empty side is open
+type validityWindow struct {
+	from string
+	to   string
+}
+
+func newValidityWindow(from, to *time.Time) validityWindow {
+	var w validityWindow
+	if from != nil {
+		w.from = from.Format(validityDateLayout)
+	}
+	if to != nil {
+		w.to = to.Format(validityDateLayout)
+	}
+	return w
+}
+
+// contains reports whether the window includes date (YYYY-MM-DD)
+func (w validityWindow) contains(date string) bool {
+	return (w.from == "" || date >= w.from) && (w.to == "" || date <= w.to)
+}
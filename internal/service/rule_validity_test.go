@@ -0,0 +1,76 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParseValidityWindow(t *testing.T) {
+	from, to, err := ParseValidityWindow("2024-01-01", " 2024-12-31 ")
+	if err != nil {
+		t.Fatalf("ParseValidityWindow() error = %v", err)
+	}
+	if from.Format(validityDateLayout) != "2024-01-01" || to.Format(validityDateLayout) != "2024-12-31" {
+		t.Errorf("ParseValidityWindow() = %v, %v", from, to)
+	}
+
+	from, to, err = ParseValidityWindow(" ", "")
+	if err != nil || from != nil || to != nil {
+		t.Errorf("an empty window should be open, got %v, %v, %v", from, to, err)
+	}
+
+	for _, bad := range [][2]string{{"2024-12-31", "2024-01-01"}, {"01/01/2024", ""}, {"", "2024-02-30"}} {
+		if _, _, err := ParseValidityWindow(bad[0], bad[1]); err == nil {
+			t.Errorf("ParseValidityWindow(%q, %q) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestFormatValidityWindow(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	if got := FormatValidityWindow(&from, nil); got != "2024-01-01 to open" {
+		t.Errorf("FormatValidityWindow() = %q", got)
+	}
+	if got := FormatValidityWindow(nil, nil); got != "open to open" {
+		t.Errorf("FormatValidityWindow() = %q", got)
+	}
+}
+
+func TestValidityWindowContains(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.Local)
+	window := newValidityWindow(&from, &to)
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2023-12-31", false},
+		{"2024-01-01", true},
+		{"2024-12-31", true},
+		{"2025-01-01", false},
+	}
+	for _, tt := range tests {
+		if got := window.contains(tt.date); got != tt.want {
+			t.Errorf("contains(%q) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+
+	if !newValidityWindow(nil, &to).contains("1999-01-01") || !newValidityWindow(&from, nil).contains("2030-01-01") {
+		t.Error("an open-ended window should contain every date on its open side")
+	}
+}
+
+func TestSameRuleConditions(t *testing.T) {
+	account := &models.RuleCondition{Field: models.ConditionFieldAccount, Op: "prefix", Operand: "61"}
+	sameAccount := &models.RuleCondition{Field: models.ConditionFieldAccount, Op: "prefix", Operand: "61"}
+	otherAccount := &models.RuleCondition{Field: models.ConditionFieldAccount, Op: "prefix", Operand: "62"}
+
+	if !SameRuleConditions(nil, nil) || !SameRuleConditions(account, sameAccount) {
+		t.Error("equal conditions should be the same")
+	}
+	if SameRuleConditions(account, nil) || SameRuleConditions(account, otherAccount) {
+		t.Error("different conditions should not be the same")
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sessionEventsChannel carries every session event to the web processes
+	sessionEventsChannel = "processing:events"
+	// Recent events of a session are kept in a Redis stream so a reconnecting client
+	// can replay what it missed; the stream IDs are the SSE event IDs
+	sessionEventStreamLen = 500
+	sessionEventStreamTTL = 24 * time.Hour
+	// sessionEventBuffer is the number of events a slow client may fall behind
+	// before it is disconnected; it reconnects and replays from its last event
+	sessionEventBuffer = 64
+	// SessionStreamTokenTTL is how long a stream token can be redeemed. A token opens
+	// one stream; a client reconnecting asks for a new one.
+	SessionStreamTokenTTL = time.Minute
+)
+
+// ErrStreamTokenInvalid is returned for a stream token that is unknown, expired,
+// already used or issued for another session
+var ErrStreamTokenInvalid = errors.New("invalid or expired stream token")
+
+// SessionStreamGrant is what a stream token allows: the given user following the
+// events of one session
+type SessionStreamGrant struct {
+	UserID      int    `json:"user_id"`
+	Role        string `json:"role"`
+	SessionCode string `json:"session_code"`
+}
+
+func sessionStreamTokenKey(token string) string {
+	return "processing:stream-token:" + token
+}
+
+func sessionEventStreamKey(sessionCode string) string {
+	return "processing:events:" + sessionCode
+}
+
+// SessionEventPublisher stores and publishes the progress events of upload sessions.
+// A publisher without Redis drops the events.
+type SessionEventPublisher struct {
+	redis *redis.Client
+}
+
+func NewSessionEventPublisher(redis *redis.Client) *SessionEventPublisher {
+	return &SessionEventPublisher{redis: redis}
+}
+
+// Publish appends the event to the stream of its session and publishes it to the web
+// processes. Errors are logged; progress events are best effort.
+func (p *SessionEventPublisher) Publish(ctx context.Context, event models.SessionEvent) {
+	if p == nil || p.redis == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode session event: %v", err)
+		return
+	}
+
+	key := sessionEventStreamKey(event.SessionCode)
+	id, err := p.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: sessionEventStreamLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}).Result()
+	if err != nil {
+		log.Printf("Failed to store session event for %s: %v", event.SessionCode, err)
+		return
+	}
+	p.redis.Expire(ctx, key, sessionEventStreamTTL)
+
+	event.ID = id
+	data, _ = json.Marshal(event)
+	if err := p.redis.Publish(ctx, sessionEventsChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish session event for %s: %v", event.SessionCode, err)
+	}
+}
+
+// SessionEventHub fans the session events published by workers out to the clients
+// connected to this web process
+type SessionEventHub struct {
+	redis *redis.Client
+
+	mu      sync.Mutex
+	clients map[string]map[*SessionEventSubscription]struct{}
+}
+
+// SessionEventSubscription receives the live events of one session. Events is closed
+// when the client falls too far behind or the subscription is closed.
+type SessionEventSubscription struct {
+	Events      <-chan models.SessionEvent
+	events      chan models.SessionEvent
+	sessionCode string
+	hub         *SessionEventHub
+	closed      bool
+}
+
+func NewSessionEventHub(redis *redis.Client) *SessionEventHub {
+	return &SessionEventHub{
+		redis:   redis,
+		clients: make(map[string]map[*SessionEventSubscription]struct{}),
+	}
+}
+
+// Start subscribes to the session events until ctx is done
+func (h *SessionEventHub) Start(ctx context.Context) {
+	pubsub := h.redis.Subscribe(ctx, sessionEventsChannel)
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	go func() {
+		for message := range pubsub.Channel() {
+			var event models.SessionEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("Ignoring malformed session event: %v", err)
+				continue
+			}
+			h.dispatch(event)
+		}
+	}()
+}
+
+// Subscribe registers a client for the live events of a session. Subscribe before
+// Replay so no event falls between the two.
+func (h *SessionEventHub) Subscribe(sessionCode string) *SessionEventSubscription {
+	events := make(chan models.SessionEvent, sessionEventBuffer)
+	sub := &SessionEventSubscription{Events: events, events: events, sessionCode: sessionCode, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[sessionCode] == nil {
+		h.clients[sessionCode] = make(map[*SessionEventSubscription]struct{})
+	}
+	h.clients[sessionCode][sub] = struct{}{}
+	return sub
+}
+
+// Close unregisters the subscription
+func (s *SessionEventSubscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove unregisters sub and closes its channel; h.mu must be held
+func (h *SessionEventHub) remove(sub *SessionEventSubscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+
+	delete(h.clients[sub.sessionCode], sub)
+	if len(h.clients[sub.sessionCode]) == 0 {
+		delete(h.clients, sub.sessionCode)
+	}
+}
+
+func (h *SessionEventHub) dispatch(event models.SessionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.clients[event.SessionCode] {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Replay returns the stored events of a session after lastEventID. Without a last
+// event ID only the latest event is returned, so a new client starts from the
+// current state.
+func (h *SessionEventHub) Replay(ctx context.Context, sessionCode, lastEventID string) ([]models.SessionEvent, error) {
+	key := sessionEventStreamKey(sessionCode)
+	if _, _, ok := splitStreamID(lastEventID); !ok {
+		lastEventID = "" // not an ID this stream handed out
+	}
+
+	var messages []redis.XMessage
+	var err error
+	if lastEventID == "" {
+		messages, err = h.redis.XRevRangeN(ctx, key, "+", "-", 1).Result()
+	} else {
+		messages, err = h.redis.XRange(ctx, key, "("+lastEventID, "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.SessionEvent, 0, len(messages))
+	for _, message := range messages {
+		data, _ := message.Values["event"].(string)
+		var event models.SessionEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		event.ID = message.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// IssueStreamToken stores a random single-use token for grant. Browsers cannot send
+// an Authorization header with EventSource, so the stream URL carries this token
+// instead of the user's access token.
+func (h *SessionEventHub) IssueStreamToken(ctx context.Context, grant SessionStreamGrant) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	data, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+	if err := h.redis.Set(ctx, sessionStreamTokenKey(token), data, SessionStreamTokenTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RedeemStreamToken consumes a stream token and returns its grant, which must be for
+// sessionCode
+func (h *SessionEventHub) RedeemStreamToken(ctx context.Context, token, sessionCode string) (*SessionStreamGrant, error) {
+	if token == "" {
+		return nil, ErrStreamTokenInvalid
+	}
+	data, err := h.redis.GetDel(ctx, sessionStreamTokenKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrStreamTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var grant SessionStreamGrant
+	if err := json.Unmarshal(data, &grant); err != nil || grant.SessionCode != sessionCode {
+		return nil, ErrStreamTokenInvalid
+	}
+	return &grant, nil
+}
+
+// SessionEventAfter reports whether the stream ID id comes after the stream ID last
+func SessionEventAfter(id, last string) bool {
+	if last == "" {
+		return true
+	}
+	idMillis, idSeq, _ := splitStreamID(id)
+	lastMillis, lastSeq, _ := splitStreamID(last)
+	if idMillis != lastMillis {
+		return idMillis > lastMillis
+	}
+	return idSeq > lastSeq
+}
+
+func splitStreamID(id string) (uint64, uint64, bool) {
+	millis, seq, found := strings.Cut(id, "-")
+	m, err := strconv.ParseUint(millis, 10, 64)
+	if err != nil || !found {
+		return 0, 0, false
+	}
+	s, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return m, s, true
+}
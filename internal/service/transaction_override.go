@@ -0,0 +1,155 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"fmt"
+)
+
+// Fields that can be manually overridden on a transaction
+const (
+	OverrideFieldKoreksi = "koreksi"
+	OverrideFieldObyek   = "obyek"
+)
+
+// TransactionOverrideService restores manually overridden fields to the value the
+// active rules would produce
+type TransactionOverrideService struct {
+	accountRepo  *repository.AccountRepository
+	rulesRepo    *repository.RulesRepository
+	analysisRepo *repository.AdditionalAnalysisRepository
+	uploadRepo   *repository.UploadRepository
+	stepRepo     *repository.ProcessingStepRepository
+	ruleCache    *RuleCache
+}
+
+func NewTransactionOverrideService(
+	accountRepo *repository.AccountRepository,
+	rulesRepo *repository.RulesRepository,
+	analysisRepo *repository.AdditionalAnalysisRepository,
+	uploadRepo *repository.UploadRepository,
+	stepRepo *repository.ProcessingStepRepository,
+	ruleCache *RuleCache,
+) *TransactionOverrideService {
+	return &TransactionOverrideService{
+		accountRepo:  accountRepo,
+		rulesRepo:    rulesRepo,
+		analysisRepo: analysisRepo,
+		uploadRepo:   uploadRepo,
+		stepRepo:     stepRepo,
+		ruleCache:    ruleCache,
+	}
+}
+
+// ResetToRuleValue clears the override flag of the given fields and re-derives their
+// values from the active rules. Other outputs of the transaction are left untouched.
+func (s *TransactionOverrideService) ResetToRuleValue(transactionID int64, fields []string, userID int, userRole string) (*models.TransactionData, error) {
+	resetKoreksi, resetObyek := false, false
+	for _, field := range fields {
+		switch field {
+		case OverrideFieldKoreksi:
+			resetKoreksi = true
+		case OverrideFieldObyek:
+			resetObyek = true
+		default:
+			return nil, fmt.Errorf("unknown override field %q", field)
+		}
+	}
+
+	tx, err := s.uploadRepo.GetTransactionByID(transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found or access denied")
+	}
+	if userRole != "admin" && tx.UserID != userID {
+		return nil, fmt.Errorf("transaction not found or access denied")
+	}
+
+	engine := NewProcessingEngine(s.accountRepo, s.rulesRepo, s.analysisRepo, s.uploadRepo)
+	engine.SetRuleCache(s.ruleCache)
+	if err := engine.LoadRules(); err != nil {
+		return nil, err
+	}
+	if err := engine.LoadSteps(s.stepRepo); err != nil {
+		return nil, err
+	}
+
+	// Evaluate a copy with the selected overrides lifted
+	derived := *tx
+	if resetKoreksi {
+		derived.KoreksiOverride = false
+	}
+	if resetObyek {
+		derived.ObyekOverride = false
+	}
+	resetOutputFields(&derived)
+	if err := engine.ProcessTransaction(&derived); err != nil {
+		return nil, err
+	}
+
+	if tx.RuleProvenance == nil {
+		tx.RuleProvenance = models.RuleProvenanceMap{}
+	}
+	if resetKoreksi {
+		tx.Koreksi = derived.Koreksi
+		tx.KoreksiOverride = false
+		tx.KoreksiOverrideBy = nil
+		tx.KoreksiOverrideAt = nil
+		delete(tx.RuleProvenance, "koreksi")
+		if p, ok := derived.RuleProvenance["koreksi"]; ok {
+			tx.RuleProvenance["koreksi"] = p
+		}
+	}
+	if resetObyek {
+		tx.Obyek = derived.Obyek
+		tx.ObyekOverride = false
+		tx.ObyekOverrideBy = nil
+		tx.ObyekOverrideAt = nil
+		delete(tx.RuleProvenance, "obyek")
+		if p, ok := derived.RuleProvenance["obyek"]; ok {
+			tx.RuleProvenance["obyek"] = p
+		}
+	}
+	tx.AnalisaKoreksiObyek = derived.AnalisaKoreksiObyek
+
+	if err := s.uploadRepo.UpdateTransactionOverrides(tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// SetOverride stores manually entered koreksi and/or obyek values as overrides. A nil
+// value leaves the field unchanged and an empty string clears it. Analisa Koreksi -
+// Obyek is combined from the resulting values the way the analisa_koreksi_obyek step
+// would, with its configured separator.
+func (s *TransactionOverrideService) SetOverride(transactionID int64, koreksi, obyek *string, userID int, userRole string) error {
+	tx, err := s.uploadRepo.GetTransactionByID(transactionID)
+	if err != nil {
+		return fmt.Errorf("transaction not found or access denied")
+	}
+	if userRole != "admin" && tx.UserID != userID {
+		return fmt.Errorf("transaction not found or access denied")
+	}
+
+	stored, err := s.stepRepo.GetSteps()
+	if err != nil {
+		return fmt.Errorf("failed to load processing steps: %w", err)
+	}
+	separator, enabled, err := koreksiObyekSeparator(stored)
+	if err != nil {
+		return err
+	}
+
+	if koreksi != nil {
+		tx.Koreksi = koreksi
+	}
+	if obyek != nil {
+		tx.Obyek = obyek
+	}
+	tx.AnalisaKoreksiObyek = nil
+	if enabled {
+		combineKoreksiObyek(tx, separator)
+	}
+
+	return s.uploadRepo.UpdateTransactionKoreksiObyek(transactionID, koreksi, obyek, tx.AnalisaKoreksiObyek, userID, userRole)
+}
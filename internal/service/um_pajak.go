@@ -0,0 +1,60 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// NormalizeDebitCondition validates a UM Pajak debit condition. An empty value
+// defaults to "positive".
+func NormalizeDebitCondition(condition string) (string, error) {
+	condition = strings.ToLower(strings.TrimSpace(condition))
+	switch condition {
+	case "":
+		return models.DebitConditionPositive, nil
+	case models.DebitConditionPositive, models.DebitConditionNegative, models.DebitConditionNonZero:
+		return condition, nil
+	}
+	return "", fmt.Errorf("debit condition must be positive, negative or nonzero")
+}
+
+// ValidateAccountPattern checks that an account pattern is a valid glob
+func ValidateAccountPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid account pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// umPajakRuleApplies checks the account pattern and debit condition of a UM Pajak
+// rule whose keyword is already known to match
+func umPajakRuleApplies(rule *models.UmPajakRule, tx *models.TransactionData) bool {
+	if rule.AccountPattern.Valid && rule.AccountPattern.String != "" {
+		matched, err := path.Match(rule.AccountPattern.String, tx.Account)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	switch rule.DebitCondition {
+	case models.DebitConditionNegative:
+		return tx.Debet < 0
+	case models.DebitConditionNonZero:
+		return tx.Debet != 0
+	default:
+		return tx.Debet > 0
+	}
+}
+
+// sortUmPajakRules orders UM Pajak rules by priority (highest first), oldest rule first on ties
+func sortUmPajakRules(rules []models.UmPajakRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ValidateWHTCalculation checks a withholding tax rule's calculation mode, VAT factor
+// and rounding policy. Empty mode and rounding mean credit and none.
+func ValidateWHTCalculation(mode string, vatRate, taxRate float64, rounding string) error {
+	switch mode {
+	case "", models.WHTModeCredit, models.WHTModeDebit:
+	case models.WHTModeDPP:
+		if vatRate <= 0 || vatRate >= 1 {
+			return fmt.Errorf("dpp mode needs a vat_rate between 0 and 1 (e.g. 0.11 for 11%%)")
+		}
+	case models.WHTModeGrossUp:
+		if taxRate >= 1 {
+			return fmt.Errorf("gross_up mode needs a tax rate below 1")
+		}
+	default:
+		return fmt.Errorf("calculation mode must be one of %s, %s, %s or %s",
+			models.WHTModeCredit, models.WHTModeDebit, models.WHTModeDPP, models.WHTModeGrossUp)
+	}
+
+	switch rounding {
+	case "", models.WHTRoundingNone, models.WHTRoundingNearest, models.WHTRoundingDown, models.WHTRoundingUp:
+	default:
+		return fmt.Errorf("rounding must be one of %s, %s, %s or %s",
+			models.WHTRoundingNone, models.WHTRoundingNearest, models.WHTRoundingDown, models.WHTRoundingUp)
+	}
+	return nil
+}
+
+// whtTaxBase returns the amount a WHT rule's rate applies to for a transaction;
+// zero when the rule's side of the posting is empty
+func whtTaxBase(rule *models.WithholdingTaxRule, tx *models.TransactionData) float64 {
+	switch rule.CalculationMode {
+	case models.WHTModeDebit:
+		return tx.Debet
+	case models.WHTModeDPP:
+		return tx.Credit / (1 + rule.VatRate)
+	case models.WHTModeGrossUp:
+		return tx.Credit / (1 - rule.TaxRate)
+	}
+	return tx.Credit
+}
+
+// whtRoundingTolerance keeps floating point noise (199999.99999997 for an exact
+// 200000) from tipping down/up rounding to the wrong rupiah
+const whtRoundingTolerance = 1e-6
+
+// roundWHT applies a rule's rounding policy to a tax amount
+func roundWHT(amount float64, rounding string) float64 {
+	switch rounding {
+	case models.WHTRoundingNearest:
+		return math.Round(amount)
+	case models.WHTRoundingDown:
+		return math.Floor(amount + whtRoundingTolerance)
+	case models.WHTRoundingUp:
+		return math.Ceil(amount - whtRoundingTolerance)
+	}
+	return amount
+}
+
+// calculateWHTAmount computes the tax a rule withholds on a transaction
+func calculateWHTAmount(rule *models.WithholdingTaxRule, tx *models.TransactionData) float64 {
+	return roundWHT(whtTaxBase(rule, tx)*rule.TaxRate, rule.Rounding)
+}
+
+// whtModeLabel describes how a rule computed its tax for the export,
+// e.g. "dpp 11%, round down"
+func whtModeLabel(rule *models.WithholdingTaxRule) string {
+	label := rule.CalculationMode
+	if label == "" {
+		label = models.WHTModeCredit
+	}
+	if rule.CalculationMode == models.WHTModeDPP {
+		label += " " + strconv.FormatFloat(rule.VatRate*100, 'f', -1, 64) + "%"
+	}
+	if rule.Rounding != "" && rule.Rounding != models.WHTRoundingNone {
+		label += ", round " + rule.Rounding
+	}
+	return label
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"math"
+	"testing"
+)
+
+func TestRoundWHT(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		rounding string
+		want     float64
+	}{
+		{1234.56, "", 1234.56},
+		{1234.56, models.WHTRoundingNone, 1234.56},
+		{1234.49, models.WHTRoundingNearest, 1234},
+		{1234.5, models.WHTRoundingNearest, 1235},
+		{1234.99, models.WHTRoundingDown, 1234},
+		{1234.01, models.WHTRoundingUp, 1235},
+		// Float noise from the rate must not push an exact amount over the edge
+		{199999.99999997, models.WHTRoundingDown, 200000},
+		{200000.00000003, models.WHTRoundingUp, 200000},
+	}
+
+	for _, tt := range tests {
+		if got := roundWHT(tt.amount, tt.rounding); got != tt.want {
+			t.Errorf("roundWHT(%v, %q) = %v, want %v", tt.amount, tt.rounding, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateWHTAmount(t *testing.T) {
+	tests := []struct {
+		rule   models.WithholdingTaxRule
+		debet  float64
+		credit float64
+		want   float64
+	}{
+		{models.WithholdingTaxRule{TaxRate: 0.02}, 0, 1000000, 20000},
+		{models.WithholdingTaxRule{CalculationMode: models.WHTModeDebit, TaxRate: 0.02}, 500000, 1000000, 10000},
+		{models.WithholdingTaxRule{CalculationMode: models.WHTModeDPP, VatRate: 0.11, TaxRate: 0.02, Rounding: models.WHTRoundingDown}, 0, 1110000, 20000},
+		// Grossed up, the tax is what makes the net payment: 9.5m / 0.95 * 5% = 500k
+		{models.WithholdingTaxRule{CalculationMode: models.WHTModeGrossUp, TaxRate: 0.05, Rounding: models.WHTRoundingNearest}, 0, 9500000, 500000},
+		{models.WithholdingTaxRule{CalculationMode: models.WHTModeGrossUp, TaxRate: 0.025, Rounding: models.WHTRoundingUp}, 0, 1000001, 25642},
+		{models.WithholdingTaxRule{CalculationMode: models.WHTModeGrossUp, TaxRate: 0.05}, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		tx := &models.TransactionData{Debet: tt.debet, Credit: tt.credit}
+		if got := calculateWHTAmount(&tt.rule, tx); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("calculateWHTAmount(%s) = %v, want %v", tt.rule.CalculationMode, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWHTCalculation(t *testing.T) {
+	if err := ValidateWHTCalculation("", 0, 0.02, ""); err != nil {
+		t.Errorf("defaults: %v", err)
+	}
+	if err := ValidateWHTCalculation(models.WHTModeDPP, 0.11, 0.02, models.WHTRoundingNearest); err != nil {
+		t.Errorf("dpp: %v", err)
+	}
+	if err := ValidateWHTCalculation(models.WHTModeGrossUp, 0, 0.05, ""); err != nil {
+		t.Errorf("gross up: %v", err)
+	}
+
+	tests := []struct {
+		mode     string
+		vatRate  float64
+		taxRate  float64
+		rounding string
+	}{
+		{models.WHTModeDPP, 0, 0.02, ""},
+		{models.WHTModeDPP, 11, 0.02, ""},
+		{models.WHTModeGrossUp, 0, 1, ""},
+		{"net", 0, 0.02, ""},
+		{"", 0, 0.02, "half_even"},
+	}
+	for _, tt := range tests {
+		if err := ValidateWHTCalculation(tt.mode, tt.vatRate, tt.taxRate, tt.rounding); err == nil {
+			t.Errorf("ValidateWHTCalculation(%q, %v, %v, %q) should fail", tt.mode, tt.vatRate, tt.taxRate, tt.rounding)
+		}
+	}
+}
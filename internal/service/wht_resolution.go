@@ -0,0 +1,108 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IsWHTResolutionPolicy reports whether policy is a known WHT resolution policy
+func IsWHTResolutionPolicy(policy string) bool {
+	switch policy {
+	case models.WHTResolutionFirst, models.WHTResolutionDistinct, models.WHTResolutionConflict:
+		return true
+	}
+	return false
+}
+
+// whtResolution maps a tax type (or "*" for the default) to its resolution policy
+type whtResolution map[string]string
+
+func newWHTResolution(policies []models.WHTResolutionPolicy) whtResolution {
+	resolution := whtResolution{}
+	for _, p := range policies {
+		if IsWHTResolutionPolicy(p.Policy) {
+			resolution[p.TaxType] = p.Policy
+		}
+	}
+	return resolution
+}
+
+// policyFor returns the policy of a tax type, falling back to the default policy and
+// then to distinct
+func (r whtResolution) policyFor(taxType string) string {
+	if policy, ok := r[taxType]; ok {
+		return policy
+	}
+	if policy, ok := r[models.WHTResolutionDefaultTaxType]; ok {
+		return policy
+	}
+	return models.WHTResolutionDistinct
+}
+
+// resolve picks which of the matched rules (indices into rules, in priority order) are
+// applied to a row, one per tax type at most. Each tax type follows its own policy:
+// first applies its rule only when it is the row's highest-priority match, distinct
+// applies its highest-priority rule, and conflict applies nothing when the row matched
+// more than one rule of that tax type and reports the conflict in a warning instead.
+func (r whtResolution) resolve(rules []models.WithholdingTaxRule, matches []int32) (apply []int32, notes, warnings []string) {
+	if len(matches) == 0 {
+		return nil, nil, nil
+	}
+
+	byType := map[string][]int32{}
+	var taxTypes []string
+	for _, i := range matches {
+		taxType := rules[i].TaxType
+		if _, seen := byType[taxType]; !seen {
+			taxTypes = append(taxTypes, taxType)
+		}
+		byType[taxType] = append(byType[taxType], i)
+	}
+
+	top := matches[0]
+	var conflicted []string
+	var conflictMatches []int32
+	for _, taxType := range taxTypes {
+		typeMatches := byType[taxType]
+		winner := typeMatches[0]
+
+		switch r.policyFor(taxType) {
+		case models.WHTResolutionFirst:
+			if winner != top {
+				notes = append(notes, fmt.Sprintf("%s rule #%d skipped: rule #%d has priority (first policy)",
+					taxType, rules[winner].ID, rules[top].ID))
+				continue
+			}
+		case models.WHTResolutionConflict:
+			if len(typeMatches) > 1 {
+				conflicted = append(conflicted, taxType)
+				conflictMatches = append(conflictMatches, typeMatches...)
+				continue
+			}
+		}
+
+		for _, i := range typeMatches[1:] {
+			notes = append(notes, fmt.Sprintf("%s rule #%d ignored: rule #%d has priority", taxType, rules[i].ID, rules[winner].ID))
+		}
+		apply = append(apply, winner)
+	}
+
+	if len(conflicted) > 0 {
+		warnings = append(warnings, fmt.Sprintf("WHT conflict: rules %s match; %s left empty",
+			describeWHTMatches(rules, conflictMatches), strings.Join(conflicted, ", ")))
+	}
+
+	sort.Slice(apply, func(a, b int) bool { return apply[a] < apply[b] })
+	return apply, notes, warnings
+}
+
+// describeWHTMatches lists matched rules as "#4 (wth_23), #9 (wth_21)"
+func describeWHTMatches(rules []models.WithholdingTaxRule, matches []int32) string {
+	parts := make([]string, len(matches))
+	for n, i := range matches {
+		parts[n] = fmt.Sprintf("#%d (%s)", rules[i].ID, rules[i].TaxType)
+	}
+	return strings.Join(parts, ", ")
+}
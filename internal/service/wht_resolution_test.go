@@ -0,0 +1,107 @@
+package service
+
+import (
+	"accounting-web/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestWHTResolutionResolve(t *testing.T) {
+	// Indices 0-3 in priority order, as the engine passes them
+	rules := []models.WithholdingTaxRule{
+		{ID: 10, TaxType: "wth_23"},
+		{ID: 11, TaxType: "wth_21"},
+		{ID: 12, TaxType: "wth_23"},
+		{ID: 13, TaxType: "wth_4_2"},
+	}
+
+	tests := []struct {
+		name         string
+		policies     whtResolution
+		matches      []int32
+		wantApply    []int32
+		wantNotes    int
+		wantWarnings []string
+	}{
+		{
+			name:    "no matches",
+			matches: nil,
+		},
+		{
+			name:      "distinct by default",
+			matches:   []int32{0, 1, 2},
+			wantApply: []int32{0, 1},
+			wantNotes: 1, // #12 ignored for #10
+		},
+		{
+			name:      "first applies only the top rule",
+			policies:  whtResolution{models.WHTResolutionDefaultTaxType: models.WHTResolutionFirst},
+			matches:   []int32{0, 1, 2},
+			wantApply: []int32{0},
+			wantNotes: 2, // wth_21 skipped, #12 ignored
+		},
+		{
+			name:      "first per tax type",
+			policies:  whtResolution{"wth_21": models.WHTResolutionFirst},
+			matches:   []int32{0, 1},
+			wantApply: []int32{0},
+			wantNotes: 1,
+		},
+		{
+			name:         "conflict within one tax type",
+			policies:     whtResolution{"wth_23": models.WHTResolutionConflict},
+			matches:      []int32{0, 1, 2},
+			wantApply:    []int32{1},
+			wantWarnings: []string{"WHT conflict: rules #10 (wth_23), #12 (wth_23) match; wth_23 left empty"},
+		},
+		{
+			name:      "conflict ignores matches of other tax types",
+			policies:  whtResolution{models.WHTResolutionDefaultTaxType: models.WHTResolutionConflict},
+			matches:   []int32{0, 1, 3},
+			wantApply: []int32{0, 1, 3},
+		},
+		{
+			name:         "conflict on several tax types",
+			policies:     whtResolution{models.WHTResolutionDefaultTaxType: models.WHTResolutionConflict},
+			matches:      []int32{0, 1, 2, 3},
+			wantApply:    []int32{1, 3},
+			wantWarnings: []string{"WHT conflict: rules #10 (wth_23), #12 (wth_23) match; wth_23 left empty"},
+		},
+		{
+			name:      "tax type policy overrides the default",
+			policies:  whtResolution{models.WHTResolutionDefaultTaxType: models.WHTResolutionConflict, "wth_23": models.WHTResolutionDistinct},
+			matches:   []int32{0, 2},
+			wantApply: []int32{0},
+			wantNotes: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apply, notes, warnings := tt.policies.resolve(rules, tt.matches)
+			if !reflect.DeepEqual(apply, tt.wantApply) {
+				t.Errorf("apply = %v, want %v", apply, tt.wantApply)
+			}
+			if len(notes) != tt.wantNotes {
+				t.Errorf("notes = %q, want %d", notes, tt.wantNotes)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestNewWHTResolutionSkipsUnknownPolicies(t *testing.T) {
+	resolution := newWHTResolution([]models.WHTResolutionPolicy{
+		{TaxType: "wth_21", Policy: models.WHTResolutionFirst},
+		{TaxType: "wth_23", Policy: "newest"},
+	})
+
+	if got := resolution.policyFor("wth_21"); got != models.WHTResolutionFirst {
+		t.Errorf("policyFor(wth_21) = %q, want first", got)
+	}
+	if got := resolution.policyFor("wth_23"); got != models.WHTResolutionDistinct {
+		t.Errorf("policyFor(wth_23) = %q, want the distinct fallback", got)
+	}
+}
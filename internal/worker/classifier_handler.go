@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+)
+
+type ClassifierTaskHandler struct {
+	trainer *service.ClassifierTrainer
+}
+
+func NewClassifierTaskHandler(db *sqlx.DB) *ClassifierTaskHandler {
+	return &ClassifierTaskHandler{
+		trainer: service.NewClassifierTrainer(repository.NewClassifierRepository(db)),
+	}
+}
+
+type ClassifierTrainPayload struct {
+	ModelID int `json:"model_id"`
+}
+
+// Handle trains a queued classifier version. A failed training is recorded on the
+// model and not retried: the same rows would fail again.
+func (h *ClassifierTaskHandler) Handle(ctx context.Context, task *asynq.Task) error {
+	var payload ClassifierTrainPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("Training classifier model %d", payload.ModelID)
+
+	model, err := h.trainer.Train(payload.ModelID)
+	if err != nil {
+		if model != nil {
+			log.Printf("Classifier version %d failed: %v", model.Version, err)
+			return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+		}
+		return err
+	}
+
+	log.Printf("Classifier version %d trained on %d rows (%d koreksi, %d obyek classes) and activated",
+		model.Version, model.TrainingRows, model.KoreksiClasses, model.ObyekClasses)
+	return nil
+}
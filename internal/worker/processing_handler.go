@@ -2,12 +2,15 @@ package worker
 
 import (
 	"accounting-web/internal/config"
+	"accounting-web/internal/models"
 	"accounting-web/internal/repository"
 	"accounting-web/internal/service"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/jmoiron/sqlx"
@@ -15,32 +18,63 @@ import (
 )
 
 type ProcessingTaskHandler struct {
-	db              *sqlx.DB
-	redis           *redis.Client
-	cfg             *config.Config
-	processingEngine *service.ProcessingEngine
-	uploadRepo      *repository.UploadRepository
+	db             *sqlx.DB
+	redis          *redis.Client
+	cfg            *config.Config
+	accountRepo    *repository.AccountRepository
+	rulesRepo      *repository.RulesRepository
+	analysisRepo   *repository.AdditionalAnalysisRepository
+	ruleSetService *service.RuleSetService
+	uploadRepo     *repository.UploadRepository
+	ruleSetRepo    *repository.RuleSetRepository
+	ruleStatsRepo  *repository.RuleStatsRepository
+	classifierRepo *repository.ClassifierRepository
+	stepRepo       *repository.ProcessingStepRepository
+	events         *service.SessionEventPublisher
 }
 
 func NewProcessingTaskHandler(db *sqlx.DB, redis *redis.Client, cfg *config.Config) *ProcessingTaskHandler {
 	accountRepo := repository.NewAccountRepository(db)
 	rulesRepo := repository.NewRulesRepository(db)
 	uploadRepo := repository.NewUploadRepository(db)
+	ruleSetRepo := repository.NewRuleSetRepository(db)
+	ruleStatsRepo := repository.NewRuleStatsRepository(db)
+	classifierRepo := repository.NewClassifierRepository(db)
+	stepRepo := repository.NewProcessingStepRepository(db)
+	analysisRepo := repository.NewAdditionalAnalysisRepository(db)
 
-	processingEngine := service.NewProcessingEngine(accountRepo, rulesRepo, uploadRepo)
+	// Runs capture the active rules from the cache, which reloads when the web app
+	// bumps the rule-set version
+	ruleCache := service.NewRuleCache("worker", redis, accountRepo, rulesRepo, analysisRepo)
+	ruleCache.Start(context.Background())
+	ruleSetService := service.NewRuleSetService(accountRepo, rulesRepo, analysisRepo, ruleSetRepo, ruleCache)
 
 	return &ProcessingTaskHandler{
-		db:              db,
-		redis:           redis,
-		cfg:             cfg,
-		processingEngine: processingEngine,
-		uploadRepo:      uploadRepo,
+		db:             db,
+		redis:          redis,
+		cfg:            cfg,
+		accountRepo:    accountRepo,
+		rulesRepo:      rulesRepo,
+		analysisRepo:   analysisRepo,
+		ruleSetService: ruleSetService,
+		uploadRepo:     uploadRepo,
+		ruleSetRepo:    ruleSetRepo,
+		ruleStatsRepo:  ruleStatsRepo,
+		classifierRepo: classifierRepo,
+		stepRepo:       stepRepo,
+		events:         service.NewSessionEventPublisher(redis),
 	}
 }
 
 type ProcessingTaskPayload struct {
 	SessionID   int    `json:"session_id"`
 	SessionCode string `json:"session_code"`
+
+	// RuleSetVersionID pins the run to a stored rule-set version; 0 snapshots the active rules
+	RuleSetVersionID int    `json:"rule_set_version_id,omitempty"`
+	RunType          string `json:"run_type,omitempty"`
+	RunID            int    `json:"run_id,omitempty"`
+	TriggeredBy      *int   `json:"triggered_by,omitempty"`
 }
 
 func (h *ProcessingTaskHandler) Handle(ctx context.Context, task *asynq.Task) error {
@@ -69,21 +103,124 @@ func (h *ProcessingTaskHandler) Handle(ctx context.Context, task *asynq.Task) er
 		return nil // Don't return error, just skip processing
 	}
 
-	// Load rules into processing engine
-	if err := h.processingEngine.LoadRules(); err != nil {
+	// Record the run before loading rules so failures are visible too. Reprocess
+	// runs are created by the API so the previous results can be snapshotted.
+	startedAt := time.Now()
+	var run *models.ProcessingRun
+	if payload.RunID > 0 {
+		run, err = h.ruleSetRepo.GetRunByID(payload.RunID)
+		if err != nil {
+			log.Printf("Failed to load processing run %d: %v", payload.RunID, err)
+			run = nil
+		}
+	}
+	// A reprocess run resets its rows once it is picked up. A retry finds the run
+	// running and keeps what the earlier attempt already reset and processed.
+	resetPending := run != nil && run.Status == "queued" && run.RunType == "reprocess"
+	if run != nil {
+		run.Status = "running"
+		run.StartedAt = &startedAt
+		h.ruleSetRepo.UpdateRun(run)
+	} else {
+		run = &models.ProcessingRun{
+			SessionID:   session.ID,
+			SessionCode: session.SessionCode,
+			RunType:     "process",
+			Status:      "running",
+			TriggeredBy: payload.TriggeredBy,
+			StartedAt:   &startedAt,
+		}
+		if payload.RunType != "" {
+			run.RunType = payload.RunType
+		}
+		if err := h.ruleSetRepo.CreateRun(run); err != nil {
+			log.Printf("Failed to record processing run: %v", err)
+		}
+	}
+
+	if resetPending {
+		resetRows, err := h.uploadRepo.ResetSessionOutputs(session.SessionCode, run.ResetFilter, run.ID)
+		if err != nil {
+			log.Printf("Failed to reset session outputs: %v", err)
+			h.uploadRepo.UpdateSessionStatus(payload.SessionID, "failed")
+			h.finishRun(run, "failed", err)
+			h.publishSessionEvent(ctx, session, "status", "failed", 0, err)
+			return fmt.Errorf("failed to reset session outputs: %w", err)
+		}
+
+		// Rows outside the filter keep their results and still count as processed
+		session.ProcessedRows = session.TotalRows - int(resetRows)
+		if session.ProcessedRows < 0 {
+			session.ProcessedRows = 0
+		}
+		session.FailedRows = 0
+		if err := h.uploadRepo.UpdateSession(session); err != nil {
+			log.Printf("Failed to update session counters: %v", err)
+		}
+	}
+
+	h.publishSessionEvent(ctx, session, "status", "processing", 0, nil)
+
+	// Batch counters for the job progress API, keyed by the asynq task ID
+	taskID, _ := asynq.GetTaskID(ctx)
+	jobStats := service.StartJobStats(ctx, h.redis, taskID, session.ID, session.SessionCode, h.cfg.BatchSize)
+
+	// Load rules into processing engine: either the pinned historical version or a fresh snapshot
+	var version *models.RuleSetVersion
+	var snapshot *models.RuleSetSnapshot
+	if payload.RuleSetVersionID > 0 {
+		version, snapshot, err = h.ruleSetService.LoadVersion(payload.RuleSetVersionID)
+	} else {
+		version, snapshot, err = h.ruleSetService.CaptureActive(payload.TriggeredBy, fmt.Sprintf("processing run for session %s", session.SessionCode))
+	}
+	if err != nil {
 		log.Printf("Failed to load rules: %v", err)
 		h.uploadRepo.UpdateSessionStatus(payload.SessionID, "failed")
+		h.finishRun(run, "failed", err)
+		jobStats.Finish(ctx, "failed")
+		h.publishSessionEvent(ctx, session, "status", "failed", 0, err)
 		return fmt.Errorf("failed to load rules: %w", err)
 	}
+	// Every run gets its own engine: tasks run concurrently and each loads its own
+	// rule set, classifier and steps
+	engine := service.NewProcessingEngine(h.accountRepo, h.rulesRepo, h.analysisRepo, h.uploadRepo)
+	engine.SetWorkers(h.cfg.ProcessingWorkers)
+	engine.LoadRuleSet(snapshot)
+	run.RuleSetVersionID = &version.ID
+	log.Printf("Session %s uses rule set version %d", payload.SessionCode, version.ID)
+
+	// Suggestions come from the active classifier version; without one rows get none
+	engine.SetClassifier(h.loadClassifier())
+
+	// Steps come from the processing_steps settings at the start of the run
+	if err := engine.LoadSteps(h.stepRepo); err != nil {
+		log.Printf("Failed to load processing steps: %v", err)
+		h.uploadRepo.UpdateSessionStatus(payload.SessionID, "failed")
+		h.finishRun(run, "failed", err)
+		jobStats.Finish(ctx, "failed")
+		h.publishSessionEvent(ctx, session, "status", "failed", 0, err)
+		return fmt.Errorf("failed to load processing steps: %w", err)
+	}
 
 	// Process in batches
 	batchSize := h.cfg.BatchSize
+	// Rows left untouched by a filtered reprocess are already counted as processed
+	baseProcessed := session.ProcessedRows
 	totalProcessed := 0
 	totalFailed := 0
+	batchNumber := 0
+	ruleHits := service.NewRuleHitCounter()
+	var stepStats []models.ProcessingStepStat
 
 	for {
-		// Get batch of unprocessed transactions
-		transactions, err := h.uploadRepo.GetUnprocessedTransactions(payload.SessionID, batchSize)
+		// Get batch of unprocessed transactions. Multi-file uploads only carry the
+		// session_code on their rows, so prefer it over session_id.
+		var transactions []models.TransactionData
+		if payload.SessionCode != "" {
+			transactions, err = h.uploadRepo.GetUnprocessedTransactionsBySessionCode(payload.SessionCode, batchSize)
+		} else {
+			transactions, err = h.uploadRepo.GetUnprocessedTransactions(payload.SessionID, batchSize)
+		}
 		if err != nil {
 			log.Printf("Failed to get unprocessed transactions: %v", err)
 			break
@@ -94,36 +231,142 @@ func (h *ProcessingTaskHandler) Handle(ctx context.Context, task *asynq.Task) er
 		}
 
 		// Process batch
-		if err := h.processingEngine.ProcessBatch(transactions); err != nil {
-			log.Printf("Failed to process batch: %v", err)
+		batchNumber++
+		batchErr := engine.ProcessBatch(transactions)
+		if batchErr != nil {
+			log.Printf("Failed to process batch: %v", batchErr)
 			totalFailed += len(transactions)
 		} else {
 			totalProcessed += len(transactions)
+			ruleHits.Add(transactions)
 		}
+		batchStepStats := engine.StepStats()
+		stepStats = service.AddStepStats(stepStats, batchStepStats)
+		log.Printf("Batch of %d rows: %s", len(transactions), service.FormatStepStats(batchStepStats))
 
 		// Update session progress
-		session.ProcessedRows = totalProcessed
+		session.ProcessedRows = baseProcessed + totalProcessed
 		session.FailedRows = totalFailed
 		h.uploadRepo.UpdateSession(session)
 
+		run.ProcessedRows = totalProcessed
+		run.FailedRows = totalFailed
+		if run.ID > 0 {
+			h.ruleSetRepo.UpdateRun(run)
+		}
+
 		// Update progress in Redis
-		progressKey := fmt.Sprintf("processing:progress:%d", payload.SessionID)
-		progress := float64(totalProcessed) / float64(session.TotalRows) * 100
+		progressKey := service.SessionProgressKey(payload.SessionID)
+		progress := float64(session.ProcessedRows) / float64(session.TotalRows) * 100
 		h.redis.Set(ctx, progressKey, fmt.Sprintf("%.2f", progress), 0)
+		jobStats.RecordBatch(ctx, batchNumber, totalProcessed, totalFailed)
+		if batchErr != nil {
+			h.publishSessionEvent(ctx, session, "batch_error", "", batchNumber, batchErr)
+		}
+		h.publishSessionEvent(ctx, session, "progress", "", batchNumber, nil)
 
-		log.Printf("Processed %d/%d transactions (%.2f%%)", totalProcessed, session.TotalRows, progress)
+		log.Printf("Processed %d/%d transactions (%.2f%%)", session.ProcessedRows, session.TotalRows, progress)
 	}
 
 	// Mark session as completed
-	session.ProcessedRows = totalProcessed
+	session.ProcessedRows = baseProcessed + totalProcessed
 	session.FailedRows = totalFailed
 	session.Status = "completed"
 	if err := h.uploadRepo.UpdateSession(session); err != nil {
 		log.Printf("Failed to update session status: %v", err)
 	}
 
+	run.ProcessedRows = totalProcessed
+	run.FailedRows = totalFailed
+	h.finishRun(run, "completed", nil)
+	jobStats.Finish(ctx, "completed")
+	h.publishSessionEvent(ctx, session, "status", "completed", batchNumber, nil)
+
+	// Record how often each rule fired in this run and what each step did
+	if run.ID > 0 {
+		if err := h.ruleStatsRepo.SaveRuleHitStats(ruleHits.Stats(run.ID, session.SessionCode)); err != nil {
+			log.Printf("Failed to save rule hit stats for run %d: %v", run.ID, err)
+		}
+		for i := range stepStats {
+			stepStats[i].RunID = run.ID
+		}
+		if err := h.stepRepo.SaveStepStats(stepStats); err != nil {
+			log.Printf("Failed to save step stats for run %d: %v", run.ID, err)
+		}
+	}
+
+	// Compare the new results against the snapshot taken when the reprocess was requested
+	if run.ID > 0 && run.RunType == "reprocess" {
+		summary, err := h.ruleSetRepo.BuildChangeSummary(run.ID)
+		if err != nil {
+			log.Printf("Failed to build change summary for run %d: %v", run.ID, err)
+		} else if err := h.ruleSetRepo.SaveChangeSummary(run.ID, summary); err != nil {
+			log.Printf("Failed to save change summary for run %d: %v", run.ID, err)
+		} else {
+			log.Printf("Run %d changed %d of %d reprocessed rows", run.ID, summary.ChangedRows, summary.TotalRows)
+		}
+	}
+
 	log.Printf("Processing completed for session %s. Processed: %d, Failed: %d",
 		payload.SessionCode, totalProcessed, totalFailed)
 
 	return nil
 }
+
+// publishSessionEvent streams the counters of the session to clients following it
+func (h *ProcessingTaskHandler) publishSessionEvent(ctx context.Context, session *models.UploadSession, eventType, status string, batch int, eventErr error) {
+	event := models.SessionEvent{
+		SessionCode:   session.SessionCode,
+		Type:          eventType,
+		Stage:         "processing",
+		Status:        status,
+		TotalRows:     session.TotalRows,
+		ProcessedRows: session.ProcessedRows,
+		FailedRows:    session.FailedRows,
+		Batch:         batch,
+	}
+	if session.TotalRows > 0 {
+		event.ProgressPercentage = float64(session.ProcessedRows) / float64(session.TotalRows) * 100
+	}
+	if eventErr != nil {
+		event.Error = eventErr.Error()
+	}
+	h.events.Publish(ctx, event)
+}
+
+// loadClassifier returns the active classifier version, or nil when none is trained
+func (h *ProcessingTaskHandler) loadClassifier() *service.TextClassifier {
+	model, err := h.classifierRepo.GetActiveModel()
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to load classifier model: %v", err)
+		}
+		return nil
+	}
+
+	classifier, err := service.LoadTextClassifier(model)
+	if err != nil {
+		log.Printf("Failed to load classifier model: %v", err)
+		return nil
+	}
+	return classifier
+}
+
+// finishRun stores the final status of a processing run
+func (h *ProcessingTaskHandler) finishRun(run *models.ProcessingRun, status string, runErr error) {
+	if run.ID == 0 {
+		return
+	}
+
+	finishedAt := time.Now()
+	run.Status = status
+	run.FinishedAt = &finishedAt
+	if runErr != nil {
+		errMsg := runErr.Error()
+		run.ErrorMessage = &errMsg
+	}
+
+	if err := h.ruleSetRepo.UpdateRun(run); err != nil {
+		log.Printf("Failed to update processing run %d: %v", run.ID, err)
+	}
+}
@@ -8,10 +8,14 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-func RegisterHandlers(mux *asynq.ServeMux, db *sqlx.DB, redis *redis.Client, cfg *config.Config) {
+func RegisterHandlers(mux *asynq.ServeMux, db *sqlx.DB, redis *redis.Client, asynqClient *asynq.Client, cfg *config.Config) {
 	// Create processing task handler
 	processingHandler := NewProcessingTaskHandler(db, redis, cfg)
+	classifierHandler := NewClassifierTaskHandler(db)
+	largeUploadHandler := NewLargeUploadTaskHandler(db, redis, asynqClient, cfg)
 
 	// Register task handlers
 	mux.HandleFunc("transaction:process", processingHandler.Handle)
+	mux.HandleFunc("classifier:train", classifierHandler.Handle)
+	mux.HandleFunc("upload:process_large", largeUploadHandler.Handle)
 }
@@ -0,0 +1,197 @@
+package worker
+
+import (
+	"accounting-web/internal/config"
+	"accounting-web/internal/models"
+	"accounting-web/internal/repository"
+	"accounting-web/internal/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+)
+
+// errUploadCanceled stops parsing when the session was canceled in the meantime
+var errUploadCanceled = fmt.Errorf("upload canceled")
+
+type LargeUploadTaskHandler struct {
+	cfg          *config.Config
+	uploadRepo   *repository.UploadRepository
+	excelService *service.ExcelService
+	asynqClient  *asynq.Client
+	events       *service.SessionEventPublisher
+}
+
+// NewLargeUploadTaskHandler creates the handler; asynqClient queues rule processing
+// once an upload is parsed and is owned by the caller
+func NewLargeUploadTaskHandler(db *sqlx.DB, redis *redis.Client, asynqClient *asynq.Client, cfg *config.Config) *LargeUploadTaskHandler {
+	return &LargeUploadTaskHandler{
+		cfg:          cfg,
+		uploadRepo:   repository.NewUploadRepository(db),
+		excelService: service.NewExcelService(),
+		events:       service.NewSessionEventPublisher(redis),
+		asynqClient:  asynqClient,
+	}
+}
+
+type LargeUploadFile struct {
+	FilePath string `json:"file_path"`
+	Filename string `json:"filename"`
+}
+
+type LargeUploadTaskPayload struct {
+	SessionID       int               `json:"session_id"`
+	SessionCode     string            `json:"session_code"`
+	BackgroundJobID int               `json:"background_job_id"`
+	UserID          int               `json:"user_id"`
+	Filename        string            `json:"filename"`
+	Files           []LargeUploadFile `json:"files"`
+}
+
+// Handle parses the saved files of a large upload batch by batch, inserts the rows
+// and queues rule processing for the session. background_jobs tracks the rows
+// inserted so far. A retry starts over, so rows of a failed attempt are removed first.
+func (h *LargeUploadTaskHandler) Handle(ctx context.Context, task *asynq.Task) error {
+	var payload LargeUploadTaskPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("Starting large upload %s (%d files, job %d)", payload.SessionCode, len(payload.Files), payload.BackgroundJobID)
+
+	job, err := h.uploadRepo.GetBackgroundJobBySessionCode(payload.SessionCode)
+	if err != nil {
+		return fmt.Errorf("failed to get background job: %w", err)
+	}
+	if job.Status == "completed" || job.Status == "failed" {
+		log.Printf("Large upload %s is already %s, skipping", payload.SessionCode, job.Status)
+		return nil
+	}
+
+	session, err := h.uploadRepo.GetSessionByID(payload.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.Status == "canceled" {
+		h.failUpload(ctx, payload, job.ProcessedRows, errUploadCanceled)
+		return nil
+	}
+
+	if err := h.uploadRepo.DeleteTransactionsBySessionCode(payload.SessionCode); err != nil {
+		return h.retryOrFail(ctx, payload, 0, fmt.Errorf("failed to remove rows of an earlier attempt: %w", err))
+	}
+	h.uploadRepo.UpdateBackgroundJobProgress(payload.BackgroundJobID, 0, "processing", nil)
+	h.publishUploadEvent(ctx, payload, models.SessionEvent{Type: "status", Status: "processing", TotalRows: job.TotalRows})
+
+	startTime := time.Now()
+	inserted := 0
+	batchNumber := 0
+	for _, file := range payload.Files {
+		_, err := h.excelService.StreamTransactionFile(file.FilePath, h.cfg.BatchSize, func(batch []models.TransactionData) error {
+			// A canceled session stops the upload between batches
+			current, err := h.uploadRepo.GetSessionByID(payload.SessionID)
+			if err == nil && current.Status == "canceled" {
+				return errUploadCanceled
+			}
+
+			for i := range batch {
+				batch[i].SessionCode = payload.SessionCode
+				batch[i].UserID = payload.UserID
+				batch[i].FilePath = file.FilePath
+				batch[i].Filename = file.Filename
+			}
+			if err := h.uploadRepo.CreateMultipleTransactions(batch); err != nil {
+				return fmt.Errorf("failed to insert transactions: %w", err)
+			}
+
+			inserted += len(batch)
+			batchNumber++
+			h.uploadRepo.UpdateBackgroundJobProgress(payload.BackgroundJobID, inserted, "processing", nil)
+			h.publishUploadEvent(ctx, payload, models.SessionEvent{Type: "progress", TotalRows: job.TotalRows, ProcessedRows: inserted, Batch: batchNumber})
+			return nil
+		})
+		if err == errUploadCanceled {
+			log.Printf("Large upload %s was canceled after %d rows", payload.SessionCode, inserted)
+			h.failUpload(ctx, payload, inserted, err)
+			return nil
+		}
+		if err != nil {
+			return h.retryOrFail(ctx, payload, inserted, fmt.Errorf("%s: %w", file.Filename, err))
+		}
+	}
+
+	log.Printf("Inserted %d rows of large upload %s in %v", inserted, payload.SessionCode, time.Since(startTime))
+
+	// The session now holds its rows; hand it to rule processing
+	session.Filename = payload.Filename
+	session.TotalRows = inserted
+	session.Status = "processing"
+	if err := h.uploadRepo.UpdateSessionUpload(session); err != nil {
+		return h.retryOrFail(ctx, payload, inserted, fmt.Errorf("failed to update session: %w", err))
+	}
+	h.uploadRepo.UpdateBackgroundJobTotalRows(payload.BackgroundJobID, inserted)
+
+	processPayload, _ := json.Marshal(ProcessingTaskPayload{
+		SessionID:   payload.SessionID,
+		SessionCode: payload.SessionCode,
+		TriggeredBy: &payload.UserID,
+	})
+	if _, err := h.asynqClient.Enqueue(asynq.NewTask("transaction:process", processPayload)); err != nil {
+		// The rows are stored; the session can still be processed from the uploads page
+		log.Printf("Failed to queue processing for session %s: %v", payload.SessionCode, err)
+		h.uploadRepo.UpdateSessionStatus(payload.SessionID, "uploaded")
+	}
+
+	h.uploadRepo.UpdateBackgroundJobProgress(payload.BackgroundJobID, inserted, "completed", nil)
+	h.publishUploadEvent(ctx, payload, models.SessionEvent{Type: "status", Status: "uploaded", TotalRows: inserted, ProcessedRows: inserted, Batch: batchNumber})
+	log.Printf("Large upload %s completed, queued rule processing", payload.SessionCode)
+	return nil
+}
+
+// retryOrFail returns err so asynq retries the upload, after marking the job and
+// the session failed when no retry is left
+func (h *LargeUploadTaskHandler) retryOrFail(ctx context.Context, payload LargeUploadTaskPayload, inserted int, err error) error {
+	log.Printf("Large upload %s failed: %v", payload.SessionCode, err)
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if retried < maxRetry {
+		errorMsg := err.Error()
+		h.uploadRepo.UpdateBackgroundJobProgress(payload.BackgroundJobID, inserted, "pending", &errorMsg)
+		h.publishUploadEvent(ctx, payload, models.SessionEvent{Type: "batch_error", ProcessedRows: inserted, Error: errorMsg})
+		return err
+	}
+
+	h.failUpload(ctx, payload, inserted, err)
+	return err
+}
+
+// failUpload records why a large upload stopped on its job, and on its session
+// unless the session was canceled
+func (h *LargeUploadTaskHandler) failUpload(ctx context.Context, payload LargeUploadTaskPayload, inserted int, err error) {
+	errorMsg := err.Error()
+	h.uploadRepo.UpdateBackgroundJobProgress(payload.BackgroundJobID, inserted, "failed", &errorMsg)
+
+	status := "canceled"
+	if err != errUploadCanceled {
+		status = "failed"
+		h.uploadRepo.UpdateSessionStatus(payload.SessionID, status)
+	}
+	h.publishUploadEvent(ctx, payload, models.SessionEvent{Type: "status", Status: status, ProcessedRows: inserted, Error: errorMsg})
+}
+
+// publishUploadEvent streams the upload stage of the session to clients following it;
+// the counters are the rows inserted so far
+func (h *LargeUploadTaskHandler) publishUploadEvent(ctx context.Context, payload LargeUploadTaskPayload, event models.SessionEvent) {
+	event.SessionCode = payload.SessionCode
+	event.Stage = "upload"
+	if event.TotalRows > 0 {
+		event.ProgressPercentage = float64(event.ProcessedRows) / float64(event.TotalRows) * 100
+	}
+	h.events.Publish(ctx, event)
+}
@@ -0,0 +1,4 @@
+-- Add processing_notes column to transaction_data
+-- Stores the rule matching decisions (matched / excluded by not_value) for reviewers
+ALTER TABLE transaction_data
+ADD COLUMN processing_notes TEXT DEFAULT NULL AFTER processing_error;
@@ -0,0 +1,3 @@
+-- Remove processing_notes column from transaction_data table
+ALTER TABLE transaction_data
+DROP COLUMN processing_notes;
@@ -0,0 +1,11 @@
+-- Ensure koreksi_rules and obyek_rules carry a priority column
+-- Higher priority = checked first; ties are broken by the lowest id
+ALTER TABLE koreksi_rules
+ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0 AFTER not_value;
+
+ALTER TABLE obyek_rules
+ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0 AFTER not_value;
+
+-- Composite indexes matching the engine's evaluation order
+CREATE INDEX IF NOT EXISTS idx_koreksi_rules_active_priority ON koreksi_rules(is_active, priority DESC, id);
+CREATE INDEX IF NOT EXISTS idx_obyek_rules_active_priority ON obyek_rules(is_active, priority DESC, id);
@@ -0,0 +1,3 @@
+-- Remove the priority ordering indexes (the priority column itself is part of the base schema)
+DROP INDEX idx_koreksi_rules_active_priority ON koreksi_rules;
+DROP INDEX idx_obyek_rules_active_priority ON obyek_rules;
@@ -0,0 +1,4 @@
+-- Add rule_provenance column to transaction_data
+-- JSON object keyed by output column, e.g. {"koreksi": {"rule_type": "koreksi", "rule_id": 12, "keyword": "sewa"}}
+ALTER TABLE transaction_data
+ADD COLUMN rule_provenance JSON DEFAULT NULL AFTER processing_notes;
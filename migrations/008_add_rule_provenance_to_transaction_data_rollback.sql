@@ -0,0 +1,3 @@
+-- Remove rule_provenance column from transaction_data table
+ALTER TABLE transaction_data
+DROP COLUMN rule_provenance;
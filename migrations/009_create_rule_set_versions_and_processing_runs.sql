@@ -0,0 +1,41 @@
+-- Immutable rule-set snapshots (accounts, koreksi, obyek, WHT, tax keywords)
+-- Identical rule sets share one version thanks to the checksum
+CREATE TABLE IF NOT EXISTS rule_set_versions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    checksum CHAR(64) NOT NULL,
+    snapshot LONGTEXT NOT NULL,
+    account_count INT NOT NULL DEFAULT 0,
+    koreksi_rule_count INT NOT NULL DEFAULT 0,
+    obyek_rule_count INT NOT NULL DEFAULT 0,
+    wht_rule_count INT NOT NULL DEFAULT 0,
+    tax_keyword_count INT NOT NULL DEFAULT 0,
+    note VARCHAR(255) DEFAULT NULL,
+    created_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uq_rule_set_versions_checksum (checksum),
+    INDEX idx_rule_set_versions_created_at (created_at)
+);
+
+-- One row per processing run of a session, recording the rule-set version it used
+CREATE TABLE IF NOT EXISTS processing_runs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    session_code VARCHAR(50) NOT NULL,
+    rule_set_version_id INT DEFAULT NULL,
+    run_type ENUM('process', 'reprocess') NOT NULL DEFAULT 'process',
+    status ENUM('queued', 'running', 'completed', 'failed') NOT NULL DEFAULT 'queued',
+    processed_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    error_message TEXT,
+    triggered_by INT DEFAULT NULL,
+    started_at TIMESTAMP NULL DEFAULT NULL,
+    finished_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_processing_runs_session_id (session_id),
+    INDEX idx_processing_runs_session_code (session_code),
+    INDEX idx_processing_runs_rule_set_version_id (rule_set_version_id),
+
+    FOREIGN KEY (rule_set_version_id) REFERENCES rule_set_versions(id)
+);
@@ -0,0 +1,3 @@
+-- Drop processing runs and rule-set versions
+DROP TABLE IF EXISTS processing_runs;
+DROP TABLE IF EXISTS rule_set_versions;
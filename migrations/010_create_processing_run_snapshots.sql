@@ -0,0 +1,26 @@
+-- Before-values of rows reset by a reprocess run, used to build the change summary
+CREATE TABLE IF NOT EXISTS processing_run_snapshots (
+    run_id INT NOT NULL,
+    transaction_id BIGINT UNSIGNED NOT NULL,
+    analisa_nature_akun VARCHAR(100),
+    analisa_koreksi_obyek VARCHAR(255),
+    koreksi VARCHAR(255),
+    obyek VARCHAR(255),
+    um_pajak_db DECIMAL(20, 2),
+    pm_db DECIMAL(20, 2),
+    wth_21_cr DECIMAL(20, 2),
+    wth_23_cr DECIMAL(20, 2),
+    wth_26_cr DECIMAL(20, 2),
+    wth_4_2_cr DECIMAL(20, 2),
+    wth_15_cr DECIMAL(20, 2),
+    pk_cr DECIMAL(20, 2),
+    analisa_tambahan TEXT,
+
+    PRIMARY KEY (run_id, transaction_id),
+    FOREIGN KEY (run_id) REFERENCES processing_runs(id) ON DELETE CASCADE
+);
+
+-- Before/after summary computed when a reprocess run finishes
+ALTER TABLE processing_runs
+ADD COLUMN change_summary JSON DEFAULT NULL AFTER error_message,
+ADD COLUMN reset_filter JSON DEFAULT NULL AFTER change_summary;
@@ -0,0 +1,6 @@
+-- Remove reprocess snapshots and change summaries
+DROP TABLE IF EXISTS processing_run_snapshots;
+
+ALTER TABLE processing_runs
+DROP COLUMN reset_filter,
+DROP COLUMN change_summary;
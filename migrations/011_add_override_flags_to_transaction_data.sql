@@ -0,0 +1,9 @@
+-- Add manual override flags to transaction_data
+-- A field marked as overridden keeps its hand-entered value when the session is reprocessed
+ALTER TABLE transaction_data
+ADD COLUMN koreksi_override BOOLEAN NOT NULL DEFAULT FALSE AFTER rule_provenance,
+ADD COLUMN koreksi_override_by INT DEFAULT NULL AFTER koreksi_override,
+ADD COLUMN koreksi_override_at TIMESTAMP NULL DEFAULT NULL AFTER koreksi_override_by,
+ADD COLUMN obyek_override BOOLEAN NOT NULL DEFAULT FALSE AFTER koreksi_override_at,
+ADD COLUMN obyek_override_by INT DEFAULT NULL AFTER obyek_override,
+ADD COLUMN obyek_override_at TIMESTAMP NULL DEFAULT NULL AFTER obyek_override_by;
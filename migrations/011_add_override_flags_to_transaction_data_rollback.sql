@@ -0,0 +1,8 @@
+-- Remove manual override flags from transaction_data table
+ALTER TABLE transaction_data
+DROP COLUMN koreksi_override,
+DROP COLUMN koreksi_override_by,
+DROP COLUMN koreksi_override_at,
+DROP COLUMN obyek_override,
+DROP COLUMN obyek_override_by,
+DROP COLUMN obyek_override_at;
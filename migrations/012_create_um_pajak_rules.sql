@@ -0,0 +1,21 @@
+-- Prepaid tax (uang muka pajak, e.g. PPh 22/25 installments) detection rules
+-- A rule matches when the keterangan contains the keyword, the account matches the
+-- optional pattern (glob, e.g. 1151*) and the debit satisfies the debit condition
+CREATE TABLE IF NOT EXISTS um_pajak_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    keyword VARCHAR(255) NOT NULL,
+    account_pattern VARCHAR(100) DEFAULT NULL,
+    debit_condition ENUM('positive', 'negative', 'nonzero') NOT NULL DEFAULT 'positive',
+    tax_type VARCHAR(50) DEFAULT NULL,
+    priority INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_um_pajak_rules_active_priority (is_active, priority DESC, id),
+    INDEX idx_um_pajak_rules_keyword (keyword)
+);
+
+-- Rule-set versions also freeze the UM Pajak rules
+ALTER TABLE rule_set_versions
+ADD COLUMN IF NOT EXISTS um_pajak_rule_count INT NOT NULL DEFAULT 0 AFTER tax_keyword_count;
@@ -0,0 +1,5 @@
+-- Remove UM Pajak rules
+ALTER TABLE rule_set_versions
+DROP COLUMN um_pajak_rule_count;
+
+DROP TABLE IF EXISTS um_pajak_rules;
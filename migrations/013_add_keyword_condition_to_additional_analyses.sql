@@ -0,0 +1,9 @@
+-- Optional keyword condition per additional analysis
+-- Comma-separated keywords; the analysis applies when the keterangan contains any of
+-- them. Terms prefixed with "!" exclude the analysis. Empty = applies to every row.
+ALTER TABLE additional_analyses
+ADD COLUMN IF NOT EXISTS keyword_condition VARCHAR(255) NULL DEFAULT NULL COLLATE utf8mb4_unicode_ci AFTER analysis_title;
+
+-- Rule-set versions also freeze the additional analyses
+ALTER TABLE rule_set_versions
+ADD COLUMN IF NOT EXISTS additional_analysis_count INT NOT NULL DEFAULT 0 AFTER um_pajak_rule_count;
@@ -0,0 +1,6 @@
+-- Remove keyword_condition from additional_analyses
+ALTER TABLE rule_set_versions
+DROP COLUMN additional_analysis_count;
+
+ALTER TABLE additional_analyses
+DROP COLUMN keyword_condition;
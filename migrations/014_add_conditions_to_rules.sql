@@ -0,0 +1,11 @@
+-- Structured match conditions for koreksi, obyek and withholding tax rules
+-- JSON condition tree over transaction fields (account, document_type, side, amounts,
+-- regex on keterangan) combined with all/any/not. NULL = match on keyword only.
+ALTER TABLE koreksi_rules
+ADD COLUMN IF NOT EXISTS conditions JSON NULL DEFAULT NULL AFTER not_value;
+
+ALTER TABLE obyek_rules
+ADD COLUMN IF NOT EXISTS conditions JSON NULL DEFAULT NULL AFTER not_value;
+
+ALTER TABLE withholding_tax_rules
+ADD COLUMN IF NOT EXISTS conditions JSON NULL DEFAULT NULL AFTER tax_rate;
@@ -0,0 +1,9 @@
+-- Remove structured match conditions from rules
+ALTER TABLE withholding_tax_rules
+DROP COLUMN conditions;
+
+ALTER TABLE obyek_rules
+DROP COLUMN conditions;
+
+ALTER TABLE koreksi_rules
+DROP COLUMN conditions;
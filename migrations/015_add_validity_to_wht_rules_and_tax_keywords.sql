@@ -0,0 +1,12 @@
+-- Effective dating for withholding tax rules and tax keywords
+-- A rule applies to transactions whose posting date falls within [valid_from, valid_to];
+-- NULL leaves that side open. Versions of the same rule must not overlap.
+ALTER TABLE withholding_tax_rules
+ADD COLUMN IF NOT EXISTS valid_from DATE NULL DEFAULT NULL AFTER conditions,
+ADD COLUMN IF NOT EXISTS valid_to DATE NULL DEFAULT NULL AFTER valid_from,
+ADD INDEX IF NOT EXISTS idx_wht_rules_validity (keyword, tax_type, valid_from, valid_to);
+
+ALTER TABLE tax_keywords
+ADD COLUMN IF NOT EXISTS valid_from DATE NULL DEFAULT NULL AFTER tax_category,
+ADD COLUMN IF NOT EXISTS valid_to DATE NULL DEFAULT NULL AFTER valid_from,
+ADD INDEX IF NOT EXISTS idx_tax_keywords_validity (keyword, tax_category, valid_from, valid_to);
@@ -0,0 +1,10 @@
+-- Remove effective dating from withholding tax rules and tax keywords
+ALTER TABLE tax_keywords
+DROP INDEX idx_tax_keywords_validity,
+DROP COLUMN valid_to,
+DROP COLUMN valid_from;
+
+ALTER TABLE withholding_tax_rules
+DROP INDEX idx_wht_rules_validity,
+DROP COLUMN valid_to,
+DROP COLUMN valid_from;
@@ -0,0 +1,7 @@
+-- Calculation mode and rounding for withholding tax rules
+-- calculation_mode: credit (default), debit, dpp (credit / (1 + vat_rate)) or
+-- gross_up (credit / (1 - tax_rate)); rounding to whole rupiah: none, nearest, down, up
+ALTER TABLE withholding_tax_rules
+ADD COLUMN IF NOT EXISTS calculation_mode VARCHAR(20) NOT NULL DEFAULT 'credit' AFTER tax_rate,
+ADD COLUMN IF NOT EXISTS vat_rate DECIMAL(5,4) NOT NULL DEFAULT 0 AFTER calculation_mode,
+ADD COLUMN IF NOT EXISTS rounding VARCHAR(20) NOT NULL DEFAULT 'none' AFTER vat_rate;
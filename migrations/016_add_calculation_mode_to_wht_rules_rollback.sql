@@ -0,0 +1,5 @@
+-- Remove calculation mode and rounding from withholding tax rules
+ALTER TABLE withholding_tax_rules
+DROP COLUMN rounding,
+DROP COLUMN vat_rate,
+DROP COLUMN calculation_mode;
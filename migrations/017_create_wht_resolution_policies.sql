@@ -0,0 +1,21 @@
+-- How to resolve a row that matches more than one withholding tax rule
+-- policy: first (only the highest-priority match applies), distinct (the highest-priority
+-- rule of each tax type applies) or conflict (nothing applies and the row is flagged).
+-- tax_type '*' is the default for tax types without their own policy.
+CREATE TABLE IF NOT EXISTS wht_resolution_policies (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tax_type VARCHAR(20) NOT NULL,
+    policy ENUM('first', 'distinct', 'conflict') NOT NULL DEFAULT 'distinct',
+    updated_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_wht_resolution_policies_tax_type (tax_type)
+);
+
+INSERT IGNORE INTO wht_resolution_policies (tax_type, policy) VALUES ('*', 'distinct');
+
+-- Warnings that need review (e.g. WHT conflicts), filterable in the session detail
+ALTER TABLE transaction_data
+ADD COLUMN IF NOT EXISTS processing_warning TEXT DEFAULT NULL AFTER processing_notes,
+ADD INDEX IF NOT EXISTS idx_transaction_data_session_warning (session_code, processing_warning(1));
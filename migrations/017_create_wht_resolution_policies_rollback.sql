@@ -0,0 +1,6 @@
+-- Remove WHT resolution policies and processing warnings
+ALTER TABLE transaction_data
+DROP INDEX idx_transaction_data_session_warning,
+DROP COLUMN processing_warning;
+
+DROP TABLE IF EXISTS wht_resolution_policies;
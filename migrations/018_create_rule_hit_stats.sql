@@ -0,0 +1,16 @@
+-- Per-run rule hit counts: how many rows each rule filled during a processing run
+-- rule_type matches the provenance rule types (koreksi, obyek, withholding_tax, tax_keyword, ...)
+CREATE TABLE IF NOT EXISTS rule_hit_stats (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    run_id INT NOT NULL,
+    session_code VARCHAR(100) NOT NULL,
+    rule_type VARCHAR(30) NOT NULL,
+    rule_id INT NOT NULL,
+    hits INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_rule_hit_stats_run_rule (run_id, rule_type, rule_id),
+    INDEX idx_rule_hit_stats_rule (rule_type, rule_id, created_at),
+    INDEX idx_rule_hit_stats_created_at (created_at)
+);
+
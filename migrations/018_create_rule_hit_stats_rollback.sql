@@ -0,0 +1,2 @@
+-- Remove per-run rule hit counts
+DROP TABLE IF EXISTS rule_hit_stats;
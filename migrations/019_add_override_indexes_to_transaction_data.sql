@@ -0,0 +1,4 @@
+-- Index manually classified rows; rule suggestions read the most recent ones
+ALTER TABLE transaction_data
+ADD INDEX IF NOT EXISTS idx_koreksi_override (koreksi_override, koreksi_override_at),
+ADD INDEX IF NOT EXISTS idx_obyek_override (obyek_override, obyek_override_at);
@@ -0,0 +1,4 @@
+-- Remove manual override indexes from transaction_data table
+ALTER TABLE transaction_data
+DROP INDEX IF EXISTS idx_koreksi_override,
+DROP INDEX IF EXISTS idx_obyek_override;
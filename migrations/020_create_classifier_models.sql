@@ -0,0 +1,30 @@
+-- Versioned naive Bayes models suggesting koreksi/obyek for rows no rule matched
+-- model holds the trained class/token counts as JSON; one ready model is active
+CREATE TABLE IF NOT EXISTS classifier_models (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    version INT NOT NULL,
+    status ENUM('queued', 'training', 'ready', 'failed') NOT NULL DEFAULT 'queued',
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    training_rows INT NOT NULL DEFAULT 0,
+    koreksi_classes INT NOT NULL DEFAULT 0,
+    obyek_classes INT NOT NULL DEFAULT 0,
+    vocabulary_size INT NOT NULL DEFAULT 0,
+    koreksi_accuracy DECIMAL(5,4) DEFAULT NULL,
+    obyek_accuracy DECIMAL(5,4) DEFAULT NULL,
+    model LONGTEXT,
+    error_message TEXT,
+    created_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    trained_at TIMESTAMP NULL DEFAULT NULL,
+
+    UNIQUE KEY uq_classifier_models_version (version),
+    INDEX idx_classifier_models_active (is_active)
+);
+
+-- Classifier suggestions, kept apart from the rule-derived koreksi/obyek
+ALTER TABLE transaction_data
+ADD COLUMN IF NOT EXISTS suggested_koreksi VARCHAR(255) DEFAULT NULL AFTER obyek_override_at,
+ADD COLUMN IF NOT EXISTS suggested_koreksi_confidence DECIMAL(5,4) DEFAULT NULL AFTER suggested_koreksi,
+ADD COLUMN IF NOT EXISTS suggested_obyek VARCHAR(255) DEFAULT NULL AFTER suggested_koreksi_confidence,
+ADD COLUMN IF NOT EXISTS suggested_obyek_confidence DECIMAL(5,4) DEFAULT NULL AFTER suggested_obyek,
+ADD COLUMN IF NOT EXISTS suggestion_model_version INT DEFAULT NULL AFTER suggested_obyek_confidence;
@@ -0,0 +1,9 @@
+-- Remove classifier suggestions and models
+ALTER TABLE transaction_data
+DROP COLUMN IF EXISTS suggested_koreksi,
+DROP COLUMN IF EXISTS suggested_koreksi_confidence,
+DROP COLUMN IF EXISTS suggested_obyek,
+DROP COLUMN IF EXISTS suggested_obyek_confidence,
+DROP COLUMN IF EXISTS suggestion_model_version;
+
+DROP TABLE IF EXISTS classifier_models;
@@ -0,0 +1,13 @@
+-- Managed synonyms for rule keywords: a term (e.g. an abbreviation like "sw gdg") is
+-- interchangeable with its canonical phrase ("sewa gedung") when rules are matched
+CREATE TABLE IF NOT EXISTS keyword_synonyms (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    term VARCHAR(255) NOT NULL,
+    canonical VARCHAR(255) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uq_keyword_synonyms_term_canonical (term, canonical),
+    INDEX idx_keyword_synonyms_canonical (canonical)
+);
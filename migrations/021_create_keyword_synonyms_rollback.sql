@@ -0,0 +1,2 @@
+-- Remove keyword synonyms
+DROP TABLE IF EXISTS keyword_synonyms;
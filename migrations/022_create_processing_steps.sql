@@ -0,0 +1,42 @@
+-- Processing pipeline settings: which steps run, in which order and with which config
+-- Steps registered in code but missing here run enabled in their default order
+CREATE TABLE IF NOT EXISTS processing_steps (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    step_order INT NOT NULL DEFAULT 0,
+    config TEXT DEFAULT NULL,
+    updated_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uq_processing_steps_name (name)
+);
+
+INSERT IGNORE INTO processing_steps (name, description, step_order) VALUES
+('analisa_nature_akun', 'Analisa Nature Akun from the account master', 10),
+('koreksi', 'Koreksi from koreksi_rules', 20),
+('obyek', 'Obyek from obyek_rules', 30),
+('analisa_koreksi_obyek', 'Analisa Koreksi - Obyek', 40),
+('withholding_tax', 'Withholding tax (21, 23, 26, 4.2, 15)', 50),
+('pm_db', 'PM DB (input tax)', 60),
+('pk_cr', 'PK CR (output tax)', 70),
+('um_pajak_db', 'UM Pajak DB (prepaid tax)', 80),
+('analisa_tambahan', 'Analisa Tambahan', 90),
+('classifier_suggestions', 'Classifier suggestions for koreksi/obyek', 100);
+
+-- Per-run totals of each step: rows processed, output fields changed and time taken
+CREATE TABLE IF NOT EXISTS processing_step_stats (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    run_id INT NOT NULL,
+    step_name VARCHAR(50) NOT NULL,
+    step_order INT NOT NULL DEFAULT 0,
+    transactions INT NOT NULL DEFAULT 0,
+    fields_changed INT NOT NULL DEFAULT 0,
+    errors INT NOT NULL DEFAULT 0,
+    duration_ms DECIMAL(14,3) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY uk_processing_step_stats_run_step (run_id, step_name)
+);
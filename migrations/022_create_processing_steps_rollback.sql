@@ -0,0 +1,3 @@
+-- Remove processing pipeline settings and step stats
+DROP TABLE IF EXISTS processing_step_stats;
+DROP TABLE IF EXISTS processing_steps;
@@ -343,6 +343,7 @@ async function editAnalysis(id) {
             document.getElementById('accountCode').value = data.account_code || '';
             document.getElementById('analysisType').value = data.analysis_type || '';
             document.getElementById('analysisTitle').value = data.analysis_title || '';
+            document.getElementById('keywordCondition').value = data.keyword_condition || '';
             document.getElementById('status').value = data.status || 'active';
             document.getElementById('notes').value = data.notes || '';
 
@@ -371,6 +372,7 @@ async function handleAnalysisSubmit(e) {
         account_code: document.getElementById('accountCode').value,
         analysis_type: document.getElementById('analysisType').value,
         analysis_title: document.getElementById('analysisTitle').value,
+        keyword_condition: document.getElementById('keywordCondition').value,
         status: document.getElementById('status').value,
         notes: document.getElementById('notes').value
     };